@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Short hashes come from git where it can: %h, %p and rev-parse --short
+// follow core.abbrev, grow with the repository like git's own output,
+// and are long enough to name one object. The go-git loader has to
+// abbreviate hashes itself, with abbrevLength and uniqueAbbrevs.
+
+// shortHEAD is HEAD's short hash, as git abbreviates it.
+func shortHEAD(repoPath string) (string, error) {
+	return gitRead(repoPath, "rev-parse", "--short", "HEAD")
+}
+
+// abbrevLength is how long git makes short hashes in the repository, 7
+// when it can't tell.
+func abbrevLength(repoPath string) int {
+	if short, err := shortHEAD(repoPath); err == nil && len(short) >= 4 {
+		return len(short)
+	}
+	return 7
+}
+
+// uniqueAbbrevs abbreviates full hashes to n characters or, where two
+// of them start alike, to as many as tell them apart.
+func uniqueAbbrevs(hashes []string, n int) map[string]string {
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+	short := make(map[string]string, len(sorted))
+	for i, h := range sorted {
+		length := n
+		for _, j := range []int{i - 1, i + 1} {
+			if j >= 0 && j < len(sorted) && sorted[j] != h {
+				length = max(length, commonPrefix(h, sorted[j])+1)
+			}
+		}
+		short[h] = h[:min(length, len(h))]
+	}
+	return short
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// widestHash is the width of the longest short hash, which the hash
+// column is padded to.
+func widestHash(commits []commit) int {
+	width := 0
+	for _, c := range commits {
+		width = max(width, len(c.Hash))
+	}
+	return width
+}
+
+// hashPad lines up what follows a commit's hash in the list.
+func (m *model) hashPad(c commit) string {
+	return strings.Repeat(" ", max(m.hashWidth-len(c.Hash), 0))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// prompt is a one-line text input shown in place of the help line. Enter
+// hands the value to onSubmit, esc cancels.
+type prompt struct {
+	input    textinput.Model
+	onSubmit func(m *model, value string) tea.Cmd
+}
+
+func newPrompt(label, placeholder string, onSubmit func(m *model, value string) tea.Cmd) *prompt {
+	ti := textinput.New()
+	ti.Prompt = label + ": "
+	ti.Placeholder = placeholder
+	ti.PromptStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	ti.Focus()
+	return &prompt{input: ti, onSubmit: onSubmit}
+}
+
+// menu offers a few single-key choices in place of the help line. A
+// detail, when given, is shown in the details panel while it is open.
+type menu struct {
+	title   string
+	options []menuOption
+	detail  string
+}
+
+// menuOption is a key of a menu. One without a label isn't listed in the
+// status line, as the menu's detail shows it.
+type menuOption struct {
+	key    string
+	label  string
+	action func(m *model) tea.Cmd
+}
+
+// updateOverlay routes keys to the tour or the active prompt, finder,
+// release overview, menu or commit editor. It reports false when none is open, so the key goes
+// through the normal bindings.
+func (m *model) updateOverlay(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch {
+	case m.tour != nil:
+		return m.updateTour(msg), true
+	case m.prompt != nil:
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			m.prompt = nil
+			return nil, true
+		case "enter":
+			p := m.prompt
+			m.prompt = nil
+			return p.onSubmit(m, strings.TrimSpace(p.input.Value())), true
+		}
+		var cmd tea.Cmd
+		m.prompt.input, cmd = m.prompt.input.Update(msg)
+		return cmd, true
+
+	case m.shell != nil:
+		return m.updateShell(msg), true
+
+	case m.finder != nil:
+		return m.updateFinder(msg), true
+
+	case m.releases != nil:
+		return m.updateReleases(msg), true
+
+	case m.compare != nil:
+		return m.updateCompare(msg), true
+
+	case m.fileView != nil:
+		return m.updateFileView(msg), true
+
+	case m.grep != nil:
+		return m.updateGrep(msg), true
+
+	case m.tree != nil:
+		return m.updateTree(msg), true
+
+	case m.menu != nil:
+		mn := m.menu
+		m.menu = nil
+		for _, opt := range mn.options {
+			if msg.String() == opt.key {
+				return opt.action(m), true
+			}
+		}
+		// Any other key, esc included, just closes the menu
+		return nil, true
+
+	case m.commit != nil:
+		return m.updateCommitEditor(msg), true
+	}
+	return nil, false
+}
+
+// renderStatusLine renders the bottom line: the open prompt or menu, the
+// result of the last action, or else the key help.
+func (m *model) renderStatusLine(help string) string {
+	switch {
+	case m.tour != nil:
+		return m.renderTour()
+	case m.prompt != nil:
+		return m.prompt.input.View()
+	case m.finder != nil:
+		return m.finder.input.View()
+	case m.menu != nil:
+		keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+		parts := []string{lipgloss.NewStyle().Bold(true).Render(m.menu.title + ":")}
+		for _, opt := range m.menu.options {
+			if opt.label == "" {
+				continue
+			}
+			parts = append(parts, keyStyle.Render("["+opt.key+"]")+" "+opt.label)
+		}
+		parts = append(parts, helpStyle.Render("esc: cancel"))
+		return strings.Join(parts, "  ")
+	case m.count.n > 0:
+		return helpStyle.Render(fmt.Sprintf("%d", m.count.n))
+	case m.status != "" && m.statusErr:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A")).Render("✗ " + m.status)
+	case m.status != "":
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C")).Render("✓ " + m.status)
+	}
+	return help
+}
+
+// gitDoneMsg reports the outcome of a git command run by runGitCmd.
+type gitDoneMsg struct {
+	action       string
+	output       string
+	err          error
+	workTreeOnly bool     // only the index or working tree changed
+	authArgs     []string // set when a remote command failed for lack of credentials
+
+	stashArgs      []string // set when the command was refused over local changes
+	stashRetry     tea.Cmd  // or this, for one that is more than a git command: runs it again, autostashed
+	stashConflicts []string // files where re-applying stashed changes conflicted
+}
+
+// runGitCmd runs a git command that changes the repository. When it
+// finishes the model reloads, and the last line of output is shown in the
+// status line.
+func runGitCmd(repoPath, action string, args ...string) tea.Cmd {
+	return runGitCmdInput(repoPath, action, "", args...)
+}
+
+// runGitCmdInput is runGitCmd with input fed to the command's stdin.
+func runGitCmdInput(repoPath, action, input string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		log.Printf("Running: git %s\n", strings.Join(args, " "))
+		cmd := gitCommand(repoPath, args...)
+		if input != "" {
+			cmd.Stdin = strings.NewReader(input)
+		}
+		out, err := cmd.CombinedOutput()
+		msg := gitDoneMsg{action: action, output: strings.TrimSpace(string(out)), err: err}
+		if err != nil && input == "" && failedOnLocalChanges(msg.output) {
+			msg.stashArgs = args
+		}
+		return msg
+	}
+}
+
+func (m *model) handleGitDone(msg gitDoneMsg) tea.Cmd {
+	if m.commit != nil && m.commit.running {
+		// A failed commit keeps the editor open so the message isn't lost
+		m.commit.running = false
+		if msg.err == nil {
+			m.commit = nil
+			m.commitDraft = ""
+		}
+	}
+
+	lines := strings.Split(msg.output, "\n")
+	last := lines[len(lines)-1]
+	if msg.err != nil {
+		log.Printf("%s failed: %v\n%s\n", msg.action, msg.err, msg.output)
+		m.statusErr = true
+		if last == "" {
+			last = msg.err.Error()
+		}
+		m.status = fmt.Sprintf("%s failed: %s", msg.action, last)
+	} else {
+		m.statusErr = false
+		m.status = msg.action
+		if last != "" {
+			m.status += ": " + last
+		}
+	}
+	if msg.authArgs != nil {
+		m.menu = authMenu(msg)
+	}
+	if msg.stashArgs != nil || msg.stashRetry != nil {
+		m.menu = autostashMenu(msg)
+	}
+	if msg.err != nil {
+		if name, hint := operationInProgress(m.repoPath); name != "" {
+			m.status = fmt.Sprintf("Stopped halfway through a %s: %s", name, hint)
+		}
+	}
+	if len(msg.stashConflicts) > 0 {
+		m.status, m.statusErr = stashConflictStatus(msg.action, msg.stashConflicts), true
+	}
+	if msg.workTreeOnly {
+		return tea.Batch(loadWorkTreeStatus(m.repoPath), m.maybeLoadWorkTreeDiff(true))
+	}
+	return m.reload()
+}
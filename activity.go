@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// activityWeeks is how far back the heat strip goes, a year as GitHub
+// shows it.
+const activityWeeks = 53
+
+// activityLevels shade a day of the heat strip, from none to the busiest,
+// in GitHub's greens.
+var activityLevels = []lipgloss.Style{
+	lipgloss.NewStyle().Foreground(lipgloss.Color("#3B4252")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("#0E4429")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("#006D32")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("#26A641")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("#39D353")),
+}
+
+// activity is who committed on which day, over the weeks of the strip.
+type activity struct {
+	since   time.Time                 // the Sunday the strip starts on
+	total   map[string]int            // commits by day, "2006-01-02"
+	authors map[string]map[string]int // commits by author, then by day
+}
+
+type activityMsg struct {
+	activity activity
+	err      error
+}
+
+// activityFormat is the author date, the author and the co-authors of
+// a commit, as "Name <email>" separated by \x01.
+const activityFormat = "%at%x00%aN%x00%(trailers:key=Co-authored-by,valueonly,separator=%x01)"
+
+// loadActivity counts the commits of the graph's scope by author date,
+// over the last year. A commit counts for its co-authors too.
+func (m *model) loadActivity() tea.Cmd {
+	scope, paths := m.logScope(), m.filterPaths()
+	listed := m.coAuthored
+	repoPath := m.repoPath
+	now := m.now
+	return func() tea.Msg {
+		// Weeks run Sunday to Saturday, the strip's columns
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		since := today.AddDate(0, 0, -int(today.Weekday())-7*(activityWeeks-1))
+		args := []string{"log", "--format=" + activityFormat}
+		if listed != nil {
+			// The commits of a filter widened by co-authors, the year
+			// picked out of them below
+			args = append(args, "--no-walk=unsorted", "--stdin")
+		} else {
+			args = append(append(args, "--since="+since.Format(time.RFC3339)), scope...)
+			args = append(append(args, "--"), paths...)
+		}
+		cmd := gitCommand(repoPath, args...)
+		if listed != nil {
+			cmd.Stdin = strings.NewReader(strings.Join(listed, "\n") + "\n")
+		}
+		out, err := cmdOutput(cmd)
+		if err != nil {
+			return activityMsg{err: err}
+		}
+		a := activity{since: since, total: map[string]int{}, authors: map[string]map[string]int{}}
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 3 {
+				continue
+			}
+			secs, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil || secs < since.Unix() {
+				continue
+			}
+			day := time.Unix(secs, 0).Format("2006-01-02")
+			a.total[day]++
+			authors := []string{fields[1]}
+			for _, coAuthor := range strings.Split(fields[2], "\x01") {
+				name, _, _ := strings.Cut(coAuthor, " <")
+				if name = strings.TrimSpace(name); name != "" && !slices.Contains(authors, name) {
+					authors = append(authors, name)
+				}
+			}
+			for _, author := range authors {
+				if a.authors[author] == nil {
+					a.authors[author] = map[string]int{}
+				}
+				a.authors[author][day]++
+			}
+		}
+		return activityMsg{activity: a}
+	}
+}
+
+// heatStrip draws commits by day as GitHub's contribution graph does: a
+// column per week, a row per weekday, shaded by how busy the day was
+// against the busiest.
+func heatStrip(days map[string]int, since, now time.Time) string {
+	busiest := 0
+	for _, n := range days {
+		busiest = max(busiest, n)
+	}
+	var sb strings.Builder
+
+	// Month names over the weeks they start in
+	months := []rune(strings.Repeat(" ", activityWeeks+4))
+	for w := range activityWeeks {
+		day := since.AddDate(0, 0, 7*w)
+		if day.Day() <= 7 && w+3 < activityWeeks {
+			copy(months[w+4:], []rune(day.Format("Jan")))
+		}
+	}
+	sb.WriteString(helpStyle.Render(strings.TrimRight(string(months), " ")))
+	sb.WriteString("\n")
+
+	for weekday := range 7 {
+		label := "    "
+		if weekday%2 == 1 {
+			label = time.Weekday(weekday).String()[:3] + " "
+		}
+		sb.WriteString(helpStyle.Render(label))
+		for w := range activityWeeks {
+			day := since.AddDate(0, 0, 7*w+weekday)
+			if day.After(now) {
+				break
+			}
+			n := days[day.Format("2006-01-02")]
+			level := 0
+			if n > 0 {
+				level = 1 + (n*(len(activityLevels)-1)-1)/busiest
+			}
+			sb.WriteString(activityLevels[level].Render("■"))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n    " + helpStyle.Render("Less "))
+	for _, style := range activityLevels {
+		sb.WriteString(style.Render("■"))
+	}
+	sb.WriteString(helpStyle.Render(" More"))
+	return sb.String()
+}
+
+// activityAuthors are how many authors the activity view offers keys
+// for, the busiest first.
+const activityAuthors = 9
+
+// activityMenu shows the heat strip of every commit, or of one author's
+// with author set, and offers the busiest authors' strips.
+func (m *model) activityMenu(a activity, author string) *menu {
+	days, title := a.total, "Activity"
+	if author != "" {
+		days, title = a.authors[author], "Activity of "+author
+	}
+	commits := 0
+	for _, n := range days {
+		commits += n
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sectionHeader(title))
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf("%d commits in the last year\n\n", commits))
+	sb.WriteString(heatStrip(days, a.since, m.now))
+	sb.WriteString("\n\n")
+
+	// The busiest authors, by their commits over the year
+	type count struct {
+		name    string
+		commits int
+	}
+	var authors []count
+	for name, byDay := range a.authors {
+		c := count{name: name}
+		for _, n := range byDay {
+			c.commits += n
+		}
+		authors = append(authors, c)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].commits != authors[j].commits {
+			return authors[i].commits > authors[j].commits
+		}
+		return authors[i].name < authors[j].name
+	})
+	authors = authors[:min(len(authors), activityAuthors)]
+
+	names := make([]string, len(authors))
+	for i, c := range authors {
+		names[i] = c.name
+	}
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	var options []menuOption
+	if author != "" {
+		options = append(options, menuOption{key: "a", label: "all authors", action: func(m *model) tea.Cmd {
+			m.menu = m.activityMenu(a, "")
+			return nil
+		}})
+	}
+	for i, key := range pickerKeys(append([]string{"a"}, names...))[1:] {
+		c := authors[i]
+		if key == "" {
+			sb.WriteString("    " + authorStyle.Render(c.name) + helpStyle.Render(fmt.Sprintf("  %d", c.commits)) + "\n")
+			continue
+		}
+		options = append(options, menuOption{key: key, action: func(m *model) tea.Cmd {
+			m.menu = m.activityMenu(a, c.name)
+			return nil
+		}})
+		marker := "  "
+		if c.name == author {
+			marker = "> "
+		}
+		sb.WriteString(keyStyle.Render(key) + " " + marker + authorStyle.Render(c.name) + helpStyle.Render(fmt.Sprintf("  %d", c.commits)) + "\n")
+	}
+	return &menu{title: title, options: options, detail: sb.String()}
+}
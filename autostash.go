@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A command that git refuses to run over uncommitted changes, as a pull,
+// a rebase or a reset --keep that would touch the changed files, can be
+// run again with the changes stashed first and re-applied after. Rebases
+// and pulls do that with their own --autostash, which keeps the changes
+// in the stash across a rebase that stops for a conflict; other commands
+// are run between a git stash push and pop.
+
+// localChangeFailures are git's messages for a command refused because
+// of uncommitted changes.
+var localChangeFailures = []string{
+	"would be overwritten by",
+	"You have unstaged changes",
+	"Your index contains uncommitted changes",
+	"Please commit or stash them",
+	"Please commit your changes or stash them",
+	"not uptodate. Cannot merge",
+}
+
+// failedOnLocalChanges tells whether a failed command's output says it
+// was refused over uncommitted changes.
+func failedOnLocalChanges(output string) bool {
+	for _, failure := range localChangeFailures {
+		if strings.Contains(output, failure) {
+			return true
+		}
+	}
+	return false
+}
+
+// autostashMenu offers to run a command refused over local changes
+// again, with the changes stashed.
+func autostashMenu(msg gitDoneMsg) *menu {
+	return &menu{
+		title: msg.action + " needs the local changes out of the way",
+		options: []menuOption{{key: "enter", label: "stash, run again, re-apply", action: func(m *model) tea.Cmd {
+			m.status = "Stashing the local changes…"
+			if msg.stashRetry != nil {
+				return msg.stashRetry
+			}
+			return runAutostashed(m.repoPath, msg.action, msg.stashArgs...)
+		}}},
+		detail: sectionHeader("Local changes") + "\n\n" + msg.output + "\n\n" +
+			"git won't run this over your uncommitted changes. Enter stashes them,\n" +
+			"runs it again and re-applies them; if they then conflict, the files\n" +
+			"are listed, and the changes are kept in the stash until you drop it.",
+	}
+}
+
+// autostashConflict is git's message for a stash that didn't re-apply
+// cleanly after --autostash.
+const autostashConflict = "Applying autostash resulted in conflicts"
+
+// autostashed finishes the message of a command run with --autostash,
+// listing the files where the changes didn't re-apply cleanly.
+func autostashed(repoPath string, msg gitDoneMsg) gitDoneMsg {
+	msg.stashArgs, msg.stashRetry = nil, nil
+	if strings.Contains(msg.output, autostashConflict) {
+		msg.stashConflicts = conflictedFiles(repoPath)
+	}
+	return msg
+}
+
+// runAutostashed runs a command with the local changes stashed, and
+// re-applies them after.
+func runAutostashed(repoPath, action string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		switch args[0] {
+		case "pull":
+			// As a pull from the menu, listing the commits it brings in
+			msg := runPull(repoPath, "@{upstream}", action, slices.Insert(slices.Clone(args[1:]), 0, "--autostash")...)().(pullDoneMsg)
+			msg.gitDoneMsg = autostashed(repoPath, msg.gitDoneMsg)
+			return msg
+		case "rebase":
+			return autostashed(repoPath, runGitCmd(repoPath, action, slices.Insert(slices.Clone(args), 1, "--autostash")...)().(gitDoneMsg))
+		}
+
+		out, err := gitRun(repoPath, "stash", "push", "--include-untracked", "-m", "gitraffe: before "+action)
+		if err != nil {
+			return gitDoneMsg{action: action, output: out, err: err}
+		}
+		msg := runGitCmd(repoPath, action, args...)().(gitDoneMsg)
+		msg.stashArgs = nil
+		if strings.Contains(out, "No local changes to save") {
+			// Nothing was stashed, so nothing is to be popped
+			return msg
+		}
+		out, err = gitRun(repoPath, "stash", "pop")
+		if err != nil {
+			msg.stashConflicts = conflictedFiles(repoPath)
+			if len(msg.stashConflicts) == 0 {
+				// Not a conflict: the changes are still in the stash
+				msg.output, msg.err = out, fmt.Errorf("git stash pop failed")
+				msg.action = action + ", but re-applying the stashed changes"
+			}
+		}
+		return msg
+	}
+}
+
+// conflictedFiles lists the files with unresolved conflicts.
+func conflictedFiles(repoPath string) []string {
+	out, _ := gitRead(repoPath, "diff", "--name-only", "--diff-filter=U")
+	return strings.Fields(out)
+}
+
+// stashConflictStatus says that a command went through but re-applying
+// the stashed changes after it conflicted, and where.
+func stashConflictStatus(action string, files []string) string {
+	where := "some files"
+	if len(files) > 0 {
+		where = strings.Join(files, ", ")
+		if len(files) > 3 {
+			where = fmt.Sprintf("%s and %d more", strings.Join(files[:3], ", "), len(files)-3)
+		}
+	}
+	return action + ", but re-applying your changes conflicted in " + where +
+		": resolve them, then git stash drop (the changes are kept in the stash)"
+}
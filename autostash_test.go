@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAutostashedPull(t *testing.T) {
+	upstream := testRepo(t)
+	upstream.commit("f", "one\ntwo\nthree\nfour\nfive\n", "Write f")
+	clone := filepath.Join(t.TempDir(), "clone")
+	gitOutput(t, upstream.dir, "clone", "-q", upstream.dir, clone)
+	upstream.commit("f", "ONE\ntwo\nthree\nfour\nfive\n", "Shout one")
+	if upstream.err != nil {
+		t.Fatal(upstream.err)
+	}
+	pulled := gitOutput(t, upstream.dir, "rev-parse", "HEAD")
+	if err := os.WriteFile(filepath.Join(clone, "f"), []byte("one\ntwo\nthree\nfour\nFIVE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := runAutostashed(clone, "Pulled", "pull", "--ff-only")().(pullDoneMsg)
+	if !ok {
+		t.Fatal("an autostashed pull doesn't bring a pullDoneMsg")
+	}
+	if msg.err != nil || len(msg.stashConflicts) > 0 {
+		t.Fatalf("pull failed: %v %q", msg.err, msg.output)
+	}
+	if want := []string{pulled}; !reflect.DeepEqual(msg.incoming, want) {
+		t.Errorf("incoming = %q, want %q", msg.incoming, want)
+	}
+	if got, _ := os.ReadFile(filepath.Join(clone, "f")); string(got) != "ONE\ntwo\nthree\nfour\nFIVE\n" {
+		t.Errorf("f after the pull = %q, want both changes", got)
+	}
+}
+
+func TestRunAutostashed(t *testing.T) {
+	tests := []struct {
+		name      string
+		local     string // f's uncommitted content, "" for none
+		want      string // f's content after
+		conflicts []string
+		stashes   int // left in the stash
+	}{
+		{"re-applied", "1\n2\n3\n4\nFIVE\n", "1\n2\n3\n4\nFIVE\n", nil, 0},
+		{"conflicting", "uno\n2\n3\n4\n5\n", "", []string{"f"}, 1},
+		{"nothing to stash", "", "1\n2\n3\n4\n5\n", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testRepo(t)
+			d.commit("f", "1\n2\n3\n4\n5\n", "Write f")
+			d.commit("f", "ONE\n2\n3\n4\n5\n", "Shout one")
+			if d.err != nil {
+				t.Fatal(d.err)
+			}
+			first := gitOutput(t, d.dir, "rev-parse", "HEAD~1")
+			path := filepath.Join(d.dir, "f")
+			if tt.local != "" {
+				if err := os.WriteFile(path, []byte(tt.local), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			msg := runAutostashed(d.dir, "Reset", "reset", "--keep", "HEAD~1")().(gitDoneMsg)
+			if msg.err != nil {
+				t.Fatalf("reset failed: %v %q", msg.err, msg.output)
+			}
+			if head := gitOutput(t, d.dir, "rev-parse", "HEAD"); head != first {
+				t.Errorf("HEAD = %s, want it reset to %s", head, first)
+			}
+			if !reflect.DeepEqual(msg.stashConflicts, tt.conflicts) {
+				t.Errorf("conflicts = %q, want %q", msg.stashConflicts, tt.conflicts)
+			}
+			if got, _ := os.ReadFile(path); tt.want != "" && string(got) != tt.want {
+				t.Errorf("f = %q, want %q", got, tt.want)
+			}
+			stashes := gitOutput(t, d.dir, "stash", "list")
+			if n := strings.Count(stashes, "stash@{"); n != tt.stashes {
+				t.Errorf("stash list = %q, want %d entries", stashes, tt.stashes)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Backends the history and diffs can be loaded with, chosen with
+// --backend or gitraffe.backend:
+//
+//   - auto, the default: git log --graph, falling back to a plain git
+//     log without the graph lines when that fails
+//   - cli: the same, without go-git opening the repository at all
+//   - go-git: go-git reads the history and the diffs, without the git
+//     CLI's graph, for when git is missing or fails on the repository
+const (
+	backendAuto  = "auto"
+	backendCLI   = "cli"
+	backendGoGit = "go-git"
+)
+
+// parseBackend checks a backend name.
+func parseBackend(name string) (string, error) {
+	switch name {
+	case backendAuto, backendCLI, backendGoGit:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown backend %q, want auto, cli or go-git", name)
+}
+
+// loadDiffGoGit is loadDiffCmd done with go-git. go-git has no combined
+// diff, so a merge is diffed against its first parent unless another is
+// chosen, and it doesn't describe commits.
+func loadDiffGoGit(repo *git.Repository, fullHash string, idx int, parent int) tea.Cmd {
+	return func() tea.Msg {
+		msg := diffLoadedMsg{commitIdx: idx, parent: parent}
+		c, err := repo.CommitObject(plumbing.NewHash(fullHash))
+		if err != nil {
+			msg.diffBody = "go-git can't read the commit: " + err.Error()
+			return msg
+		}
+		if _, body, ok := strings.Cut(c.Message, "\n"); ok {
+			msg.body = strings.TrimLeft(body, "\n")
+		}
+
+		var from *object.Tree
+		if n := max(parent, 1); c.NumParents() >= n {
+			p, err := c.Parent(n - 1)
+			if err == nil {
+				from, err = p.Tree()
+			}
+			if err != nil {
+				msg.diffBody = "go-git can't read the parent: " + err.Error()
+				return msg
+			}
+		}
+		to, err := c.Tree()
+		if err != nil {
+			msg.diffBody = "go-git can't read the tree: " + err.Error()
+			return msg
+		}
+		changes, err := object.DiffTreeWithOptions(context.Background(), from, to, object.DefaultDiffTreeOptions)
+		if err != nil {
+			msg.diffBody = "go-git can't diff the commit: " + err.Error()
+			return msg
+		}
+		patch, err := changes.Patch()
+		if err != nil {
+			msg.diffBody = "go-git can't diff the commit: " + err.Error()
+			return msg
+		}
+
+		diffLines := strings.Split(summarizeLFSDiff(patch.String()), "\n")
+		if len(diffLines) > 2000 {
+			diffLines = append(diffLines[:2000], "... (truncated)")
+		}
+		msg.diffBody = strings.Join(diffLines, "\n")
+		for _, fp := range patch.FilePatches() {
+			msg.files = append(msg.files, goGitFileChange(fp))
+		}
+		return msg
+	}
+}
+
+// goGitFileChange is what --name-status and --numstat tell of a file.
+func goGitFileChange(fp diff.FilePatch) fileChange {
+	from, to := fp.Files()
+	var fc fileChange
+	switch {
+	case from == nil:
+		fc.Status, fc.Path = "A", to.Path()
+	case to == nil:
+		fc.Status, fc.Path = "D", from.Path()
+	case from.Path() != to.Path():
+		fc.Status, fc.Path, fc.OldPath = "R", to.Path(), from.Path()
+	default:
+		fc.Status, fc.Path = "M", to.Path()
+	}
+	if fp.IsBinary() {
+		fc.Binary = true
+		return fc
+	}
+	for _, chunk := range fp.Chunks() {
+		lines := strings.Count(chunk.Content(), "\n")
+		if !strings.HasSuffix(chunk.Content(), "\n") {
+			lines++
+		}
+		switch chunk.Type() {
+		case diff.Add:
+			fc.Added += lines
+		case diff.Delete:
+			fc.Deleted += lines
+		}
+	}
+	return fc
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// B on the Diff tab blames the hunk at the top of it: the lines it
+// removes and the context around them, as they were before the commit,
+// each with the commit that last changed it. That is who wrote the code
+// the change modifies, without leaving the diff. Commits listed to be
+// ignored, as reformatting ones, are passed over, as git blame and forges
+// do; I shows what they changed again.
+
+// blameHunk is the part of a file's pre-image a hunk covers.
+type blameHunk struct {
+	rev, path   string
+	start, size int    // old lines, as in @@ -start,size
+	focus       int    // the line at the top of the Diff tab, 0 when it isn't an old one
+	removed     []bool // of each old line, whether the hunk removes it
+}
+
+// blameLine is a line of git blame --line-porcelain.
+type blameLine struct {
+	hash, author string
+	time         time.Time
+	summary      string
+	text         string
+}
+
+type blameMsg struct {
+	hunk        blameHunk
+	lines       []blameLine
+	ignoreRevs  string // the file of commits to ignore, "" without one
+	showIgnored bool   // the commits in it were blamed all the same
+	err         error
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,\d+)? @@`)
+
+// diffHunkAt finds the hunk of a diff at line top, or the first one after
+// it above the first hunk of a file, and the file it is of.
+func diffHunkAt(diff string, top int) (hunk blameHunk, ok bool) {
+	lines := strings.Split(diff, "\n")
+	outline := outlineDiff(diff)
+	at := -1
+	for _, h := range outline.hunks {
+		// A hunk ends at the next file header
+		if h <= top && !fileBetween(outline.files, h, top) {
+			at = h
+		}
+	}
+	if at < 0 {
+		for _, h := range outline.hunks {
+			if h > top {
+				at = h
+				break
+			}
+		}
+	}
+	if at < 0 {
+		return blameHunk{}, false
+	}
+
+	match := hunkHeader.FindStringSubmatch(lines[at])
+	if match == nil {
+		// A combined diff's @@@ hunks have more than one old side
+		return blameHunk{}, false
+	}
+	hunk.start, _ = strconv.Atoi(match[1])
+	hunk.size = 1
+	if match[2] != "" {
+		hunk.size, _ = strconv.Atoi(match[2])
+	}
+	for i := at - 1; i >= 0 && !strings.HasPrefix(lines[i], "diff "); i-- {
+		if old, ok := strings.CutPrefix(lines[i], "--- "); ok {
+			hunk.path = strings.TrimPrefix(strings.TrimRight(old, "\t"), "a/")
+		}
+	}
+	if hunk.path == "" || hunk.path == "/dev/null" {
+		hunk.path = ""
+	}
+
+	old := hunk.start
+	for i := at + 1; i < len(lines) && len(hunk.removed) < hunk.size; i++ {
+		line := lines[i]
+		if line == "" || (line[0] != ' ' && line[0] != '-') {
+			if line != "" && line[0] != '+' && line[0] != '\\' {
+				break
+			}
+			continue
+		}
+		if i == top {
+			hunk.focus = old
+		}
+		hunk.removed = append(hunk.removed, line[0] == '-')
+		old++
+	}
+	return hunk, true
+}
+
+// fileBetween tells whether a file header lies after line from, up to to.
+func fileBetween(files []int, from, to int) bool {
+	for _, f := range files {
+		if f > from && f <= to {
+			return true
+		}
+	}
+	return false
+}
+
+// blameTopHunk blames the hunk at the top of the Diff tab.
+func (m *model) blameTopHunk() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := &m.commits[m.selected]
+	start := m.diffStart(c)
+	if start < 0 {
+		m.status, m.statusErr = "No diff to blame", true
+		return nil
+	}
+	switch {
+	case len(c.Parents) == 0:
+		m.status, m.statusErr = "A root commit's lines are all new", true
+		return nil
+	case len(c.Parents) > 1 && c.DiffParent == 0:
+		m.status, m.statusErr = "Press m to diff against one parent, then blame that", true
+		return nil
+	}
+	hunk, ok := diffHunkAt(m.visibleDiff(c), m.detailsScroll[tabDiff]-start)
+	switch {
+	case !ok:
+		m.status, m.statusErr = "No hunk to blame here", true
+		return nil
+	case hunk.path == "":
+		m.status, m.statusErr = "The file is new in this commit; there is nothing before it to blame", true
+		return nil
+	case hunk.size == 0:
+		m.status, m.statusErr = "The hunk only adds lines; there is nothing before it to blame", true
+		return nil
+	}
+	hunk.rev = c.Parents[max(c.DiffParent, 1)-1]
+	m.status = "Blaming " + hunk.path + "…"
+	return loadBlame(m.repoPath, hunk, false)
+}
+
+// loadBlame runs git blame over the old lines of a hunk, passing over
+// the commits to ignore unless showIgnored is set.
+func loadBlame(repoPath string, hunk blameHunk, showIgnored bool) tea.Cmd {
+	return func() tea.Msg {
+		msg := blameMsg{hunk: hunk, showIgnored: showIgnored}
+		args := []string{"blame", "--line-porcelain"}
+		file, configured := blameIgnoreRevsFile(repoPath)
+		switch {
+		case showIgnored && file != "":
+			// An empty file name clears the list, blame.ignoreRevsFile's too
+			args = append(args, "--ignore-revs-file=")
+		case file != "" && !configured:
+			args = append(args, "--ignore-revs-file", file)
+		}
+		msg.ignoreRevs = file
+		args = append(args, fmt.Sprintf("-L%d,+%d", hunk.start, hunk.size), hunk.rev, "--", hunk.path)
+		out, err := gitRead(repoPath, args...)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		msg.lines = parseBlame(out)
+		return msg
+	}
+}
+
+// blameIgnoreRevsFile is the file of commits for blame to ignore: git's
+// blame.ignoreRevsFile, which git blame reads itself, or else the
+// .git-blame-ignore-revs at the top of the work tree that GitHub and
+// GitLab go by. It is "" when there is neither.
+func blameIgnoreRevsFile(repoPath string) (file string, configured bool) {
+	if file, _ := gitRead(repoPath, "config", "--get", "blame.ignoreRevsFile"); file != "" {
+		return file, true
+	}
+	top, _ := gitRead(repoPath, "rev-parse", "--show-toplevel")
+	if top == "" {
+		return "", false
+	}
+	file = filepath.Join(top, ".git-blame-ignore-revs")
+	if _, err := os.Stat(file); err != nil {
+		return "", false
+	}
+	return file, false
+}
+
+// parseBlame reads git blame --line-porcelain: a header line with the
+// hash, the commit's fields, and the line itself after a tab.
+func parseBlame(out string) []blameLine {
+	var lines []blameLine
+	var cur blameLine
+	for _, line := range strings.Split(out, "\n") {
+		switch key, value, _ := strings.Cut(line, " "); {
+		case strings.HasPrefix(line, "\t"):
+			cur.text = line[1:]
+			lines = append(lines, cur)
+			cur = blameLine{}
+		case len(key) == 40 && cur.hash == "":
+			cur.hash = key
+		case key == "author":
+			cur.author = value
+		case key == "author-time":
+			secs, _ := strconv.ParseInt(value, 10, 64)
+			cur.time = time.Unix(secs, 0)
+		case key == "summary":
+			cur.summary = value
+		}
+	}
+	return lines
+}
+
+// blameMenu shows a blamed hunk, the line at the top of the Diff tab
+// pointed out, and offers to jump to the commits that wrote it.
+func (m *model) blameMenu(msg blameMsg) *menu {
+	hunk := msg.hunk
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Blame"))
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render(fmt.Sprintf("%s before %s, lines %d-%d", hunk.path, hunk.rev, hunk.start, hunk.start+hunk.size-1)))
+	sb.WriteString("\n\n")
+
+	// The commits, in the order they first appear, each with a key
+	var hashes, names []string
+	seen := map[string]bool{}
+	for _, l := range msg.lines {
+		if !seen[l.hash] {
+			seen[l.hash] = true
+			hashes = append(hashes, l.hash)
+			names = append(names, l.summary)
+		}
+	}
+	keys := pickerKeys(names)
+	keyOf := map[string]string{}
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	var options []menuOption
+	for i, hash := range hashes {
+		if keys[i] == "" {
+			continue
+		}
+		keyOf[hash] = keys[i]
+		options = append(options, menuOption{key: keys[i], action: func(m *model) tea.Cmd {
+			if j := m.commitIndex(hash); j >= 0 {
+				return m.jumpTo(j)
+			}
+			m.status, m.statusErr = m.shortOf(hash)+" isn't in the graph", true
+			return nil
+		}})
+	}
+
+	authorWidth := 0
+	for _, l := range msg.lines {
+		authorWidth = max(authorWidth, min(lipgloss.Width(l.author), 16))
+	}
+	removedStyle := m.cfg.Colors.Old
+	for i, l := range msg.lines {
+		n := hunk.start + i
+		pointer := "  "
+		if n == hunk.focus {
+			pointer = keyStyle.Render("> ")
+		}
+		key := keyStyle.Render(fmt.Sprintf("%1s", keyOf[l.hash]))
+		text := l.text
+		if i < len(hunk.removed) && hunk.removed[i] {
+			text = removedStyle.Render("-" + text)
+		} else {
+			text = " " + text
+		}
+		author := truncate(l.author, 16)
+		sb.WriteString(fmt.Sprintf("%s%s %s %s %s %s %s\n", pointer, key,
+			helpStyle.Render(fmt.Sprintf("%4d", n)),
+			commitHashStyle.Render(m.shortOf(l.hash)),
+			authorStyle.Render(author+strings.Repeat(" ", authorWidth-lipgloss.Width(author))),
+			helpStyle.Render(l.time.Format("2006-01-02")),
+			text))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("Press a commit's key to jump to it."))
+	if msg.ignoreRevs != "" {
+		label, note := "show ignored commits", "The commits in "+filepath.Base(msg.ignoreRevs)+" are passed over."
+		if msg.showIgnored {
+			label, note = "pass over ignored commits", "The commits in "+filepath.Base(msg.ignoreRevs)+" are shown."
+		}
+		sb.WriteString("\n" + helpStyle.Render(note))
+		options = append(options, menuOption{key: "I", label: label, action: func(m *model) tea.Cmd {
+			m.status = "Blaming " + hunk.path + "…"
+			return loadBlame(m.repoPath, hunk, !msg.showIgnored)
+		}})
+	}
+	return &menu{title: "Blame of " + hunk.path, options: options, detail: sb.String()}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffHunkAt(t *testing.T) {
+	diff := "diff --git a/f b/f\n" + // 0
+		"--- a/f\n" +
+		"+++ b/f\n" +
+		"@@ -3,4 +3,4 @@ func f() {\n" + // 3
+		" three\n" +
+		"-four\n" + // 5
+		"+FOUR\n" +
+		" five\n" +
+		"-six\n" +
+		"diff --git a/g b/g\n" + // 9
+		"--- a/g\n" +
+		"+++ b/g\n" +
+		"@@ -1 +1,2 @@\n" + // 12
+		" one\n" +
+		"+two\n"
+
+	tests := []struct {
+		top  int
+		want blameHunk
+	}{
+		{0, blameHunk{path: "f", start: 3, size: 4, removed: []bool{false, true, false, true}}},
+		{5, blameHunk{path: "f", start: 3, size: 4, focus: 4, removed: []bool{false, true, false, true}}},
+		{6, blameHunk{path: "f", start: 3, size: 4, removed: []bool{false, true, false, true}}},
+		{10, blameHunk{path: "g", start: 1, size: 1, removed: []bool{false}}},
+		{13, blameHunk{path: "g", start: 1, size: 1, focus: 1, removed: []bool{false}}},
+	}
+	for _, tt := range tests {
+		got, ok := diffHunkAt(diff, tt.top)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("diffHunkAt(top %d) = %+v, %v; want %+v", tt.top, got, ok, tt.want)
+		}
+	}
+}
+
+func TestBlameIgnoreRevs(t *testing.T) {
+	d := testRepo(t)
+	d.commit("f", "one\ntwo\n", "Write f")
+	d.commit("f", "one \ntwo \n", "Reformat f")
+	reformat := gitOutput(t, d.dir, "rev-parse", "HEAD")
+	d.commit(".git-blame-ignore-revs", reformat+"\n", "Ignore the reformat")
+	d.commit("f", "ONE \ntwo \n", "Change f")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+
+	hunk := blameHunk{rev: "HEAD", path: "f", start: 1, size: 2}
+	summaries := func(showIgnored bool) []string {
+		msg := loadBlame(d.dir, hunk, showIgnored)().(blameMsg)
+		if msg.err != nil {
+			t.Fatal(msg.err)
+		}
+		if msg.ignoreRevs == "" {
+			t.Error("the blame has no file of commits to ignore")
+		}
+		var got []string
+		for _, l := range msg.lines {
+			got = append(got, l.summary)
+		}
+		return got
+	}
+	if got, want := summaries(false), []string{"Change f", "Write f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("blame passing over the reformat = %q, want %q", got, want)
+	}
+	if got, want := summaries(true), []string{"Change f", "Reformat f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("blame showing the reformat = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// goneStyle marks a branch whose upstream was deleted on the remote,
+// usually once its pull request was merged.
+var goneStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A"))
+
+// aheadStyle shows how far a branch and its upstream went apart.
+var aheadStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B"))
+
+// branchInfo is a local branch and how it stands against its upstream.
+type branchInfo struct {
+	name          string
+	current       bool
+	upstream      string // short name, empty without one
+	gone          bool   // the upstream is set but no longer exists
+	ahead, behind int    // commits not on the upstream, and on it but not here
+	merged        bool   // in HEAD already, so git branch -d deletes it
+}
+
+type branchesMsg struct {
+	branches []branchInfo
+	err      error
+}
+
+// loadBranches lists the local branches with their upstreams, from the
+// last fetch, and which are merged into HEAD.
+func loadBranches(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "for-each-ref", "--format=%(HEAD)%00%(refname:short)%00%(upstream:short)%00%(upstream:track,nobracket)", "refs/heads")
+		if err != nil {
+			return branchesMsg{err: err}
+		}
+		merged := map[string]bool{}
+		if list, err := gitRead(repoPath, "for-each-ref", "--merged=HEAD", "--format=%(refname:short)", "refs/heads"); err == nil {
+			for _, name := range strings.Split(list, "\n") {
+				merged[name] = true
+			}
+		}
+
+		var branches []branchInfo
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 4 {
+				continue
+			}
+			b := branchInfo{name: fields[1], current: fields[0] == "*", upstream: fields[2], merged: merged[fields[1]]}
+			// "ahead 2, behind 1", "gone", or nothing when in sync
+			for _, part := range strings.Split(fields[3], ", ") {
+				switch {
+				case part == "gone":
+					b.gone = true
+				case strings.HasPrefix(part, "ahead "):
+					fmt.Sscan(strings.TrimPrefix(part, "ahead "), &b.ahead)
+				case strings.HasPrefix(part, "behind "):
+					fmt.Sscan(strings.TrimPrefix(part, "behind "), &b.behind)
+				}
+			}
+			branches = append(branches, b)
+		}
+		return branchesMsg{branches: branches}
+	}
+}
+
+// branchesMenu shows every local branch against its upstream, and
+// offers to delete the merged ones, other than protected branches, or
+// look at where one pointed before.
+func branchesMenu(branches []branchInfo, cfg config) *menu {
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Local branches"))
+	sb.WriteString("\n\n")
+	if len(branches) == 0 {
+		sb.WriteString(helpStyle.Render("No local branches."))
+		sb.WriteString("\n")
+	}
+	nameWidth, upstreamWidth := 0, 0
+	for _, b := range branches {
+		nameWidth = max(nameWidth, len(b.name))
+		upstreamWidth = max(upstreamWidth, len(b.upstream))
+	}
+	var deletable []string
+	gone := 0
+	for _, b := range branches {
+		marker := "  "
+		if b.current {
+			marker = "> "
+		}
+		line := marker + branchStyle.Render(fmt.Sprintf("%-*s", nameWidth, b.name)) + "  "
+		upstream := fmt.Sprintf("%-*s", upstreamWidth, b.upstream)
+		switch {
+		case b.upstream == "":
+			line += helpStyle.Render(fmt.Sprintf("%-*s", upstreamWidth, "no upstream"))
+		case b.gone:
+			line += goneStyle.Render(upstream + "  gone")
+			gone++
+		case b.ahead > 0 || b.behind > 0:
+			line += authorStyle.Render(upstream) + aheadStyle.Render(fmt.Sprintf("  ↑%d ↓%d", b.ahead, b.behind))
+		default:
+			line += authorStyle.Render(upstream) + helpStyle.Render("  up to date")
+		}
+		switch {
+		case b.merged && !b.current && cfg.protected(b.name):
+			line += helpStyle.Render("  merged, protected")
+		case b.merged && !b.current:
+			line += helpStyle.Render("  merged")
+			deletable = append(deletable, b.name)
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	if gone > 0 {
+		sb.WriteString(helpStyle.Render("Gone upstreams were deleted on the remote, typically once the branch was merged there."))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(helpStyle.Render("Ahead and behind are as of the last fetch. Merged means contained in HEAD."))
+
+	mn := &menu{title: "Branches", detail: sb.String()}
+	mn.options = append(mn.options, menuOption{key: "c", label: "clean up…", action: startCleanup})
+	mn.options = append(mn.options, menuOption{key: "r", label: "reflog…", action: func(m *model) tea.Cmd {
+		m.menu = reflogPicker(branches)
+		return nil
+	}})
+	if len(deletable) > 0 {
+		mn.options = append(mn.options, menuOption{
+			key:   "d",
+			label: fmt.Sprintf("delete %d merged", len(deletable)),
+			action: func(m *model) tea.Cmd {
+				return runGitCmd(m.repoPath, "Deleted the merged branches",
+					append([]string{"branch", "-d"}, deletable...)...)
+			},
+		})
+	}
+	return mn
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkoutInfo describes a clone that doesn't have everything locally.
+type checkoutInfo struct {
+	partial string // filter of a partial clone, e.g. blob:none; empty for a full clone
+	sparse  string // sparse-checkout summary, empty when everything is checked out
+}
+
+// loadCheckoutInfo detects partial clones, where missing objects are
+// fetched from a promisor remote on demand, and sparse checkouts.
+func loadCheckoutInfo(repoPath string) checkoutInfo {
+	var info checkoutInfo
+	// "remote.origin.partialclonefilter blob:none", or just a promisor
+	// remote in clones made before the filter was recorded
+	filters, _ := gitRead(repoPath, "config", "--get-regexp", `^remote\..*\.partialclonefilter$`)
+	promisors, _ := gitRead(repoPath, "config", "--get-regexp", `^remote\..*\.promisor$`)
+	partialClone, _ := gitRead(repoPath, "config", "extensions.partialClone")
+	if filters != "" {
+		_, info.partial, _ = strings.Cut(strings.Split(filters, "\n")[0], " ")
+	} else if promisors != "" || partialClone != "" {
+		info.partial = "promisor"
+	}
+
+	if sparse, _ := gitRead(repoPath, "config", "--bool", "core.sparseCheckout"); sparse == "true" {
+		list, _ := gitRead(repoPath, "sparse-checkout", "list")
+		cone, _ := gitRead(repoPath, "config", "--bool", "core.sparseCheckoutCone")
+		n := len(strings.Fields(list))
+		switch {
+		case cone != "false" && n == 1:
+			info.sparse = "cone, 1 dir"
+		case cone != "false":
+			info.sparse = fmt.Sprintf("cone, %d dirs", n)
+		default:
+			info.sparse = fmt.Sprintf("%d patterns", n)
+		}
+	}
+	return info
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// cleanupBranch is a local branch whose work is in the base already.
+type cleanupBranch struct {
+	name         string
+	cherryPicked bool   // not merged, but git cherry finds each patch in the base
+	remote       string // remote of the upstream, empty without one or when gone
+	remoteBranch string // the upstream's branch name on that remote
+}
+
+type cleanupMsg struct {
+	base     string
+	branches []cleanupBranch
+	err      error
+}
+
+// cleanup is the state of the cleanup menu, kept between its keys.
+type cleanup struct {
+	base     string
+	branches []cleanupBranch
+	selected map[string]bool
+	remote   bool // delete the upstreams on the remote too
+}
+
+// cleanupKeys toggle the branches in the cleanup menu; a, r and d are
+// its actions.
+var cleanupKeys = strings.Split("123456789bcefghijklmnopqstuvwxyz", "")
+
+// loadCleanup finds the local branches, other than the current one,
+// protected ones and those named keep, that are merged into base, or
+// whose patches all are in it, like after a rebase or squash merge
+// upstream. A protected upstream isn't offered for deletion either.
+func loadCleanup(repoPath, base string, protected func(branch string) bool, keep ...string) tea.Cmd {
+	return func() tea.Msg {
+		merged := map[string]bool{}
+		list, err := gitRead(repoPath, "for-each-ref", "--merged="+base, "--format=%(refname:short)", "refs/heads")
+		if err != nil {
+			return cleanupMsg{base: base, err: fmt.Errorf("%s: %w", base, err)}
+		}
+		for _, name := range strings.Split(list, "\n") {
+			merged[name] = true
+		}
+		out, err := gitRead(repoPath, "for-each-ref", "--format=%(HEAD)%00%(refname:short)%00%(upstream:remotename)%00%(upstream:remoteref)%00%(upstream:track)", "refs/heads")
+		if err != nil {
+			return cleanupMsg{base: base, err: err}
+		}
+
+		var branches []cleanupBranch
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 5 || fields[0] == "*" || slices.Contains(keep, fields[1]) || protected(fields[1]) {
+				continue
+			}
+			b := cleanupBranch{name: fields[1]}
+			if !merged[b.name] {
+				// Lines of "-" are patches the base has, "+" ones it hasn't
+				cherry, err := gitRead(repoPath, "cherry", base, b.name)
+				if err != nil || cherry == "" || strings.Contains("\n"+cherry, "\n+") {
+					continue
+				}
+				b.cherryPicked = true
+			}
+			remoteBranch := strings.TrimPrefix(fields[3], "refs/heads/")
+			if fields[2] != "" && fields[4] != "[gone]" && !protected(remoteBranch) {
+				b.remote, b.remoteBranch = fields[2], remoteBranch
+			}
+			branches = append(branches, b)
+		}
+		return cleanupMsg{base: base, branches: branches}
+	}
+}
+
+// startCleanup looks for branches to clean up against the base, or the
+// current branch without one.
+func startCleanup(m *model) tea.Cmd {
+	base, keep := m.base, []string{m.baseBranch()}
+	if base == "" {
+		base, keep = "HEAD", nil
+	}
+	m.status = "Looking for merged branches…"
+	return loadCleanup(m.repoPath, base, m.cfg.protected, keep...)
+}
+
+// cleanupMenu lists the branches to clean up, all selected at first.
+func cleanupMenu(msg cleanupMsg) *menu {
+	c := &cleanup{base: msg.base, branches: msg.branches, selected: map[string]bool{}}
+	for _, b := range c.branches {
+		c.selected[b.name] = true
+	}
+	return c.menu()
+}
+
+// menu renders the cleanup; each key that changes the selection opens
+// it again as it is now.
+func (c *cleanup) menu() *menu {
+	base := c.base
+	if base == "HEAD" {
+		base = "the current branch"
+	}
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Branches merged into " + base))
+	sb.WriteString("\n\n")
+	if len(c.branches) == 0 {
+		sb.WriteString(helpStyle.Render("None: every other local branch has work that isn't in " + base + "."))
+		return &menu{title: "Clean up", detail: sb.String()}
+	}
+
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	nameWidth := 0
+	for _, b := range c.branches {
+		nameWidth = max(nameWidth, len(b.name))
+	}
+	reopen := func(m *model) tea.Cmd {
+		m.menu = c.menu()
+		return nil
+	}
+	var options []menuOption
+	for i, b := range c.branches {
+		key := " "
+		if i < len(cleanupKeys) {
+			key = cleanupKeys[i]
+			options = append(options, menuOption{key: key, action: func(m *model) tea.Cmd {
+				c.selected[b.name] = !c.selected[b.name]
+				return reopen(m)
+			}})
+		}
+		box := "[ ]"
+		if c.selected[b.name] {
+			box = "[x]"
+		}
+		line := fmt.Sprintf("%s %s %s", keyStyle.Render(key), box, branchStyle.Render(fmt.Sprintf("%-*s", nameWidth, b.name)))
+		if b.cherryPicked {
+			line += helpStyle.Render("  cherry-picked")
+		} else {
+			line += helpStyle.Render("  merged")
+		}
+		if b.remote != "" {
+			line += "  " + authorStyle.Render(b.remote+"/"+b.remoteBranch)
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("Cherry-picked branches aren't merged, but each of their patches is in " + base + ", as after a rebase there."))
+
+	selected := c.chosen()
+	allLabel := "select all"
+	if len(selected) == len(c.branches) {
+		allLabel = "select none"
+	}
+	remoteLabel := "also delete upstreams"
+	if c.remote {
+		remoteLabel = "keep upstreams"
+	}
+	options = append(options,
+		menuOption{key: "a", label: allLabel, action: func(m *model) tea.Cmd {
+			all := len(c.chosen()) < len(c.branches)
+			for _, b := range c.branches {
+				c.selected[b.name] = all
+			}
+			return reopen(m)
+		}},
+		menuOption{key: "r", label: remoteLabel, action: func(m *model) tea.Cmd {
+			c.remote = !c.remote
+			return reopen(m)
+		}},
+	)
+	if len(selected) > 0 {
+		options = append(options, menuOption{key: "d", label: fmt.Sprintf("delete %d", len(selected)), action: func(m *model) tea.Cmd {
+			return c.delete(m.repoPath)
+		}})
+	}
+	return &menu{title: "Clean up", options: options, detail: sb.String()}
+}
+
+// chosen are the selected branches, in the order listed.
+func (c *cleanup) chosen() []cleanupBranch {
+	var chosen []cleanupBranch
+	for _, b := range c.branches {
+		if c.selected[b.name] {
+			chosen = append(chosen, b)
+		}
+	}
+	return chosen
+}
+
+func branchNames(branches []cleanupBranch) []string {
+	names := make([]string, len(branches))
+	for i, b := range branches {
+		names[i] = b.name
+	}
+	return names
+}
+
+// delete deletes the selected branches, then their upstreams when asked
+// to, with one push per remote.
+func (c *cleanup) delete(repoPath string) tea.Cmd {
+	chosen := c.chosen()
+	remoteBranches := map[string][]string{}
+	for _, b := range chosen {
+		if c.remote && b.remote != "" {
+			remoteBranches[b.remote] = append(remoteBranches[b.remote], b.remoteBranch)
+		}
+	}
+	names := branchNames(chosen)
+	cmds := []tea.Cmd{runGitCmd(repoPath, "Deleted "+strings.Join(names, ", "), append([]string{"branch", "-D"}, names...)...)}
+	remotes := make([]string, 0, len(remoteBranches))
+	for remote := range remoteBranches {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	for _, remote := range remotes {
+		cmds = append(cmds, runRemoteCmd(repoPath, "Deleted "+strings.Join(remoteBranches[remote], ", ")+" on "+remote,
+			append([]string{"push", remote, "--delete"}, remoteBranches[remote]...)...))
+	}
+	return guardedSequence(cmds...)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// options are the command line settings. Settings that can also be made in
+// git config (see config) override it when given.
+type options struct {
+	repoPath string
+	ref      string
+	revRange string
+	exclude  []string
+	base     string
+	backend  string
+	gitDir   string
+	workTree string
+	present  bool
+	profile  bool
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// parseArgs parses `gitraffe [flags] [path] [range]`. Unlike the flag
+// package's default, flags may also follow the positional arguments. A
+// positional argument that isn't a directory is taken as the range, once
+// the repository has the revisions it names, so that a mistyped path
+// isn't reported by git later on as a bad revision.
+func parseArgs(args []string) (options, error) {
+	var opts options
+	fs := newFlagSet(&opts)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return opts, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	var rangeArg string
+	for _, arg := range positional {
+		if info, err := os.Stat(normalizeRepoPath(arg)); err == nil && info.IsDir() && opts.repoPath == "" {
+			opts.repoPath = normalizeRepoPath(arg)
+		} else if opts.revRange == "" {
+			opts.revRange, rangeArg = arg, arg
+		} else {
+			err := fmt.Errorf("unexpected argument %q", arg)
+			fmt.Fprintln(fs.Output(), err)
+			fs.Usage()
+			return opts, err
+		}
+	}
+	if err := applyGitDir(&opts); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return opts, err
+	}
+	if opts.repoPath == "" {
+		opts.repoPath = "."
+	}
+	if rangeArg != "" && !isRevRange(opts.repoPath, rangeArg) {
+		err := fmt.Errorf("%s: not a directory or revision", rangeArg)
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return opts, err
+	}
+	if opts.backend != "" {
+		if _, err := parseBackend(opts.backend); err != nil {
+			fmt.Fprintln(fs.Output(), err)
+			fs.Usage()
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+// isRevRange tells whether arg is a revision of the repository, or a
+// range of two like v1.2.0..HEAD or main...topic, where an end left out
+// is HEAD.
+func isRevRange(repoPath, arg string) bool {
+	ends := strings.SplitN(arg, "...", 2)
+	if len(ends) == 1 {
+		ends = strings.SplitN(arg, "..", 2)
+	}
+	for _, end := range ends {
+		if end == "" {
+			continue
+		}
+		if _, err := gitRead(repoPath, "rev-parse", "--verify", "--quiet", end+"^{commit}"); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// newFlagSet defines the flags, setting opts. The man page is generated
+// from it too.
+func newFlagSet(opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("gitraffe", flag.ContinueOnError)
+	fs.StringVar(&opts.ref, "ref", "", "start the graph at `ref` instead of showing all refs")
+	fs.StringVar(&opts.ref, "branch", "", "same as -ref, for starting at a `branch`")
+	fs.StringVar(&opts.revRange, "range", "", "only show the commits in `revspec`, e.g. v1.2.0..HEAD")
+	fs.StringVar(&opts.base, "base", "", "compare local branches with `ref`, e.g. origin/main, for stacked branches")
+	fs.StringVar(&opts.backend, "backend", "", "load the history and diffs with `backend`: auto (git log --graph, else a plain git log), cli (never go-git) or go-git")
+	fs.StringVar(&opts.gitDir, "git-dir", "", "use the repository at `path`, like git's --git-dir or GIT_DIR, which is honored too")
+	fs.StringVar(&opts.workTree, "work-tree", "", "use `path` as the work tree, like git's --work-tree or GIT_WORK_TREE, which is honored too")
+	fs.BoolVar(&opts.present, "present", false, "presentation mode: only the graph with refs and subjects, read-only, following HEAD")
+	fs.BoolVar(&opts.profile, "profile", false, "write CPU and heap profiles and the startup timings to the log directory, for performance issues")
+	fs.Var((*stringList)(&opts.exclude), "exclude", "hide refs matching `pattern` (e.g. refs/tags/nightly-*) from the all-refs graph; repeatable")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gitraffe [flags] [path] [range]\n       gitraffe help [topic]\n       gitraffe large-files [path]\n       gitraffe demo [path]\n       gitraffe render [-width n] [-height n] [-frame key=value] [path]\n       gitraffe export [-format svg|png] [-range revspec] [-o file] [path]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// exitUsage exits after a parseArgs error, which has already been reported
+// along with the usage text: -h exits cleanly, anything else with the
+// conventional usage status.
+func exitUsage(err error) {
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	os.Exit(2)
+}
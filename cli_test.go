@@ -0,0 +1,35 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestParseArgsRange checks that a positional argument is taken as the
+// range only when the repository has the revisions it names.
+func TestParseArgsRange(t *testing.T) {
+	dir := demoFixture(t)
+	tests := []struct {
+		arg string
+		ok  bool
+	}{
+		{"main", true},
+		{"HEAD~3..HEAD", true},
+		{"HEAD~3..", true},
+		{"main...HEAD~2", true},
+		{"mian", false},
+		{"HEAD..mian", false},
+		{filepath.Join(dir, "no-such-dir"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			opts, err := parseArgs([]string{dir, tt.arg})
+			if ok := err == nil; ok != tt.ok {
+				t.Fatalf("error %v, want ok %v", err, tt.ok)
+			}
+			if tt.ok && opts.revRange != tt.arg {
+				t.Errorf("range %q, want %q", opts.revRange, tt.arg)
+			}
+		})
+	}
+}
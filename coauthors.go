@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// coAuthorTrailer is the trailer naming the other authors of a commit,
+// however its key is capitalized.
+const coAuthorTrailer = "^[Cc]o-[Aa]uthored-[Bb]y:.*"
+
+// coAuthoredHistory lists the commits of a filter with author terms
+// when the Co-authored-by trailers widen it: the commits by one of the
+// authors, and those with one as a co-author that match the rest of the
+// filter. git log can't give both in one walk, as it ANDs --author with
+// --grep, so the co-authored ones come from walks of their own. It is
+// nil when the filter has no author terms or no co-author adds a commit,
+// and the graph loads as usual.
+func (m *model) coAuthoredHistory() ([]string, error) {
+	if m.filter == nil || m.highlight {
+		return nil, nil
+	}
+	isAuthor := func(arg string) bool { return strings.HasPrefix(arg, "--author=") }
+	isGrep := func(arg string) bool { return strings.HasPrefix(arg, "--grep=") }
+	var coAuthors []string
+	for _, arg := range m.filter.args {
+		if author, ok := strings.CutPrefix(arg, "--author="); ok {
+			coAuthors = append(coAuthors, "--grep="+coAuthorTrailer+author)
+		}
+	}
+	if len(coAuthors) == 0 {
+		return nil, nil
+	}
+
+	scope := m.logScope()
+	authored, err := m.filterHashes(scope)
+	if err != nil {
+		return nil, err
+	}
+	// Any of the co-authors, and none of the filter's own greps: they
+	// would have to match together with a trailer
+	rest := slices.DeleteFunc(slices.Clone(scope), isAuthor)
+	coAuthored, err := m.filterHashes(append(slices.DeleteFunc(slices.Clone(rest), isGrep), coAuthors...))
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(m.filter.args, isGrep) {
+		grepped, err := m.filterHashes(rest)
+		if err != nil {
+			return nil, err
+		}
+		matches := make(map[string]bool, len(grepped))
+		for _, h := range grepped {
+			matches[h] = true
+		}
+		coAuthored = slices.DeleteFunc(coAuthored, func(h string) bool { return !matches[h] })
+	}
+
+	all := authored
+	listed := make(map[string]bool, len(authored))
+	for _, h := range authored {
+		listed[h] = true
+	}
+	for _, h := range coAuthored {
+		if !listed[h] {
+			all = append(all, h)
+		}
+	}
+	if len(all) == len(authored) {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// filterHashes lists the full hashes git log gives for the scope args
+// and the filter's paths.
+func (m *model) filterHashes(scope []string) ([]string, error) {
+	const maxCommits = 5000
+	args := append([]string{"log", "--format=%H", fmt.Sprintf("-n%d", maxCommits)}, scope...)
+	out, err := gitRead(m.repoPath, append(append(args, "--"), m.filterPaths()...)...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// colors are the styles of refs and diffs. Any set in git's own color
+// config, color.decorate.<slot> and color.diff.<slot>, are taken from
+// there, so they look as in git log and git diff; the rest are
+// gitraffe's.
+type colors struct {
+	Head   lipgloss.Style // color.decorate.HEAD
+	Branch lipgloss.Style // color.decorate.branch
+	Remote lipgloss.Style // color.decorate.remoteBranch
+	Tag    lipgloss.Style // color.decorate.tag
+	Stash  lipgloss.Style // color.decorate.stash
+
+	Meta    lipgloss.Style // color.diff.meta: diff, index, ---, +++ and mode lines
+	Frag    lipgloss.Style // color.diff.frag: the @@ of a hunk header
+	Func    lipgloss.Style // color.diff.func: the function after it
+	Old     lipgloss.Style // color.diff.old
+	New     lipgloss.Style // color.diff.new
+	Context lipgloss.Style // color.diff.context
+}
+
+var defaultColors = colors{
+	Head:   branchStyle,
+	Branch: branchStyle,
+	Remote: authorStyle,
+	Tag:    commitHashStyle,
+	Stash:  branchStyle,
+
+	Meta:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#E5E9F0")),
+	Frag:    lipgloss.NewStyle().Foreground(lipgloss.Color("#5E81AC")),
+	Func:    lipgloss.NewStyle().Foreground(lipgloss.Color("#5E81AC")),
+	Old:     lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A")),
+	New:     lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C")),
+	Context: lipgloss.NewStyle(),
+}
+
+// set reads a color.decorate.* or color.diff.* key, lowercased as git
+// prints it. Slots gitraffe has no use for, like color.diff.whitespace,
+// are left alone.
+func (c *colors) set(key, value string) {
+	slots := map[string]*lipgloss.Style{
+		"color.decorate.head":         &c.Head,
+		"color.decorate.branch":       &c.Branch,
+		"color.decorate.remotebranch": &c.Remote,
+		"color.decorate.tag":          &c.Tag,
+		"color.decorate.stash":        &c.Stash,
+		"color.diff.meta":             &c.Meta,
+		"color.diff.frag":             &c.Frag,
+		"color.diff.func":             &c.Func,
+		"color.diff.old":              &c.Old,
+		"color.diff.new":              &c.New,
+		"color.diff.context":          &c.Context,
+		"color.diff.plain":            &c.Context, // the old name of context
+	}
+	slot, ok := slots[key]
+	if !ok {
+		return
+	}
+	style, err := parseGitColor(value)
+	if err != nil {
+		log.Printf("Ignoring %s: %v\n", key, err)
+		return
+	}
+	*slot = style
+}
+
+// gitColorNames are the colors git knows by name, as ANSI colors.
+var gitColorNames = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3, "blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+}
+
+// gitAttributes are git's color attributes, each of which can be turned
+// off again with a "no" or "no-" in front.
+var gitAttributes = map[string]func(lipgloss.Style, bool) lipgloss.Style{
+	"bold":    lipgloss.Style.Bold,
+	"dim":     lipgloss.Style.Faint,
+	"italic":  lipgloss.Style.Italic,
+	"ul":      lipgloss.Style.Underline,
+	"blink":   lipgloss.Style.Blink,
+	"reverse": lipgloss.Style.Reverse,
+	"strike":  lipgloss.Style.Strikethrough,
+}
+
+// parseGitColor reads a git color value, like "bold red", "reverse
+// 214 #1e1e2e" or "ul brightblue": attributes, and a foreground and a
+// background color, by name, as one of the 256 ANSI colors or as
+// #rrggbb. "normal" and "default" leave a color as the terminal has it.
+func parseGitColor(value string) (lipgloss.Style, error) {
+	style := lipgloss.NewStyle()
+	colorsSeen := 0
+	for _, word := range strings.Fields(strings.ToLower(value)) {
+		if word == "reset" {
+			style = lipgloss.NewStyle()
+			continue
+		}
+		attr, off := strings.CutPrefix(word, "no")
+		if off {
+			attr = strings.TrimPrefix(attr, "-")
+		}
+		if set, ok := gitAttributes[attr]; ok {
+			style = set(style, !off)
+			continue
+		}
+
+		var color lipgloss.TerminalColor
+		name, bright := strings.CutPrefix(word, "bright")
+		if n, ok := gitColorNames[name]; ok {
+			if bright {
+				n += 8
+			}
+			color = lipgloss.Color(strconv.Itoa(n))
+		} else if n, err := strconv.Atoi(word); err == nil && n >= 0 && n <= 255 {
+			color = lipgloss.Color(word)
+		} else if word == "normal" || word == "default" {
+			color = lipgloss.NoColor{}
+		} else if strings.HasPrefix(word, "#") && (len(word) == 7 || len(word) == 4) {
+			color = lipgloss.Color(word)
+		} else {
+			return style, fmt.Errorf("%q is not a color or attribute", word)
+		}
+		switch colorsSeen {
+		case 0:
+			style = style.Foreground(color)
+		case 1:
+			style = style.Background(color)
+		default:
+			return style, fmt.Errorf("more than two colors in %q", value)
+		}
+		colorsSeen++
+	}
+	return style, nil
+}
+
+// styleRefs styles decorations like "HEAD -> main, origin/main, tag:
+// v1.2.3, +27 more" one ref at a time, each by its kind, as git log
+// does.
+func (m *model) styleRefs(refs string) string {
+	c := m.cfg.Colors
+	parts := strings.Split(refs, ", ")
+	for i, ref := range parts {
+		switch {
+		case strings.HasPrefix(ref, "+") && strings.HasSuffix(ref, " more"):
+			parts[i] = helpStyle.Render(ref)
+		case strings.HasPrefix(ref, "HEAD -> "):
+			parts[i] = c.Head.Render("HEAD -> ") + c.Branch.Render(strings.TrimPrefix(ref, "HEAD -> "))
+		case strings.HasPrefix(ref, "HEAD"):
+			parts[i] = c.Head.Render(ref)
+		case strings.HasPrefix(ref, "tag: "):
+			parts[i] = c.Tag.Render(ref)
+		case ref == "refs/stash":
+			parts[i] = c.Stash.Render(ref)
+		case m.isRemoteRef(ref):
+			parts[i] = c.Remote.Render(ref)
+		default:
+			parts[i] = c.Branch.Render(ref)
+		}
+		parts[i] = m.hyperlink(m.refURL(ref), parts[i])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// colorizeDiff styles the lines of a unified diff.
+func (c colors) colorizeDiff(diff string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			sb.WriteString(c.New.Render(line))
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			sb.WriteString(c.Old.Render(line))
+		case strings.HasPrefix(line, "@@"):
+			// The function git found for the hunk follows the second @@
+			frag, fn := line, ""
+			if i := strings.Index(line[2:], "@@"); i >= 0 {
+				frag, fn = line[:i+4], line[i+4:]
+			}
+			sb.WriteString(c.Frag.Render(frag))
+			if fn != "" {
+				sb.WriteString(c.Func.Render(fn))
+			}
+		case isDiffMeta(line):
+			sb.WriteString(c.Meta.Render(line))
+		case line == "":
+		default:
+			sb.WriteString(c.Context.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// diffMetaPrefixes start the lines of a diff's file header.
+var diffMetaPrefixes = []string{
+	"diff ", "index ", "--- ", "+++ ", "old mode ", "new mode ", "deleted file mode ", "new file mode ",
+	"similarity index ", "dissimilarity index ", "rename from ", "rename to ", "copy from ", "copy to ",
+}
+
+func isDiffMeta(line string) bool {
+	for _, prefix := range diffMetaPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Conventional commit message line lengths, drawn as guides above the
+// editor.
+const (
+	subjectWidth = 50
+	bodyWidth    = 72
+)
+
+// commitEditor is the commit message screen, shown in place of the working
+// tree diff.
+type commitEditor struct {
+	input   textarea.Model
+	amend   bool
+	signoff bool
+	running bool           // git commit (and its hooks) in progress
+	reword  *rewriteTarget // the commit whose message this is, when rewording
+}
+
+func newCommitEditor(draft string) *commitEditor {
+	ta := textarea.New()
+	ta.Prompt = ""
+	ta.ShowLineNumbers = false
+	ta.MaxHeight = 0
+	ta.Placeholder = "Subject line, a blank line, then the body"
+	ta.Cursor.SetMode(cursor.CursorStatic)
+	ta.SetValue(draft)
+	ta.Focus()
+	return &commitEditor{input: ta}
+}
+
+// startFrom replaces the message with a template and puts the cursor on
+// the first line, after prefix.
+func (ed *commitEditor) startFrom(prefix, template string) {
+	ed.input.SetValue(template)
+	for i := 0; i < ed.input.LineCount(); i++ {
+		ed.input.CursorUp()
+	}
+	ed.input.CursorStart()
+	ed.input.InsertString(prefix)
+}
+
+// commitTemplate is a message to start a commit from.
+type commitTemplate struct {
+	name string
+	text string
+}
+
+type commitTemplatesMsg []commitTemplate
+
+// loadCommitTemplates reads git's commit.template followed by the
+// gitraffe.commitTemplate files. Relative paths are taken from the
+// repository, like git does when run there.
+func loadCommitTemplates(repoPath string, extra []string) tea.Cmd {
+	return func() tea.Msg {
+		var paths []string
+		if out, err := gitRead(repoPath, "config", "--path", "--get", "commit.template"); err == nil {
+			paths = append(paths, out)
+		}
+		paths = append(paths, extra...)
+
+		var templates []commitTemplate
+		for _, path := range paths {
+			if rest, ok := strings.CutPrefix(path, "~/"); ok {
+				if home, err := os.UserHomeDir(); err == nil {
+					path = filepath.Join(home, rest)
+				}
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(repoPath, path)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Printf("Skipping commit template: %v\n", err)
+				continue
+			}
+			templates = append(templates, commitTemplate{name: filepath.Base(path), text: string(data)})
+		}
+		return commitTemplatesMsg(templates)
+	}
+}
+
+// startCommit opens the commit screen. A saved draft is picked up as is;
+// otherwise the message starts from a template, chosen from a menu when
+// there are several, and with gitraffe.conventionalCommits a type and
+// scope are picked first.
+func (m *model) startCommit(templates []commitTemplate) tea.Cmd {
+	if m.commitDraft != "" {
+		m.commit = newCommitEditor(m.commitDraft)
+		m.focus(2)
+		return nil
+	}
+
+	open := func(m *model, text string) tea.Cmd {
+		if m.cfg.ConventionalCommits {
+			m.menu = m.commitTypeMenu(text)
+			return nil
+		}
+		m.openCommitEditor("", text)
+		return nil
+	}
+
+	if len(templates) <= 1 {
+		text := ""
+		if len(templates) == 1 {
+			text = templates[0].text
+		}
+		return open(m, text)
+	}
+	var options []menuOption
+	for i, t := range templates {
+		if i == 9 {
+			break
+		}
+		text := t.text
+		options = append(options, menuOption{
+			key:    fmt.Sprint(i + 1),
+			label:  t.name,
+			action: func(m *model) tea.Cmd { return open(m, text) },
+		})
+	}
+	m.menu = &menu{title: "Template", options: options}
+	return nil
+}
+
+func (m *model) openCommitEditor(prefix, template string) {
+	m.commit = newCommitEditor("")
+	m.commit.startFrom(prefix, template)
+	m.focus(2)
+}
+
+// pickerKeys gives each choice the first of its letters not taken by an
+// earlier one, so "feat" gets f and "fix" gets i. Choices with no free
+// letter fall back to digits.
+func pickerKeys(choices []string) []string {
+	used := map[string]bool{"enter": true}
+	keys := make([]string, len(choices))
+	digit := 1
+	for i, c := range choices {
+		for _, r := range strings.ToLower(c) {
+			if r >= 'a' && r <= 'z' && !used[string(r)] {
+				keys[i] = string(r)
+				break
+			}
+		}
+		if keys[i] == "" && digit <= 9 {
+			keys[i] = fmt.Sprint(digit)
+			digit++
+		}
+		used[keys[i]] = true
+	}
+	return keys
+}
+
+// commitTypeMenu picks the conventional commit type, then the scope.
+func (m *model) commitTypeMenu(template string) *menu {
+	types := m.cfg.commitTypes()
+	var options []menuOption
+	for i, key := range pickerKeys(types) {
+		if key == "" {
+			continue
+		}
+		typ := types[i]
+		options = append(options, menuOption{key: key, label: typ, action: func(m *model) tea.Cmd {
+			m.pickCommitScope(typ, template)
+			return nil
+		}})
+	}
+	return &menu{title: "Type", options: options}
+}
+
+// pickCommitScope offers the configured scopes, or asks for one when none
+// are configured. Either way the scope is optional.
+func (m *model) pickCommitScope(typ, template string) {
+	open := func(m *model, scope string) tea.Cmd {
+		prefix := typ + ": "
+		if scope != "" {
+			prefix = fmt.Sprintf("%s(%s): ", typ, scope)
+		}
+		m.openCommitEditor(prefix, template)
+		return nil
+	}
+
+	if len(m.cfg.CommitScopes) == 0 {
+		m.prompt = newPrompt("Scope", "optional, enter to skip", open)
+		return
+	}
+	options := []menuOption{{key: "enter", label: "none", action: func(m *model) tea.Cmd { return open(m, "") }}}
+	for i, key := range pickerKeys(m.cfg.CommitScopes) {
+		if key == "" {
+			continue
+		}
+		scope := m.cfg.CommitScopes[i]
+		options = append(options, menuOption{key: key, label: scope, action: func(m *model) tea.Cmd { return open(m, scope) }})
+	}
+	m.menu = &menu{title: "Scope", options: options}
+}
+
+// headMessageMsg carries the message of HEAD, to start an amend from.
+type headMessageMsg string
+
+func loadHeadMessage(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "log", "-1", "--format=%B")
+		if err != nil {
+			return nil
+		}
+		return headMessageMsg(strings.TrimRight(out, "\n"))
+	}
+}
+
+// updateCommitEditor handles keys while the commit screen is open. Esc
+// closes it but keeps the message as a draft for next time.
+func (m *model) updateCommitEditor(msg tea.KeyMsg) tea.Cmd {
+	ed := m.commit
+	if ed.running {
+		return nil
+	}
+	switch msg.String() {
+	case "esc":
+		if ed.reword == nil {
+			m.commitDraft = ed.input.Value()
+		}
+		m.commit = nil
+		return nil
+	case "alt+a":
+		if ed.reword != nil {
+			return nil
+		}
+		if m.unborn {
+			m.statusErr = true
+			m.status = "Nothing to amend: there are no commits yet"
+			return nil
+		}
+		ed.amend = !ed.amend
+		if ed.amend && strings.TrimSpace(ed.input.Value()) == "" {
+			return loadHeadMessage(m.repoPath)
+		}
+		return nil
+	case "alt+s":
+		ed.signoff = !ed.signoff && ed.reword == nil
+		return nil
+	case "ctrl+s":
+		message := ed.input.Value()
+		if strings.TrimSpace(message) == "" {
+			m.statusErr = true
+			m.status = "Empty commit message"
+			return nil
+		}
+		if ed.reword != nil {
+			ed.running = true
+			return rewriteCommit(m.repoPath, *ed.reword, message, "")
+		}
+		// Not --no-verify: the repository's hooks run as they would from
+		// the command line, and their output ends up in the status line.
+		args := []string{"commit", "--cleanup=strip", "-F", "-"}
+		action := "Committed"
+		if ed.amend {
+			args = append(args, "--amend")
+			action = "Amended"
+		}
+		if ed.signoff {
+			args = append(args, "--signoff")
+		}
+		ed.running = true
+		return runGitCmdInput(m.repoPath, action, message, args...)
+	}
+	var cmd tea.Cmd
+	ed.input, cmd = ed.input.Update(msg)
+	return cmd
+}
+
+// stagedFiles returns the working tree files with staged changes.
+func (m *model) stagedFiles() []string {
+	var paths []string
+	for _, f := range m.wtFiles {
+		if f.Staged != ' ' && !f.untracked() {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// renderCommitEditor renders the commit screen for a panel of the given
+// inner size: the staged files, guides at the subject and body widths,
+// the editor, and warnings about the message layout.
+func (m *model) renderCommitEditor(width, height int) string {
+	ed := m.commit
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
+	warn := lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B"))
+	on := func(b bool) string {
+		if b {
+			return "[x]"
+		}
+		return "[ ]"
+	}
+
+	title := "New commit"
+	if ed.amend {
+		title = "Amend " + m.currentCommit
+	}
+	var header []string
+	if ed.reword != nil {
+		header = append(header, lipgloss.NewStyle().Bold(true).Render("Reword "+ed.reword.short))
+	} else {
+		header = append(header, lipgloss.NewStyle().Bold(true).Render(title)+"  "+
+			dim.Render(fmt.Sprintf("amend %s  signoff %s", on(ed.amend), on(ed.signoff))))
+	}
+
+	staged := m.stagedFiles()
+	switch {
+	case ed.reword != nil:
+	case len(staged) == 0 && !ed.amend:
+		header = append(header, warn.Render("Nothing staged"))
+	case len(staged) > 0:
+		summary := fmt.Sprintf("%d staged: %s", len(staged), strings.Join(staged, ", "))
+		header = append(header, lipgloss.NewStyle().MaxWidth(width).Render(summary))
+	}
+	header = append(header, "")
+
+	// Column guides at 50 and 72, clipped to the editor width
+	ruler := []rune(strings.Repeat("·", bodyWidth))
+	ruler[subjectWidth-1] = '┆'
+	ruler[bodyWidth-1] = '┆'
+	if len(ruler) > width {
+		ruler = ruler[:width]
+	}
+	header = append(header, dim.Render(string(ruler)))
+
+	var footer []string
+	lines := strings.Split(ed.input.Value(), "\n")
+	if n := len([]rune(lines[0])); n > subjectWidth {
+		footer = append(footer, warn.Render(fmt.Sprintf("Subject is %d characters, over %d", n, subjectWidth)))
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		footer = append(footer, warn.Render("Separate the subject from the body with a blank line"))
+	}
+	long := 0
+	for _, line := range lines[1:] {
+		if len([]rune(line)) > bodyWidth {
+			long++
+		}
+	}
+	if long > 0 {
+		footer = append(footer, warn.Render(fmt.Sprintf("%d body lines over %d characters", long, bodyWidth)))
+	}
+	if ed.running && ed.reword != nil {
+		footer = append(footer, dim.Render("Rewording…"))
+	} else if ed.running {
+		footer = append(footer, dim.Render("Running git commit…"))
+	}
+
+	ed.input.SetWidth(width)
+	editorHeight := height - len(header) - len(footer)
+	if editorHeight < 1 {
+		editorHeight = 1
+	}
+	ed.input.SetHeight(editorHeight)
+
+	return strings.Join(append(append(header, ed.input.View()), footer...), "\n")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// What is worked out per commit over the whole graph, as the lines each
+// commit changed or its patch-id, is done in chunks of commitChunk
+// commits, with at most commitWorkers git processes at a time, and kept
+// in a commitCache, as commits don't change.
+const (
+	commitChunk   = 250
+	commitWorkers = 4
+)
+
+// commitCache is a file of the state dir with a line per commit,
+// "<hash> <value>", appended to as chunks are done.
+type commitCache struct {
+	name string
+	mu   sync.Mutex // keeps the chunks from appending at once
+}
+
+var (
+	impactCache  = &commitCache{name: "impact"}    // "<added> <deleted>"
+	patchIDCache = &commitCache{name: "patch-ids"} // the patch-id, "-" for none
+)
+
+func (c *commitCache) path(repoPath string) (string, error) {
+	dir, err := stateDir(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, c.name), nil
+}
+
+// load reads the values of earlier runs by hash. A missing or unreadable
+// cache reads as empty, so everything is worked out again. A last line
+// without its newline is still being written, by another gitraffe if not
+// by this one, and is left for then.
+func (c *commitCache) load(repoPath string) map[string]string {
+	values := make(map[string]string)
+	path, err := c.path(repoPath)
+	if err != nil {
+		return values
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if hash, value, ok := strings.Cut(strings.TrimSuffix(line, "\n"), " "); ok {
+			values[hash] = value
+		}
+	}
+	return values
+}
+
+// save appends the values of a chunk.
+func (c *commitCache) save(repoPath string, values map[string]string) error {
+	path, err := c.path(repoPath)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for hash, value := range values {
+		fmt.Fprintf(&sb, "%s %s\n", hash, value)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(sb.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// inChunks runs work on each chunk of the hashes, once one of the workers
+// is free, and tells how many chunks there are, each bringing a message.
+func inChunks(hashes []string, work func(chunk []string) tea.Msg) (tea.Cmd, int) {
+	workers := make(chan struct{}, commitWorkers)
+	var cmds []tea.Cmd
+	for len(hashes) > 0 {
+		chunk := hashes[:min(commitChunk, len(hashes))]
+		hashes = hashes[len(chunk):]
+		cmds = append(cmds, func() tea.Msg {
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			return work(chunk)
+		})
+	}
+	return tea.Batch(cmds...), len(cmds)
+}
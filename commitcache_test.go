@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCommitCacheLoad(t *testing.T) {
+	d := testRepo(t)
+	cache := &commitCache{name: "test"}
+	if err := cache.save(d.dir, map[string]string{"aaaa": "3 1"}); err != nil {
+		t.Fatal(err)
+	}
+	path, err := cache.path(d.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A chunk caught halfway through being appended
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("bbbb 12")
+	f.Close()
+
+	values := cache.load(d.dir)
+	if values["aaaa"] != "3 1" {
+		t.Errorf("aaaa = %q, want 3 1", values["aaaa"])
+	}
+	if v, ok := values["bbbb"]; ok {
+		t.Errorf("bbbb = %q read from a line without its newline", v)
+	}
+}
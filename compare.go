@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// c in the file viewer, or on a file of the tree, compares the file with
+// how another commit has it, chosen in the finder: the two side by side,
+// the older commit's on the left, whether or not one descends from the
+// other.
+
+// sideRow is a row of a side-by-side diff: a line of each side, or of
+// one when the other has none there, or a hunk's header.
+type sideRow struct {
+	old, new   string
+	oldN, newN int // line numbers, 0 where the side has no line
+	changed    bool
+	header     bool
+}
+
+var sideHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)`)
+
+// compareView is a file compared between two commits.
+type compareView struct {
+	path         string
+	old, new     string // the commits' short hashes
+	oldAt, newAt time.Time
+	rows         []sideRow
+	binary       bool
+	scroll       int
+}
+
+type compareMsg struct {
+	view *compareView
+	err  error
+}
+
+// loadCompare diffs a file between two commits, the older one as the
+// old side.
+func loadCompare(repoPath, a, b, path string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "show", "--no-patch", "--format=%h %ct", a, b)
+		if err != nil {
+			return compareMsg{err: err}
+		}
+		var shorts [2]string
+		var times [2]time.Time
+		for i, line := range strings.SplitN(strings.TrimSpace(out), "\n", 2) {
+			short, secs, _ := strings.Cut(line, " ")
+			n, _ := strconv.ParseInt(secs, 10, 64)
+			shorts[i], times[i] = short, time.Unix(n, 0)
+		}
+		// Commits of the same second go by ancestry, where they have it
+		swap := times[1].Before(times[0])
+		if times[1].Equal(times[0]) {
+			_, err := gitRead(repoPath, "merge-base", "--is-ancestor", b, a)
+			swap = err == nil
+		}
+		if swap {
+			a, b = b, a
+			shorts[0], shorts[1] = shorts[1], shorts[0]
+			times[0], times[1] = times[1], times[0]
+		}
+		diff, err := gitRead(repoPath, "diff", "--no-color", "--no-ext-diff", "--no-renames", a, b, "--", path)
+		if err != nil {
+			return compareMsg{err: err}
+		}
+		v := &compareView{path: path, old: shorts[0], new: shorts[1], oldAt: times[0], newAt: times[1]}
+		v.rows, v.binary = sideBySide(diff)
+		return compareMsg{view: v}
+	}
+}
+
+// sideBySide lays out a unified diff of one file as side-by-side rows,
+// pairing each run of removed lines with the added lines after it.
+// It tells too whether git found the file binary.
+func sideBySide(diff string) (rows []sideRow, binary bool) {
+	var removed, added []string
+	var oldN, newN int
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			row := sideRow{changed: true}
+			if i < len(removed) {
+				row.old, row.oldN = removed[i], oldN
+				oldN++
+			}
+			if i < len(added) {
+				row.new, row.newN = added[i], newN
+				newN++
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+
+	inHunk := false
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Binary files "):
+			return nil, true
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			inHunk = true
+			if match := sideHunkHeader.FindStringSubmatch(line); match != nil {
+				oldN, _ = strconv.Atoi(match[1])
+				newN, _ = strconv.Atoi(match[2])
+			}
+			rows = append(rows, sideRow{old: line, header: true})
+		case !inHunk || line == "":
+		case line[0] == '-':
+			removed = append(removed, line[1:])
+		case line[0] == '+':
+			added = append(added, line[1:])
+		case line[0] == ' ':
+			flush()
+			rows = append(rows, sideRow{old: line[1:], new: line[1:], oldN: oldN, newN: newN})
+			oldN++
+			newN++
+		}
+	}
+	flush()
+	return rows, false
+}
+
+// compareFile chooses a commit in the finder to compare a file of
+// commit rev with.
+func (m *model) compareFile(rev, path string) tea.Cmd {
+	m.pickCommit("compare "+path+" with it", func(m *model, index int) tea.Cmd {
+		other := m.commits[index].FullHash.String()
+		if other == rev {
+			m.status, m.statusErr = "That is the commit the file is of", true
+			return nil
+		}
+		m.status = "Comparing " + path + "…"
+		return loadCompare(m.repoPath, rev, other, path)
+	})
+	return nil
+}
+
+// handleCompare shows a compared file, or says there is nothing to show.
+func (m *model) handleCompare(msg compareMsg) {
+	switch {
+	case msg.err != nil:
+		m.status, m.statusErr = "Can't compare: "+msg.err.Error(), true
+	case len(msg.view.rows) == 0 && !msg.view.binary:
+		m.status = msg.view.path + " is the same in " + msg.view.old + " and " + msg.view.new
+	default:
+		m.status = ""
+		m.compare = msg.view
+	}
+}
+
+// updateCompare handles a key while a compared file is shown: n and N
+// move to the next and previous hunk.
+func (m *model) updateCompare(msg tea.KeyMsg) tea.Cmd {
+	v := m.compare
+	page := max(m.windowHeight/2, 1)
+	last := max(len(v.rows)-1, 0)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.compare = nil
+	case "up", "k":
+		v.scroll = max(v.scroll-1, 0)
+	case "down", "j":
+		v.scroll = min(v.scroll+1, last)
+	case "u", "ctrl+u", "pgup":
+		v.scroll = max(v.scroll-page, 0)
+	case "d", "ctrl+d", "pgdown":
+		v.scroll = min(v.scroll+page, last)
+	case "g", "home":
+		v.scroll = 0
+	case "G", "end":
+		v.scroll = last
+	case "n":
+		for i := v.scroll + 1; i < len(v.rows); i++ {
+			if v.rows[i].header {
+				v.scroll = i
+				break
+			}
+		}
+	case "N":
+		for i := v.scroll - 1; i >= 0; i-- {
+			if v.rows[i].header {
+				v.scroll = i
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// renderCompare shows the rows of a compared file from the scroll
+// position on, the old side left of the new.
+func (m *model) renderCompare(width, height int) string {
+	v := m.compare
+	side := func(short string, at time.Time) string {
+		return commitHashStyle.Render(short) + " " + dateStyle.Render(at.Format("2006-01-02"))
+	}
+	lines := []string{
+		sectionHeader(v.path),
+		"",
+		side(v.old, v.oldAt) + helpStyle.Render(" → ") + side(v.new, v.newAt),
+		helpStyle.Render("j/k, d/u, g/G: scroll  n/N: next/previous hunk  esc: close"),
+		"",
+	}
+	if v.binary {
+		return strings.Join(append(lines, helpStyle.Render("Binary file, not shown.")), "\n")
+	}
+
+	numbers := 1
+	for _, r := range v.rows {
+		numbers = max(numbers, len(strconv.Itoa(max(r.oldN, r.newN))))
+	}
+	half := max((width-3)/2, numbers+2)
+	cell := func(text string, n int, style lipgloss.Style) string {
+		number := strings.Repeat(" ", numbers)
+		if n > 0 {
+			number = fmt.Sprintf("%*d", numbers, n)
+		}
+		text = truncate(strings.Map(printable, strings.ReplaceAll(text, "\t", "    ")), half-numbers-1)
+		return lineNumberStyle.Render(number) + " " + style.Render(text+strings.Repeat(" ", half-numbers-1-lipgloss.Width(text)))
+	}
+	rows := max(height-len(lines), 1)
+	for i := v.scroll; i < len(v.rows) && i < v.scroll+rows; i++ {
+		r := v.rows[i]
+		if r.header {
+			lines = append(lines, helpStyle.Render(truncate(r.old, width)))
+			continue
+		}
+		oldStyle, newStyle := lipgloss.NewStyle(), lipgloss.NewStyle()
+		if r.changed {
+			oldStyle, newStyle = m.cfg.Colors.Old, m.cfg.Colors.New
+		}
+		lines = append(lines, cell(r.old, r.oldN, oldStyle)+helpStyle.Render(" │ ")+cell(r.new, r.newN, newStyle))
+	}
+	return strings.Join(lines, "\n")
+}
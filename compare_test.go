@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSideBySide(t *testing.T) {
+	diff := "diff --git a/f b/f\n" +
+		"index 1..2 100644\n" +
+		"--- a/f\n" +
+		"+++ b/f\n" +
+		"@@ -3,4 +3,4 @@ func x\n" +
+		" three\n" +
+		"-four\n" +
+		"-five\n" +
+		"+4\n" +
+		" six\n" +
+		"+seven\n"
+	want := []sideRow{
+		{old: "@@ -3,4 +3,4 @@ func x", header: true},
+		{old: "three", new: "three", oldN: 3, newN: 3},
+		{old: "four", new: "4", oldN: 4, newN: 4, changed: true},
+		{old: "five", oldN: 5, changed: true},
+		{old: "six", new: "six", oldN: 6, newN: 5},
+		{new: "seven", newN: 6, changed: true},
+	}
+	rows, binary := sideBySide(diff)
+	if binary || !reflect.DeepEqual(rows, want) {
+		t.Errorf("sideBySide =\n%+v\nwant\n%+v", rows, want)
+	}
+	if _, binary := sideBySide("diff --git a/x b/x\nBinary files a/x and b/x differ\n"); !binary {
+		t.Error("sideBySide of a binary file isn't binary")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// config holds user settings. They are read from the gitraffe section of
+// git config, so they can be set globally or per repository:
+//
+//	git config --global gitraffe.relativeDates true
+type config struct {
+	RelativeDates bool     // show "3 hours ago" next to absolute dates
+	Ref           string   // start the graph here instead of at all refs
+	Exclude       []string // ref patterns hidden from the all-refs graph
+	FetchInterval int      // minutes between background fetches, 0 for none
+	Base          string   // ref to compare local branches with, for stacked branches
+	Backend       string   // what loads the history and diffs, see backend.go
+	NotesRef      string   // git notes ref for notes on commits, instead of the state dir
+	CollapseLines int      // files with longer diffs start out collapsed, 0 for never
+	Impact        bool     // show the lines added and deleted by each commit in the list
+	Minimap       bool     // show the whole history in a column left of the list
+	TimeZone      string   // zone dates are shown in: local, author or utc
+	Symbols       symbols  // glyphs the graph is drawn with
+	LanePalette   []string // colors of the graph's lanes in turn, nil for all orange
+	Colors        colors   // of refs and diffs, from git's color.decorate and color.diff
+	MemoryBudget  int      // MB of loaded diffs kept, 0 for no limit
+	IssueURL      string   // page of issue %s, from gitraffe.issueURL or the origin remote
+	WebURL        string   // the origin remote's repository page, for hyperlinks
+	Hyperlinks    bool     // write OSC 8 hyperlinks, see hyperlinks.go
+	Filters       []filter // presets picked with f, in the order set
+
+	CommitEncoding      string   // git's i18n.commitEncoding, for messages that aren't UTF-8
+	CommitTemplates     []string // message templates offered besides commit.template
+	ConventionalCommits bool     // pick a type and scope before writing a message
+	CommitTypes         []string // conventional commit types, defaultCommitTypes if unset
+	CommitScopes        []string // conventional commit scopes to pick from
+
+	ProtectedBranches []string // branch patterns guarded from deletes and force pushes, see protect.go
+	Shell             string   // runs the commands of !, with -c
+}
+
+var defaultCommitTypes = []string{"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert"}
+
+func loadConfig(repoPath string) config {
+	cfg := config{Backend: backendAuto, CollapseLines: defaultCollapseLines, TimeZone: zoneLocal, Symbols: defaultSymbols, LanePalette: lanePalettes["lanes"], Colors: defaultColors, MemoryBudget: defaultMemoryBudget, Hyperlinks: hyperlinksSupported(), Shell: "sh"}
+
+	out, err := gitRead(repoPath, "config", "--get-regexp", `^gitraffe\.|^i18n\.commitencoding$|^color\.(decorate|diff)\.|^remote\.origin\.url$`)
+	if err != nil {
+		// Exit status 1 just means nothing is set
+		return cfg
+	}
+
+	origin := ""
+	for _, line := range strings.Split(out, "\n") {
+		// git prints the key lowercased, then a space and the value. A key
+		// with no value at all ("[gitraffe] relativeDates") means true.
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "gitraffe.relativedates":
+			cfg.RelativeDates = gitBool(value)
+		case "gitraffe.ref":
+			cfg.Ref = value
+		case "gitraffe.exclude":
+			// Multi-valued: every occurrence adds a pattern
+			cfg.Exclude = append(cfg.Exclude, value)
+		case "gitraffe.protectedbranch":
+			cfg.ProtectedBranches = append(cfg.ProtectedBranches, value)
+		case "gitraffe.shell":
+			if strings.TrimSpace(value) != "" {
+				cfg.Shell = value
+			} else {
+				log.Printf("Ignoring gitraffe.shell: no shell given\n")
+			}
+		case "gitraffe.fetchinterval":
+			if n, err := strconv.Atoi(value); err == nil {
+				cfg.FetchInterval = n
+			} else {
+				log.Printf("Ignoring gitraffe.fetchInterval %q: not a number of minutes\n", value)
+			}
+		case "gitraffe.base":
+			cfg.Base = value
+		case "gitraffe.backend":
+			if backend, err := parseBackend(value); err == nil {
+				cfg.Backend = backend
+			} else {
+				log.Printf("Ignoring gitraffe.backend: %v\n", err)
+			}
+		case "gitraffe.notesref":
+			cfg.NotesRef = value
+		case "gitraffe.impact":
+			cfg.Impact = gitBool(value)
+		case "gitraffe.minimap":
+			cfg.Minimap = gitBool(value)
+		case "gitraffe.graphcolors":
+			if palette, err := parseGraphColors(value); err == nil {
+				cfg.LanePalette = palette
+			} else {
+				log.Printf("Ignoring gitraffe.graphColors: %v\n", err)
+			}
+		case "gitraffe.issueurl":
+			cfg.IssueURL = value
+		case "gitraffe.hyperlinks":
+			cfg.Hyperlinks = gitBool(value)
+		case "remote.origin.url":
+			origin = value
+		case "gitraffe.timezone":
+			cfg.TimeZone = parseTimeZone(value)
+		case "gitraffe.collapselines":
+			if n, err := strconv.Atoi(value); err == nil {
+				cfg.CollapseLines = n
+			} else {
+				log.Printf("Ignoring gitraffe.collapseLines %q: not a number of lines\n", value)
+			}
+		case "gitraffe.memorybudget":
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				cfg.MemoryBudget = n
+			} else {
+				log.Printf("Ignoring gitraffe.memoryBudget %q: not a number of MB\n", value)
+			}
+		case "i18n.commitencoding":
+			cfg.CommitEncoding = value
+		case "gitraffe.committemplate":
+			cfg.CommitTemplates = append(cfg.CommitTemplates, value)
+		case "gitraffe.conventionalcommits":
+			cfg.ConventionalCommits = gitBool(value)
+		case "gitraffe.committype":
+			cfg.CommitTypes = append(cfg.CommitTypes, value)
+		case "gitraffe.commitscope":
+			cfg.CommitScopes = append(cfg.CommitScopes, value)
+		case "":
+		default:
+			if strings.HasPrefix(key, "color.") {
+				// Git's own colors; the slots gitraffe has no use for are
+				// no concern of it
+				cfg.Colors.set(key, value)
+				continue
+			}
+			if name, ok := strings.CutPrefix(key, "gitraffe.filter."); ok {
+				if f, err := parseFilter(name, value); err == nil {
+					cfg.Filters = append(cfg.Filters, f)
+				} else {
+					log.Printf("Ignoring filter %s: %v\n", name, err)
+				}
+				continue
+			}
+			if !cfg.Symbols.set(strings.TrimPrefix(key, "gitraffe."), value) {
+				log.Printf("Ignoring unknown config key %s\n", key)
+			}
+		}
+	}
+	if cfg.IssueURL == "" {
+		cfg.IssueURL = remoteIssueURL(origin)
+	}
+	cfg.WebURL = webURL(origin)
+
+	return cfg
+}
+
+func (c config) commitTypes() []string {
+	if len(c.CommitTypes) == 0 {
+		return defaultCommitTypes
+	}
+	return c.CommitTypes
+}
+
+// gitBool interprets a git config boolean.
+func gitBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// crashReport is the path of the report written for the panic that ended
+// the program, so main can point at it once the terminal is back.
+var (
+	crashMu     sync.Mutex
+	crashReport string
+)
+
+// reportCrash writes a crash report to crashDir with the panic, its
+// stack and a summary of the model, and returns its path. Only the first
+// crash is written; later calls return the same path.
+func reportCrash(r any, summary string) string {
+	crashMu.Lock()
+	defer crashMu.Unlock()
+	if crashReport != "" {
+		return crashReport
+	}
+
+	path := filepath.Join(crashDir(), fmt.Sprintf("gitraffe-crash-%s.log", time.Now().Format("20060102-150405")))
+	report := fmt.Sprintf("gitraffe crashed at %s\n\npanic: %v\n\n%s\nState:\n%s\n",
+		time.Now().Format(time.RFC3339), r, debug.Stack(), summary)
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		log.Printf("Could not write crash report: %v\n", err)
+		return ""
+	}
+	log.Printf("PANIC: %v, crash report written to %s\n", r, path)
+	crashReport = path
+	return path
+}
+
+// crashDir is where crash reports go: with the cached logs, rather than
+// the directory gitraffe was started from, which is usually a work tree
+// they would show up in.
+func crashDir() string {
+	if dir, err := logCacheDir(); err == nil {
+		dir = filepath.Join(dir, "crashes")
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// logDir is where gitraffe.log and profiles go.
+func logDir() string {
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return os.TempDir()
+}
+
+// summary describes the model's state for a crash report, leaving out
+// anything from the repository beyond its path.
+func (m *model) summary() string {
+	return fmt.Sprintf("  repo: %s  backend: %s, loaded with %s\n  ref: %q  range: %q  exclude: %q\n  commits: %d  rows: %d  selected: %d\n"+
+		"  window: %dx%d  focus: %d  tab: %d  zoomed: %v\n  workTree: %v  files: %d  selected file: %d\n"+
+		"  prompt: %v  menu: %v  commit screen: %v\n  status: %q\n",
+		m.repoPath, m.backend, m.loadedWith, m.ref, m.revRange, m.exclude, len(m.commits), len(m.displayRows), m.selected,
+		m.windowWidth, m.windowHeight, m.focusedBox, m.detailTab, m.zoomed, m.workTree, len(m.wtFiles), m.wtSelected,
+		m.prompt != nil, m.menu != nil, m.commit != nil, m.status)
+}
+
+// guardCmd makes a panic in a command, which runs on its own goroutine,
+// leave a crash report too. Bubble Tea still does the recovering and
+// restores the terminal. Commands in a batch are guarded as they come.
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer func() {
+			if r := recover(); r != nil {
+				reportCrash(r, "  (in a command)\n")
+				panic(r)
+			}
+		}()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = guardCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
+
+// guardedSequence is tea.Sequence with each command guarded. The
+// commands of a sequence come back in a message guardCmd can't look
+// into, so they are guarded before they go in.
+func guardedSequence(cmds ...tea.Cmd) tea.Cmd {
+	for i := range cmds {
+		cmds[i] = guardCmd(cmds[i])
+	}
+	return tea.Sequence(cmds...)
+}
+
+// gitOperations are the operations git can leave half done, by the file
+// in the git dir that marks them.
+var gitOperations = []struct {
+	marker string
+	name   string
+	hint   string
+}{
+	{"rebase-merge", "rebase", "git rebase --continue, or git rebase --abort"},
+	{"rebase-apply", "rebase", "git rebase --continue, or git rebase --abort"},
+	{"MERGE_HEAD", "merge", "commit the merge, or git merge --abort"},
+	{"CHERRY_PICK_HEAD", "cherry-pick", "git cherry-pick --continue, or git cherry-pick --abort"},
+	{"REVERT_HEAD", "revert", "git revert --continue, or git revert --abort"},
+	{"BISECT_LOG", "bisect", "git bisect reset when done"},
+}
+
+// operationInProgress reports a rebase, merge or the like that stopped
+// halfway, say on a conflict during a pull or because gitraffe was
+// interrupted, with how to finish or undo it.
+func operationInProgress(repoPath string) (name, hint string) {
+	args := []string{"rev-parse"}
+	for _, op := range gitOperations {
+		args = append(args, "--git-path", op.marker)
+	}
+	out, err := gitRead(repoPath, args...)
+	if err != nil {
+		return "", ""
+	}
+	for i, path := range strings.Split(out, "\n") {
+		if i >= len(gitOperations) {
+			break
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repoPath, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return gitOperations[i].name, gitOperations[i].hint
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// rowRefsWidth caps the refs shown on a row of the graph, so that a
+// commit with dozens of nightly tags still leaves room for its subject.
+const rowRefsWidth = 40
+
+// shortRefs shortens a commit's refs, as git decorates it, to width
+// columns: as many as fit in git's order, HEAD and branches before tags,
+// then how many more there are, as in "HEAD -> main, v1.2.3, +27 more".
+// The first ref is cut short when not even it fits. It also reports
+// whether any were left out.
+func shortRefs(refs string, width int) (string, bool) {
+	if lipgloss.Width(refs) <= width {
+		return refs, false
+	}
+	all := strings.Split(refs, ", ")
+	kept := 0
+	for kept < len(all) {
+		if lipgloss.Width(joinRefs(all[:kept+1], len(all)-kept-1)) > width {
+			break
+		}
+		kept++
+	}
+	if kept == 0 {
+		more := ""
+		if len(all) > 1 {
+			more = fmt.Sprintf(", +%d more", len(all)-1)
+		}
+		return truncate(all[0], width-lipgloss.Width(more)) + more, true
+	}
+	return joinRefs(all[:kept], len(all)-kept), true
+}
+
+func joinRefs(refs []string, more int) string {
+	s := strings.Join(refs, ", ")
+	if more > 0 {
+		s += fmt.Sprintf(", +%d more", more)
+	}
+	return s
+}
+
+// truncate cuts s to width columns, ending in "…" when it was cut.
+func truncate(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, r := range s {
+		if lipgloss.Width(sb.String()+string(r)) > width-1 {
+			break
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String() + "…"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// demoRepo builds the synthetic repository of `gitraffe demo`. Names,
+// dates and contents are fixed, and the user's git config is left out,
+// so every demo repository has the same hashes: a screenshot or a
+// rendering bug made with one can be reproduced with another. The first
+// error stops the build, and the steps after it do nothing.
+type demoRepo struct {
+	dir   string
+	clock time.Time // date of the next commit
+	err   error
+}
+
+// git runs a git command in the demo repository as the demo author.
+func (d *demoRepo) git(args ...string) {
+	if d.err != nil {
+		return
+	}
+	date := d.clock.Format(time.RFC3339)
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgSign=false", "-c", "tag.gpgSign=false"}, args...)...)
+	cmd.Dir = d.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Ada Graph", "GIT_AUTHOR_EMAIL=ada@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME=Ada Graph", "GIT_COMMITTER_EMAIL=ada@example.com", "GIT_COMMITTER_DATE="+date,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		d.err = fmt.Errorf("git %s: %v (%s)", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+}
+
+// commit writes a file and commits it, an hour after the last commit.
+func (d *demoRepo) commit(path, content, message string) {
+	if d.err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, path), []byte(content), 0644); err != nil {
+		d.err = err
+		return
+	}
+	d.clock = d.clock.Add(time.Hour)
+	d.git("add", "--", path)
+	d.git("commit", "-q", "-m", message)
+}
+
+// merge merges branches into the current one with a merge commit,
+// several at once for an octopus merge.
+func (d *demoRepo) merge(message string, branches ...string) {
+	d.clock = d.clock.Add(time.Hour)
+	d.git(append([]string{"merge", "-q", "--no-ff", "-m", message}, branches...)...)
+}
+
+// buildDemoRepo fills dir with a small project's history: a feature
+// branch merged back, tags, an octopus merge of three fixes, a branch
+// still open and an unrelated gh-pages history.
+func buildDemoRepo(dir string) error {
+	d := &demoRepo{dir: dir, clock: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)}
+	d.git("init", "-q")
+	d.git("symbolic-ref", "HEAD", "refs/heads/main")
+	d.commit("README.md", "# giraffe\n", "Initial commit")
+	d.commit("neck.go", "package giraffe\n", "Add the neck")
+	d.git("tag", "-a", "v0.1.0", "-m", "First release")
+
+	// A feature developed while main moves on, merged back
+	d.git("checkout", "-q", "-b", "feature/spots")
+	d.commit("spots.go", "package giraffe\n\n// Spots\n", "Draw the spots")
+	d.commit("spots.go", "package giraffe\n\n// Spots, none alike\n", "Make every spot different")
+	d.git("checkout", "-q", "main")
+	d.commit("README.md", "# giraffe\n\nA tall library.\n", "Describe the project")
+	d.merge("Merge branch 'feature/spots'", "feature/spots")
+
+	// Three fixes from the same commit, merged at once
+	d.git("branch", "fix/legs")
+	d.git("branch", "fix/tail")
+	d.git("branch", "fix/ears")
+	d.git("checkout", "-q", "fix/legs")
+	d.commit("legs.go", "package giraffe\n", "Fix the leg count")
+	d.git("checkout", "-q", "fix/tail")
+	d.commit("tail.go", "package giraffe\n", "Fix the tail swing")
+	d.git("checkout", "-q", "fix/ears")
+	d.commit("ears.go", "package giraffe\n", "Fix the ear angle")
+	d.git("checkout", "-q", "main")
+	d.merge("Merge the leg, tail and ear fixes", "fix/legs", "fix/tail", "fix/ears")
+	d.git("tag", "-a", "v0.2.0", "-m", "Second release")
+
+	// Work in progress, not merged yet
+	d.git("checkout", "-q", "-b", "feature/ossicones")
+	d.commit("ossicones.go", "package giraffe\n", "Sketch the ossicones")
+	d.git("checkout", "-q", "main")
+	d.commit("neck.go", "package giraffe\n\n// Longer\n", "Lengthen the neck")
+
+	// Docs site with a history of its own
+	d.git("checkout", "-q", "--orphan", "gh-pages")
+	d.git("rm", "-q", "-r", "--cached", ".")
+	d.git("clean", "-q", "-f", "-d")
+	d.commit("index.html", "<h1>giraffe</h1>\n", "Publish the docs")
+	d.git("checkout", "-q", "main")
+	return d.err
+}
+
+// runDemo implements `gitraffe demo [path]`: it builds the demo
+// repository in path, which must not exist yet, or in a new temporary
+// directory, and returns where, for gitraffe to open.
+func runDemo(args []string) (string, error) {
+	var dir string
+	var err error
+	if len(args) > 0 {
+		dir = args[0]
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return "", fmt.Errorf("%s already exists", dir)
+		}
+		err = os.MkdirAll(dir, 0755)
+	} else {
+		dir, err = os.MkdirTemp("", "gitraffe-demo-")
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := buildDemoRepo(dir); err != nil {
+		return "", fmt.Errorf("building the demo repository: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Tabs of the details panel. Each tab keeps its own scroll offset.
+const (
+	tabCommit = iota
+	tabDiff
+	tabFiles
+	tabRefs
+	numDetailTabs
+)
+
+var detailTabNames = [numDetailTabs]string{"Commit", "Diff", "Files", "Refs"}
+
+// containsPreview is how many containing branches or tags are listed before
+// the rest are collapsed behind the "c" toggle.
+const containsPreview = 10
+
+// fileChange is one entry of `git show --name-status`.
+type fileChange struct {
+	Status  string // A, M, D, R, C, T (similarity score stripped)
+	Path    string
+	OldPath string // source path for renames and copies
+	Added   int    // lines, from --numstat
+	Deleted int
+	Binary  bool
+}
+
+func parseNameStatus(out string) []fileChange {
+	var files []fileChange
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 2 || parts[0] == "" {
+			continue
+		}
+		fc := fileChange{Status: parts[0][:1], Path: parts[len(parts)-1]}
+		if len(parts) > 2 {
+			fc.OldPath = parts[1]
+		}
+		files = append(files, fc)
+	}
+	return files
+}
+
+// trailer is a "Key: value" line from the last paragraph of a commit
+// message, such as Co-authored-by or Reviewed-by.
+type trailer struct {
+	Key   string
+	Value string
+}
+
+var trailerPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+
+// parseTrailers splits the trailer block off a commit body. Like git, it only
+// considers the last paragraph, and only if every line in it is a trailer or
+// an indented continuation of one.
+func parseTrailers(body string) (string, []trailer) {
+	body = strings.TrimRight(body, "\n ")
+	start := 0
+	if i := strings.LastIndex(body, "\n\n"); i >= 0 {
+		start = i + 2
+	}
+	var trailers []trailer
+	for _, line := range strings.Split(body[start:], "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(trailers) > 0 {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		match := trailerPattern.FindStringSubmatch(line)
+		if match == nil {
+			return body, nil
+		}
+		trailers = append(trailers, trailer{Key: match[1], Value: match[2]})
+	}
+	if len(trailers) == 0 {
+		return body, nil
+	}
+	return strings.TrimRight(body[:start], "\n"), trailers
+}
+
+func sectionHeader(title string) string {
+	return lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")).
+		Render("─── " + title + " " + strings.Repeat("─", max(3, 30-lipgloss.Width(title))))
+}
+
+// renderCommitDetails renders the selected commit for a panel whose content
+// area is height lines tall: a tab bar followed by the scrolled active tab.
+func (m *model) renderCommitDetails(width, height int) string {
+	log.Printf("renderCommitDetails: selected=%d, len(commits)=%d, tab=%d", m.selected, len(m.commits), m.detailTab)
+	if len(m.commits) == 0 && m.unborn {
+		return helpStyle.Render(fmt.Sprintf("No commits yet on branch %s.\n\nPress w for the working tree: stage files with space, then c to make the first commit.", m.currentBranch))
+	}
+	if len(m.commits) == 0 || m.selected < 0 || m.selected >= len(m.commits) {
+		log.Printf("renderCommitDetails: skipping (empty or out of bounds)")
+		return ""
+	}
+
+	c := &m.commits[m.selected]
+
+	var content string
+	diffStart := -1
+	switch m.detailTab {
+	case tabCommit:
+		content, _ = m.renderCommitTab(c, width)
+	case tabDiff:
+		var lines []string
+		lines, diffStart = m.diffTabLines(c, width)
+		content = strings.Join(lines, "\n")
+	case tabFiles:
+		content = m.renderFilesTab(c)
+	case tabRefs:
+		content, _ = m.renderRefsTab(c)
+	}
+
+	// Apply scroll offset and truncate to fit panel height.
+	// lipgloss Height() only pads short content, it does NOT clip overflow,
+	// so we must truncate here to prevent the panel from growing unbounded.
+	allLines := strings.Split(content, "\n")
+
+	// Clamp scroll
+	scroll := m.detailsScroll[m.detailTab]
+	if scroll >= len(allLines) {
+		scroll = len(allLines) - 1
+	}
+	if scroll < 0 {
+		scroll = 0
+	}
+	allLines = allLines[scroll:]
+
+	// Truncate to available height inside the panel
+	// Panel uses Height(contentHeight) with Padding(1,2) → 2 vertical padding
+	// lines, and the tab bar plus its spacer take two more.
+	maxLines := height - 2 - 2
+	if maxLines < 1 {
+		maxLines = 1
+	}
+	if len(allLines) > maxLines {
+		allLines = allLines[:maxLines]
+	}
+
+	// The file being read is pinned under the tab bar, so its path stays
+	// in view while scrolling through it
+	spacer := ""
+	if path := m.pinnedDiffFile(c, diffStart, scroll); path != "" {
+		spacer = pinnedStyle.Render("▸ " + path)
+	}
+	return m.renderTabBar(c, width) + "\n" + spacer + "\n" + strings.Join(allLines, "\n")
+}
+
+// renderTabBar shows the tabs, and once the diff is loaded, the size of
+// the change when it fits in width.
+func (m *model) renderTabBar(c *commit, width int) string {
+	activeStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500")).Underline(true)
+	tabs := make([]string, numDetailTabs)
+	for i, name := range detailTabNames {
+		if i == m.detailTab {
+			tabs[i] = activeStyle.Render(name)
+		} else {
+			tabs[i] = helpStyle.Render(name)
+		}
+	}
+	bar := strings.Join(tabs, helpStyle.Render(" │ "))
+	if c.DiffLoaded && len(c.Files) > 0 {
+		if totals := "   " + diffTotals(c.Files); lipgloss.Width(bar+totals) <= width {
+			bar += totals
+		}
+	}
+	return bar
+}
+
+// rewrittenThreshold is how far the committer date may drift from the author
+// date before the commit is flagged as rebased or cherry-picked.
+const rewrittenThreshold = 24 * time.Hour
+
+func formatIdent(name, email string) string {
+	if email == "" {
+		return name
+	}
+	return name + " <" + email + ">"
+}
+
+// formatDuration renders a duration in its largest sensible unit.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d minutes", int(d.Minutes()))
+	}
+}
+
+// relativeTime describes t relative to now, e.g. "3 hours ago".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	if d < 0 {
+		return "in the future"
+	}
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d.Hours()/24), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d.Hours()/24/30), "month")
+	default:
+		return plural(int(d.Hours()/24/365), "year")
+	}
+}
+
+// renderCommitTab shows the commit metadata and message, width columns
+// wide, and returns the links in it.
+func (m *model) renderCommitTab(c *commit, width int) (string, *linker) {
+	var sb strings.Builder
+	l := &linker{focus: m.link}
+
+	// SHA
+	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500")).Render("SHA:     "))
+	sb.WriteString(m.hyperlink(m.commitURL(*c), commitHashStyle.Render(c.FullHash.String())))
+	sb.WriteString("\n")
+
+	// Position relative to the nearest tag
+	if c.Describe != "" {
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500")).Render("Desc:    "))
+		tag := describedTag(c.Describe)
+		sb.WriteString(l.link(detailLink{kind: linkRev, target: "refs/tags/" + tag}, tag, lipgloss.NewStyle()))
+		sb.WriteString(c.Describe[len(tag):])
+		sb.WriteString("\n")
+	}
+
+	// Date
+	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A3BE8C")).Render("Date:    "))
+	sb.WriteString(m.formatDate(c.Date, "author"))
+	if m.cfg.RelativeDates {
+		sb.WriteString(helpStyle.Render(" (" + relativeTime(c.Date, m.now) + ")"))
+	}
+	sb.WriteString("\n")
+
+	// Author
+	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7DD3FC")).Render("Author:  "))
+	sb.WriteString(authorStyle.Render(formatIdent(c.Author, c.AuthorEmail)))
+	sb.WriteString("\n")
+
+	// Committer, only when it tells something the author line doesn't
+	sameIdent := c.Committer == "" || (c.Committer == c.Author && c.CommitterEmail == c.AuthorEmail)
+	sameDate := c.CommitDate.IsZero() || c.CommitDate.Equal(c.Date)
+	if !sameIdent || !sameDate {
+		committer := formatIdent(c.Committer, c.CommitterEmail)
+		if committer == "" {
+			committer = formatIdent(c.Author, c.AuthorEmail)
+		}
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7DD3FC")).Render("Commit:  "))
+		sb.WriteString(authorStyle.Render(committer))
+		if !sameDate {
+			sb.WriteString("  ")
+			sb.WriteString(m.formatDate(c.CommitDate, "committer"))
+		}
+		sb.WriteString("\n")
+		if gap := c.CommitDate.Sub(c.Date); !sameDate && (gap > rewrittenThreshold || gap < -rewrittenThreshold) {
+			when := "after"
+			if gap < 0 {
+				when = "before"
+			}
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B")).
+				Render(fmt.Sprintf("         ↻ rebased or cherry-picked: committed %s %s authoring", formatDuration(gap), when)))
+			sb.WriteString("\n")
+		}
+	}
+
+	// Parents, numbered for merges as in <commit>^<n>
+	if len(c.Parents) == 1 {
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Render("Parents: "))
+		sb.WriteString(l.link(detailLink{kind: linkRev, target: c.Parents[0]}, c.Parents[0], lipgloss.NewStyle()))
+		sb.WriteString("\n")
+	} else if len(c.Parents) > 1 {
+		label := "Parents: "
+		if len(c.Parents) > 2 {
+			label = "Octopus: "
+		}
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Render(label))
+		for i, p := range c.Parents {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(helpStyle.Render(fmt.Sprintf("^%d ", i+1)))
+			sb.WriteString(l.link(detailLink{kind: linkRev, target: p}, p, lipgloss.NewStyle()))
+		}
+		sb.WriteString("\n")
+	}
+
+	if note := rewrittenNote(*c); note != "" {
+		sb.WriteString(rewrittenStyle.Render(note))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(m.copiesNote(m.selected))
+	sb.WriteString(m.mergeBaseNote(*c))
+
+	// Refs, on one line; the Refs tab lists them all
+	if c.Refs != "" {
+		const label, hint = "Refs:    ", " (Refs tab)"
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0")).Render(label))
+		avail := width - len(label)
+		refs, cut := shortRefs(c.Refs, avail)
+		if cut && avail-len(hint) >= 20 {
+			refs, _ = shortRefs(c.Refs, avail-len(hint))
+			refs = m.styleRefs(refs) + helpStyle.Render(hint)
+		} else {
+			refs = m.styleRefs(refs)
+		}
+		sb.WriteString(refs)
+		sb.WriteString("\n")
+	}
+
+	// Commit message
+	sb.WriteString("\n")
+	sb.WriteString(sectionHeader("Message"))
+	sb.WriteString("\n")
+	sb.WriteString(m.linkText(l, c.Message, messageStyle))
+	sb.WriteString("\n")
+	if c.Body != "" {
+		sb.WriteString("\n")
+		sb.WriteString(m.linkText(l, c.Body, messageStyle))
+		sb.WriteString("\n")
+	}
+
+	if note := m.notes.notes[c.FullHash.String()]; note != "" {
+		sb.WriteString("\n")
+		sb.WriteString(sectionHeader("Note"))
+		sb.WriteString("\n")
+		sb.WriteString(noteStyle.Render(note))
+		sb.WriteString("\n")
+	}
+
+	if len(c.Trailers) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(sectionHeader("Trailers"))
+		sb.WriteString("\n")
+		keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#B48EAD"))
+		for _, t := range c.Trailers {
+			style := messageStyle
+			switch strings.ToLower(t.Key) {
+			case "co-authored-by", "signed-off-by", "reviewed-by", "acked-by", "tested-by", "reported-by", "helped-by":
+				style = authorStyle
+			}
+			sb.WriteString(keyStyle.Render(t.Key + ": "))
+			sb.WriteString(m.linkText(l, t.Value, style))
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String(), l
+}
+
+// diffTabLines renders the diff stat bars, for a panel width columns
+// wide, and the colorized patch, along with the line the patch starts at,
+// -1 when there is none.
+func (m *model) diffTabLines(c *commit, width int) ([]string, int) {
+	if !c.DiffLoaded {
+		if m.checkout.partial != "" {
+			return []string{helpStyle.Render("Loading diff, fetching any missing objects from the promisor remote...")}, -1
+		}
+		return []string{helpStyle.Render("Loading diff...")}, -1
+	}
+
+	head := m.diffTabHead(c, statBars(c.Files, width))
+	if c.DiffBody == "" {
+		return strings.Split(head+helpStyle.Render("No changes"), "\n"), -1
+	}
+	head += sectionHeader("Diff") + "\n"
+	return strings.Split(head+m.cfg.Colors.colorizeDiff(m.visibleDiff(c)), "\n"), strings.Count(head, "\n")
+}
+
+// diffTabHead is what the Diff tab shows above the patch: the side of a
+// merge it is against, and the stat, as the given lines.
+func (m *model) diffTabHead(c *commit, stats []string) string {
+	var sb strings.Builder
+
+	// Which side of a merge the diff is against; m steps through them
+	if len(c.Parents) > 1 {
+		switch {
+		case c.DiffParent == 0 && m.backend == backendGoGit:
+			sb.WriteString(helpStyle.Render(fmt.Sprintf("Diff against parent ^1 %s; go-git has no combined diff (m: next parent)", c.Parents[0])))
+		case c.DiffParent == 0:
+			sb.WriteString(helpStyle.Render(fmt.Sprintf("Combined diff of %d parents (m: diff against each parent)", len(c.Parents))))
+		default:
+			sb.WriteString(helpStyle.Render(fmt.Sprintf("Diff against parent ^%d %s (m: next parent)", c.DiffParent, c.Parents[c.DiffParent-1])))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	// Diff stats
+	if len(c.Files) > 0 {
+		sb.WriteString(sectionHeader("Stats"))
+		sb.WriteString("\n")
+		sb.WriteString(strings.Join(stats, "\n"))
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// renderFilesTab shows the changed files as a directory tree.
+func (m *model) renderFilesTab(c *commit) string {
+	if !c.DiffLoaded {
+		return helpStyle.Render("Loading files...")
+	}
+	if len(c.Files) == 0 {
+		return helpStyle.Render("No changed files")
+	}
+	lines, _ := m.filesTabLines(c)
+	return strings.Join(lines, "\n")
+}
+
+// filesTabLines lays out the Files tab, with the line each file (in
+// sortedFiles order) ends up on, so the file cursor can be scrolled to.
+func (m *model) filesTabLines(c *commit) ([]string, []int) {
+	statusStyles := map[string]lipgloss.Style{
+		"A": lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C")),
+		"D": lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A")),
+		"M": lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B")),
+		"R": lipgloss.NewStyle().Foreground(lipgloss.Color("#5E81AC")),
+		"C": lipgloss.NewStyle().Foreground(lipgloss.Color("#5E81AC")),
+	}
+	dirStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0"))
+	cursorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+
+	files := sortedFiles(c)
+	lines := []string{sectionHeader("Files")}
+	fileLines := make([]int, len(files))
+
+	// Print each directory the first time a file below it is seen, indented
+	// by depth, so the sorted paths form a tree.
+	var prevDirs []string
+	for i, f := range files {
+		dir, name := path.Split(f.Path)
+		var dirs []string
+		if dir != "" {
+			dirs = strings.Split(strings.TrimSuffix(dir, "/"), "/")
+		}
+		common := 0
+		for common < len(dirs) && common < len(prevDirs) && dirs[common] == prevDirs[common] {
+			common++
+		}
+		for d := common; d < len(dirs); d++ {
+			lines = append(lines, "  "+strings.Repeat("  ", d)+dirStyle.Render(dirs[d]+"/"))
+		}
+		prevDirs = dirs
+
+		style, ok := statusStyles[f.Status]
+		if !ok {
+			style = messageStyle
+		}
+		// The cursor picks the file v marks reviewed
+		gutter := "  "
+		if m.focusedBox == 2 && i == m.filesCursor {
+			gutter = cursorStyle.Render("▌ ")
+		}
+		line := gutter + strings.Repeat("  ", len(dirs)) + style.Render(f.Status+" "+name)
+		if f.OldPath != "" {
+			line += helpStyle.Render(" ← " + f.OldPath)
+		}
+		if m.review.fileReviewed(c.FullHash.String(), f.Path) {
+			line += reviewedStyle.Render(" ✓")
+		}
+		fileLines[i] = len(lines)
+		lines = append(lines, line)
+	}
+	return lines, fileLines
+}
+
+// isRemoteRef reports whether a short decoration like "origin/main" names a
+// remote-tracking branch rather than a local branch with a slash in it.
+func (m *model) isRemoteRef(ref string) bool {
+	for _, r := range m.remotes {
+		if strings.HasPrefix(ref, r+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// renderRefsTab lists the refs pointing at the commit, grouped by kind,
+// and returns the links to those containing it.
+func (m *model) renderRefsTab(c *commit) (string, *linker) {
+	l := &linker{focus: m.link}
+	var head, branches, remotes, tags []string
+	for _, ref := range strings.Split(c.Refs, ", ") {
+		switch {
+		case ref == "":
+		case strings.HasPrefix(ref, "tag: "):
+			tags = append(tags, strings.TrimPrefix(ref, "tag: "))
+		case strings.HasPrefix(ref, "HEAD"):
+			head = append(head, ref)
+		case m.isRemoteRef(ref):
+			remotes = append(remotes, ref)
+		default:
+			branches = append(branches, ref)
+		}
+	}
+
+	var sb strings.Builder
+	// Tags are listed without decorations' "tag: ", put back for refURL
+	writeGroup := func(title string, refs []string, style lipgloss.Style, decoration string) {
+		if len(refs) == 0 {
+			return
+		}
+		sb.WriteString(sectionHeader(title))
+		sb.WriteString("\n")
+		for _, r := range refs {
+			sb.WriteString(m.hyperlink(m.refURL(decoration+r), style.Render(r)))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+	colors := m.cfg.Colors
+	writeGroup("HEAD", head, colors.Head, "")
+	writeGroup("Branches", branches, colors.Branch, "")
+	writeGroup("Remotes", remotes, colors.Remote, "")
+	sortTags(tags)
+	writeGroup("Tags", tags, colors.Tag, "tag: ")
+
+	if sb.Len() == 0 {
+		sb.WriteString(helpStyle.Render("No refs point at this commit"))
+		sb.WriteString("\n\n")
+	}
+
+	if !c.ContainsLoaded {
+		sb.WriteString(helpStyle.Render("Finding containing branches and tags..."))
+		return sb.String(), l
+	}
+
+	sb.WriteString(sectionHeader("Contained in"))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("%d branches, %d tags\n\n", len(c.ContainsBranches), len(c.ContainsTags)))
+	hidden := 0
+	writeContains := func(title string, refs []string, style lipgloss.Style) {
+		if len(refs) == 0 {
+			return
+		}
+		sb.WriteString(lipgloss.NewStyle().Bold(true).Render(title))
+		sb.WriteString("\n")
+		shown := refs
+		if !m.containsAll && len(shown) > containsPreview {
+			shown = shown[:containsPreview]
+			hidden += len(refs) - containsPreview
+		}
+		for _, r := range shown {
+			sb.WriteString("  " + l.link(detailLink{kind: linkRev, target: r}, r, style) + "\n")
+		}
+		if len(shown) < len(refs) {
+			sb.WriteString(helpStyle.Render(fmt.Sprintf("  … %d more", len(refs)-len(shown))))
+			sb.WriteString("\n")
+		}
+	}
+	writeContains("Branches:", c.ContainsBranches, colors.Branch)
+	writeContains("Tags:", c.ContainsTags, colors.Tag)
+	if hidden > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(helpStyle.Render("c: show all"))
+	} else if m.containsAll && len(c.ContainsBranches)+len(c.ContainsTags) > containsPreview {
+		sb.WriteString("\n")
+		sb.WriteString(helpStyle.Render("c: collapse"))
+	}
+	return sb.String(), l
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pinnedStyle is the path of the file being read, pinned above the diff.
+var pinnedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#E5E9F0"))
+
+// diffOutline locates the files and hunks of a diff by line.
+type diffOutline struct {
+	files []int // "diff --git" header lines
+	paths []string
+	sizes []int // lines of each file after its header
+	hunks []int // "@@" lines
+}
+
+func outlineDiff(diff string) diffOutline {
+	var o diffOutline
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			o.files = append(o.files, i)
+			o.paths = append(o.paths, diffFilePath(line))
+		case strings.HasPrefix(line, "@@"):
+			o.hunks = append(o.hunks, i)
+		}
+	}
+	for i, start := range o.files {
+		end := len(lines)
+		if i+1 < len(o.files) {
+			end = o.files[i+1]
+		}
+		o.sizes = append(o.sizes, end-start-1)
+	}
+	return o
+}
+
+// diffFilePath takes the path out of a file header: the new side of
+// "diff --git a/old b/new", or the one path of a combined diff's
+// "diff --cc path".
+func diffFilePath(header string) string {
+	if rest, ok := strings.CutPrefix(header, "diff --git "); ok {
+		if i := strings.LastIndex(rest, " b/"); i >= 0 {
+			return rest[i+3:]
+		}
+		return rest
+	}
+	_, path, _ := strings.Cut(strings.TrimPrefix(header, "diff "), " ")
+	return path
+}
+
+// updateDiffTab handles the keys of the Diff tab that jump between files
+// ({ and }) and hunks (( and )), scrolling the target to the top.
+func (m *model) updateDiffTab(msg tea.KeyMsg) bool {
+	var forward, files bool
+	switch msg.String() {
+	case "}":
+		forward, files = true, true
+	case "{":
+		files = true
+	case ")":
+		forward = true
+	case "(":
+	case "enter":
+		m.toggleFold()
+		return true
+	default:
+		return false
+	}
+
+	c := &m.commits[m.selected]
+	start := m.diffStart(c)
+	if start < 0 {
+		return true
+	}
+	outline := outlineDiff(m.visibleDiff(c))
+	marks := outline.hunks
+	if files {
+		marks = outline.files
+	}
+	scroll := m.detailsScroll[tabDiff]
+	target := -1
+	for _, line := range marks {
+		line += start
+		if forward && line > scroll {
+			target = line
+			break
+		}
+		if !forward && line < scroll {
+			target = line
+		}
+	}
+	if target >= 0 {
+		m.detailsScroll[tabDiff] = target
+	}
+	return true
+}
+
+// diffStart is the line of the Diff tab the patch starts at, -1 when
+// there is none. Each file's stat bar takes a line at any width, so the
+// tab's head is laid out with blank ones, rendering neither the bars nor
+// the patch; the Diff header follows it.
+func (m *model) diffStart(c *commit) int {
+	if !c.DiffLoaded || c.DiffBody == "" {
+		return -1
+	}
+	return strings.Count(m.diffTabHead(c, make([]string, len(c.Files))), "\n") + 1
+}
+
+// pinnedDiffFile is the path of the file at the top of the scrolled Diff
+// tab, empty above the first file.
+func (m *model) pinnedDiffFile(c *commit, start, scroll int) string {
+	if start < 0 {
+		return ""
+	}
+	outline := outlineDiff(m.visibleDiff(c))
+	path := ""
+	for i, line := range outline.files {
+		if line+start > scroll {
+			break
+		}
+		path = outline.paths[i]
+	}
+	return path
+}
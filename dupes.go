@@ -0,0 +1,234 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Commits with the same patch-id make the same change: a fix
+// cherry-picked onto each release branch, or a branch rebased while the
+// original is still around. git patch-id --stable hashes the diff without
+// its line numbers and whitespace, so the copies match even when they
+// landed at different places in a file.
+
+var copyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#D08770"))
+
+// findingCopies is the status while hashing, replaced by what was found
+// unless something else took its place meanwhile.
+const findingCopies = "Finding copied commits…"
+
+// patchIDMsg brings the patch-ids of one chunk of commits, or those read
+// from the cache when cached is set. A commit without a diff of its own,
+// a merge or an empty commit, has "" for a patch-id.
+type patchIDMsg struct {
+	ids    map[string]string
+	cached bool
+	err    error
+}
+
+// loadPatchIDCache reads the patch-ids of earlier runs.
+func loadPatchIDCache(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		ids := patchIDCache.load(repoPath)
+		for hash, id := range ids {
+			if id == "-" {
+				ids[hash] = ""
+			}
+		}
+		return patchIDMsg{ids: ids, cached: true}
+	}
+}
+
+// hashPatches hashes the diffs of a chunk of commits, git log -p piped
+// into git patch-id.
+func hashPatches(repoPath string, hashes []string) tea.Msg {
+	logCmd := gitCommand(repoPath, "log", "--no-walk=unsorted", "--stdin", "-p", "--no-color", "--no-ext-diff", "--format=commit %H")
+	logCmd.Stdin = strings.NewReader(strings.Join(hashes, "\n") + "\n")
+	idCmd := gitCommand(repoPath, "patch-id", "--stable")
+	pipe, err := logCmd.StdoutPipe()
+	if err != nil {
+		return patchIDMsg{err: err}
+	}
+	idCmd.Stdin = pipe
+	if err := logCmd.Start(); err != nil {
+		return patchIDMsg{err: err}
+	}
+	out, err := idCmd.Output()
+	if waitErr := logCmd.Wait(); err == nil {
+		err = waitErr
+	}
+	if err != nil {
+		return patchIDMsg{err: err}
+	}
+
+	// Commits without a diff print nothing, and keep ""
+	ids := make(map[string]string, len(hashes))
+	for _, hash := range hashes {
+		ids[hash] = ""
+	}
+	// "<patch-id> <commit>"
+	for _, line := range strings.Split(string(out), "\n") {
+		if id, hash, ok := strings.Cut(line, " "); ok {
+			ids[hash] = id
+		}
+	}
+	values := make(map[string]string, len(ids))
+	for hash, id := range ids {
+		values[hash] = cmp.Or(id, "-")
+	}
+	if err := patchIDCache.save(repoPath, values); err != nil {
+		log.Printf("Not caching patch-ids: %v\n", err)
+	}
+	return patchIDMsg{ids: ids}
+}
+
+// maybeLoadPatchIDs starts hashing when copies are shown and some commit
+// in the graph isn't hashed yet, the way maybeLoadImpact counts lines.
+func (m *model) maybeLoadPatchIDs() tea.Cmd {
+	if !m.showCopies || m.patchIDPending > 0 {
+		return nil
+	}
+	if !m.patchIDsCached {
+		m.patchIDPending = 1
+		return loadPatchIDCache(m.repoPath)
+	}
+	var missing []string
+	for _, c := range m.commits {
+		if _, ok := m.patchIDs[c.FullHash.String()]; !ok {
+			missing = append(missing, c.FullHash.String())
+		}
+	}
+	if len(missing) == 0 {
+		m.groupCopies()
+		return nil
+	}
+	repoPath := m.repoPath
+	cmd, chunks := inChunks(missing, func(hashes []string) tea.Msg {
+		return hashPatches(repoPath, hashes)
+	})
+	m.patchIDPending = chunks
+	return cmd
+}
+
+// handlePatchIDs takes in the patch-ids of a chunk, or of the cache, and
+// groups the copies once the last chunk is in.
+func (m *model) handlePatchIDs(msg patchIDMsg) tea.Cmd {
+	m.patchIDPending--
+	if msg.err != nil {
+		m.status, m.statusErr = "Finding copied commits failed: "+msg.err.Error(), true
+	}
+	for hash, id := range msg.ids {
+		m.patchIDs[hash] = id
+	}
+	if msg.cached {
+		m.patchIDsCached = true
+		return m.maybeLoadPatchIDs()
+	}
+	if m.patchIDPending == 0 {
+		m.groupCopies()
+	}
+	return nil
+}
+
+// groupCopies gathers the commits of the graph by patch-id, keeping the
+// patch-ids of more than one, each with its commits in graph order.
+func (m *model) groupCopies() {
+	groups := make(map[string][]int)
+	for i, c := range m.commits {
+		if id := m.patchIDs[c.FullHash.String()]; id != "" {
+			groups[id] = append(groups[id], i)
+		}
+	}
+	m.copies = make(map[string][]int)
+	copied := 0
+	for id, commits := range groups {
+		if len(commits) > 1 {
+			m.copies[id] = commits
+			copied++
+		}
+	}
+	m.dataVersion++
+	if m.status == findingCopies {
+		m.status = fmt.Sprintf("%d changes are in the graph more than once", copied)
+	}
+}
+
+// copiesOf are the indexes of the commits that make the same change as
+// commit i, itself included, or nil when it has no copies.
+func (m *model) copiesOf(i int) []int {
+	if !m.showCopies || i < 0 || i >= len(m.commits) {
+		return nil
+	}
+	return m.copies[m.patchIDs[m.commits[i].FullHash.String()]]
+}
+
+// copyMarker is shown after the hash of a commit with copies.
+func (m *model) copyMarker(i int) string {
+	if m.copiesOf(i) == nil {
+		return ""
+	}
+	return copyStyle.Render(" ≡")
+}
+
+// toggleCopies shows or hides the copy markers, hashing the commits
+// first when needed.
+func (m *model) toggleCopies() tea.Cmd {
+	m.showCopies = !m.showCopies
+	m.dataVersion++
+	if !m.showCopies {
+		m.status = "Copied commits hidden"
+		return nil
+	}
+	m.status, m.statusErr = findingCopies, false
+	return m.maybeLoadPatchIDs()
+}
+
+// nextCopy jumps to the next commit down the graph that makes the same
+// change as the selected one, around to the first after the last.
+func (m *model) nextCopy() tea.Cmd {
+	if !m.showCopies {
+		m.status, m.statusErr = "Copied commits are hidden; D finds them", true
+		return nil
+	}
+	copies := m.copiesOf(m.selected)
+	if copies == nil {
+		m.status = "No other commit in the graph makes this change"
+		return nil
+	}
+	for n, i := range copies {
+		if i == m.selected {
+			return m.jumpTo(copies[(n+1)%len(copies)])
+		}
+	}
+	return nil
+}
+
+// copiesNote lists the other commits that make the same change as commit
+// i, for the details panel.
+func (m *model) copiesNote(i int) string {
+	copies := m.copiesOf(i)
+	if copies == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(copyStyle.Render("≡ Same patch as these commits; = jumps to the next:"))
+	sb.WriteString("\n")
+	for _, j := range copies {
+		if j == i {
+			continue
+		}
+		c := m.commits[j]
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		sb.WriteString("    " + commitHashStyle.Render(c.Hash) + " " + dateStyle.Render(c.Date.Format("2006-01-02")) + " " + subject)
+		if c.Refs != "" {
+			sb.WriteString(" " + helpStyle.Render("("+c.Refs+")"))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
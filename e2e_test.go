@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+)
+
+// The tests run gitraffe on fixture repositories made by buildDemoRepo,
+// whose hashes are the same every time. Run `go test -update` to rewrite
+// the golden files in testdata after a deliberate change to the layout.
+
+// demoCommits is how many commits the demo repository has.
+const demoCommits = 13
+
+func TestMain(m *testing.M) {
+	// gitraffe.log is for the UI; the tests only need failures
+	log.SetOutput(io.Discard)
+	// Plain text and UTC dates, wherever the tests run, and none of the
+	// settings of whoever runs them
+	lipgloss.SetColorProfile(termenv.Ascii)
+	time.Local = time.UTC
+	os.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	logOutputUTF8()
+	// Logs are cached in a directory of their own, removed afterwards
+	cache, err := os.MkdirTemp("", "gitraffe-cache-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", cache)
+	code := m.Run()
+	os.RemoveAll(cache)
+	os.Exit(code)
+}
+
+// demoFixture builds the demo repository in a temporary directory. It is
+// always called giraffe, as the repo info box shows the name.
+func demoFixture(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "giraffe")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := buildDemoRepo(dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// testRepo starts an empty repository in a temporary directory, to be
+// filled with the demoRepo steps. The commands the tests run commit as
+// the demo author too.
+func testRepo(t *testing.T) *demoRepo {
+	t.Helper()
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "Ada Graph")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "ada@example.com")
+	}
+	d := &demoRepo{dir: t.TempDir(), clock: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)}
+	d.git("init", "-q")
+	d.git("symbolic-ref", "HEAD", "refs/heads/main")
+	return d
+}
+
+// gitOutput runs git in a test repository and returns its output,
+// trimmed, failing the test on an error.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// startUI runs gitraffe on a repository and waits for it to show the
+// text, which tells that it is loaded.
+func startUI(t *testing.T, dir string, width, height int, loaded string) *teatest.TestModel {
+	t.Helper()
+	tm := teatest.NewTestModel(t, initialModel(options{repoPath: dir}), teatest.WithInitialTermSize(width, height))
+	waitFor(t, tm, loaded)
+	return tm
+}
+
+func waitFor(t *testing.T, tm *teatest.TestModel, text string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		return bytes.Contains(out, []byte(text))
+	}, teatest.WithDuration(10*time.Second), teatest.WithCheckInterval(20*time.Millisecond))
+}
+
+// typeKeys sends keys one by one, with "ctrl+o" and the like as
+// themselves and anything else as the runes typed.
+func typeKeys(tm *teatest.TestModel, keys ...string) {
+	for _, key := range keys {
+		switch key {
+		case "ctrl+o":
+			tm.Send(tea.KeyMsg{Type: tea.KeyCtrlO})
+		case "ctrl+n":
+			tm.Send(tea.KeyMsg{Type: tea.KeyCtrlN})
+		case "tab":
+			tm.Send(tea.KeyMsg{Type: tea.KeyTab})
+		case "enter":
+			tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+		default:
+			for _, r := range key {
+				tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+		}
+	}
+}
+
+// quit quits the UI and returns the model it ended with.
+func quit(t *testing.T, tm *teatest.TestModel) model {
+	t.Helper()
+	typeKeys(tm, "q")
+	return tm.FinalModel(t, teatest.WithFinalTimeout(10*time.Second)).(model)
+}
+
+func TestNavigation(t *testing.T) {
+	dir := demoFixture(t)
+	tests := []struct {
+		name     string
+		keys     []string
+		selected int
+	}{
+		{"down", []string{"j", "j"}, 2},
+		{"down and up", []string{"j", "j", "k"}, 1},
+		{"bottom", []string{"G"}, demoCommits - 1},
+		{"bottom and top", []string{"G", "g"}, 0},
+		{"count", []string{"3j"}, 3},
+		{"count past the end", []string{"99j"}, demoCommits - 1},
+		{"next merge", []string{"]"}, 3},
+		{"jump back", []string{"G", "ctrl+o"}, 0},
+		{"jump back and forward", []string{"G", "ctrl+o", "ctrl+n"}, demoCommits - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := startUI(t, dir, 120, 40, "cb51601")
+			typeKeys(tm, tt.keys...)
+			m := quit(t, tm)
+			if m.selected != tt.selected {
+				t.Fatalf("selected %d, want %d", m.selected, tt.selected)
+			}
+			hash := m.commits[tt.selected].Hash
+			marked := ""
+			for _, line := range strings.Split(m.View(), "\n") {
+				if strings.HasPrefix(line, "│ > ") {
+					marked = line
+				}
+			}
+			if !strings.Contains(marked, hash) {
+				t.Errorf("row of %s isn't marked selected, this is: %q", hash, marked)
+			}
+		})
+	}
+}
+
+func TestDetailTabs(t *testing.T) {
+	tm := startUI(t, demoFixture(t), 120, 40, "cb51601")
+	typeKeys(tm, "j", "tab")
+	waitFor(t, tm, "diff --git a/neck.go b/neck.go")
+	typeKeys(tm, "tab")
+	waitFor(t, tm, "neck.go")
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'4'}, Alt: true})
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'3'}, Alt: true})
+	if m := quit(t, tm); m.detailTab != tabFiles || m.focusedBox != 1 {
+		t.Errorf("on tab %d with panel %d focused, want the Files tab and the list", m.detailTab, m.focusedBox)
+	}
+}
+
+// TestResize checks that every window size is filled exactly, without
+// lines wrapping or the view running over the bottom.
+func TestResize(t *testing.T) {
+	dir := demoFixture(t)
+	sizes := []struct{ width, height int }{
+		{120, 40},
+		{80, 24},
+		{60, 30}, // stacked panels
+		{60, 12}, // a single panel
+		{200, 60},
+	}
+	for _, size := range sizes {
+		tm := startUI(t, dir, 120, 40, "cb51601")
+		tm.Send(tea.WindowSizeMsg{Width: size.width, Height: size.height})
+		m := quit(t, tm)
+
+		lines := strings.Split(m.View(), "\n")
+		if len(lines) != size.height {
+			t.Errorf("%dx%d: %d lines", size.width, size.height, len(lines))
+		}
+		for i, line := range lines {
+			if w := lipgloss.Width(line); w > size.width {
+				t.Errorf("%dx%d: line %d is %d wide: %q", size.width, size.height, i, w, line)
+			}
+		}
+	}
+}
+
+// TestGitCLIFallback loads the graph the way gitraffe does when go-git
+// can't open the repository, in a partial clone so the repo info box has
+// to notice it without go-git too.
+func TestGitCLIFallback(t *testing.T) {
+	dir := demoFixture(t)
+	gitOutput(t, dir, "config", "remote.origin.promisor", "true")
+	gitOutput(t, dir, "config", "remote.origin.partialclonefilter", "blob:none")
+	m := initialModel(options{repoPath: dir})
+	tm, cmd := m.Update(errMsg{errors.New("go-git can't read this repository")})
+	tm = settle(tm, cmd)
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	m = tm.(model)
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	if len(m.commits) != demoCommits {
+		t.Fatalf("%d commits, want %d", len(m.commits), demoCommits)
+	}
+	if m.currentBranch != "main" {
+		t.Errorf("branch %q, want main", m.currentBranch)
+	}
+	if m.checkout.partial != "blob:none" {
+		t.Errorf("partial clone filter %q, want blob:none", m.checkout.partial)
+	}
+	if view := m.View(); !strings.Contains(view, "✱───╮") {
+		t.Errorf("octopus merge not drawn:\n%s", view)
+	}
+}
+
+// TestSimpleList covers the last fallback, a list without the graph
+// when git log --graph fails.
+func TestSimpleList(t *testing.T) {
+	m := initialModel(options{repoPath: demoFixture(t)})
+	commits, err := m.loadCommitsFromGitCLI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.commits, m.ready = commits, true
+	tm, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	view := tm.View()
+	for _, c := range commits {
+		if !strings.Contains(view, c.Hash) {
+			t.Errorf("%s missing from the list", c.Hash)
+		}
+	}
+	for _, node := range []string{"○", "✱"} {
+		if !strings.Contains(view, node) {
+			t.Errorf("no %s node in the list:\n%s", node, view)
+		}
+	}
+}
+
+// TestLogCache checks that the cached log is used while nothing changed,
+// and left aside once a branch moved.
+func TestLogCache(t *testing.T) {
+	dir := demoFixture(t)
+	m := initialModel(options{repoPath: dir})
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir, err := logCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := filepath.Glob(filepath.Join(cacheDir, "*.log"))
+	if err != nil || len(cached) == 0 {
+		t.Fatalf("no log cached in %s", cacheDir)
+	}
+
+	// The cached log is read as it is, so a mark in it shows up
+	for _, file := range cached {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		marked := bytes.Replace(data, []byte("Lengthen the neck"), []byte("Cached neck"), 1)
+		if err := os.WriteFile(file, marked, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.commits[1].Message; got != "Cached neck" {
+		t.Errorf("message %q, want the one from the cache", got)
+	}
+
+	d := &demoRepo{dir: dir, clock: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)}
+	d.commit("tail.go", "package giraffe\n\n// Swishing\n", "Swish the tail")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.commits) != demoCommits+1 || m.commits[0].Message != "Swish the tail" {
+		t.Errorf("new commit missing, the graph starts with %q", m.commits[0].Message)
+	}
+}
+
+// TestLogCacheKey checks what the cache key follows besides the refs:
+// the content of mailmap.file, and a window of dates, which isn't cached.
+func TestLogCacheKey(t *testing.T) {
+	dir := demoFixture(t)
+	args := []string{"log", "--"}
+	mailmap := filepath.Join(t.TempDir(), "mailmap")
+	if err := os.WriteFile(mailmap, []byte("Ada <ada@example.com>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitOutput(t, dir, "config", "mailmap.file", mailmap)
+	_, before, err := logCacheKey(dir, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mailmap, []byte("Ada Graph <ada@example.com>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, after, _ := logCacheKey(dir, args); after == before {
+		t.Error("same key after mailmap.file changed")
+	}
+
+	if _, _, err := logCacheKey(dir, []string{"log", "--since=1.month.ago", "--"}); err == nil {
+		t.Error("a log since a month ago is cached")
+	}
+}
+
+// TestFetchReload checks that a background fetch reloads only when a
+// ref moved, keeping the selected commit.
+func TestFetchReload(t *testing.T) {
+	dir := demoFixture(t)
+	m := initialModel(options{repoPath: dir})
+	tm := settle(m, loadRepo(m.repoPath, m.backend))
+	m = tm.(model)
+	m.selected = 2
+	selected := m.commits[2].FullHash.String()
+
+	d := &demoRepo{dir: dir, clock: time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)}
+	d.commit("tail.go", "package giraffe\n\n// Swishing\n", "Swish the tail")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	tm, cmd := m.Update(fetchDoneMsg{})
+	if cmd != nil {
+		tm = settle(tm, cmd)
+	}
+	if n := len(tm.(model).commits); n != demoCommits {
+		t.Errorf("%d commits after a fetch that moved nothing, want the %d from before", n, demoCommits)
+	}
+	tm, cmd = tm.Update(fetchDoneMsg{moved: true})
+	m = settle(tm, cmd).(model)
+	if len(m.commits) != demoCommits+1 {
+		t.Fatalf("%d commits after the reload, want %d", len(m.commits), demoCommits+1)
+	}
+	if m.commits[m.selected].FullHash.String() != selected {
+		t.Errorf("selected %s, want %s as before the reload", m.commits[m.selected].FullHash.String(), selected)
+	}
+}
+
+// TestReplaceToggle checks that R shows the history without a graft and
+// back, through the git commands alone: gitraffe's own environment, which
+// the editor and the shell of ! inherit, stays as it was.
+func TestReplaceToggle(t *testing.T) {
+	dir := demoFixture(t)
+	gitOutput(t, dir, "replace", "--graft", "HEAD~2")
+	t.Cleanup(func() { noReplaceObjects.Store(false) })
+	m := initialModel(options{repoPath: dir})
+	tm := settle(m, loadRepo(m.repoPath, m.backend))
+	grafted := len(tm.(model).commits)
+	if grafted >= demoCommits {
+		t.Fatalf("%d commits with the graft, want fewer than %d", grafted, demoCommits)
+	}
+
+	for _, want := range []int{demoCommits, grafted} {
+		var cmd tea.Cmd
+		tm, cmd = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'R'}})
+		tm = settle(tm, cmd)
+		if n := len(tm.(model).commits); n != want {
+			t.Errorf("%d commits after R, want %d", n, want)
+		}
+		if v, ok := os.LookupEnv("GIT_NO_REPLACE_OBJECTS"); ok {
+			t.Errorf("GIT_NO_REPLACE_OBJECTS=%q in gitraffe's environment", v)
+		}
+	}
+}
+
+// TestLogCacheWorktrees checks that a linked work tree caches its log
+// apart from the main one, as their HEADs differ.
+func TestLogCacheWorktrees(t *testing.T) {
+	dir := demoFixture(t)
+	linked := filepath.Join(t.TempDir(), "linked")
+	d := &demoRepo{dir: dir}
+	d.git("worktree", "add", "-q", "--detach", linked, "HEAD~1")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	args := []string{"log", "--graph"}
+	first, _, err := logCacheKey(dir, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := logCacheKey(linked, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == other {
+		t.Errorf("both work trees cache their log in %s", first)
+	}
+}
+
+// TestPruneLogCache checks that cached logs not used for long, then the
+// least recently used past the budget, are removed, but never the one
+// just written.
+func TestPruneLogCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+		kept bool
+	}{
+		{"written.log", 60, 40 * 24 * time.Hour, true},
+		{"recent.log", 30, time.Hour, true},
+		{"older.log", 30, 2 * time.Hour, false}, // past the budget
+		{"stale.log", 1, 31 * 24 * time.Hour, false},
+		{".log-123", 1, 2 * time.Hour, false},
+		{".log-456", 1, time.Minute, true}, // may still be being written
+		{"other", 100, 60 * 24 * time.Hour, true},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneLogCache(dir, filepath.Join(dir, "written.log"), 30*24*time.Hour, 100)
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, f.name))
+		if kept := err == nil; kept != f.kept {
+			t.Errorf("%s kept: %v, want %v", f.name, kept, f.kept)
+		}
+	}
+}
+
+// TestAbbrev checks that short hashes follow core.abbrev, and that the
+// parents of a commit are abbreviated alike.
+func TestAbbrev(t *testing.T) {
+	dir := demoFixture(t)
+	d := &demoRepo{dir: dir}
+	d.git("config", "core.abbrev", "10")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	m := initialModel(options{repoPath: dir})
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	short := map[string]bool{}
+	for _, c := range m.commits {
+		if len(c.Hash) != 10 {
+			t.Errorf("%s abbreviated to %q", c.FullHash.String(), c.Hash)
+		}
+		short[c.Hash] = true
+	}
+	for _, c := range m.commits {
+		for _, p := range c.Parents {
+			if !short[p] {
+				t.Errorf("parent %q of %s is no commit's short hash", p, c.Hash)
+			}
+		}
+	}
+	if m.hashWidth != 10 {
+		t.Errorf("hash column %d wide, want 10", m.hashWidth)
+	}
+
+	got := uniqueAbbrevs([]string{"abcdef01", "abcdef99", "1234abcd"}, 4)
+	want := map[string]string{"abcdef01": "abcdef0", "abcdef99": "abcdef9", "1234abcd": "1234"}
+	for full, s := range want {
+		if got[full] != s {
+			t.Errorf("%s abbreviated to %q, want %q", full, got[full], s)
+		}
+	}
+}
+
+// TestUnbornRepository opens a repository without commits, which starts
+// on the working tree to make the first one.
+func TestUnbornRepository(t *testing.T) {
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := startUI(t, dir, 120, 40, "README.md")
+	m := quit(t, tm)
+	if !m.unborn || !m.workTree {
+		t.Errorf("unborn %v, working tree %v; want both", m.unborn, m.workTree)
+	}
+}
+
+// TestRenderGolden compares frames with the golden files in testdata.
+func TestRenderGolden(t *testing.T) {
+	dir := demoFixture(t)
+	tests := []struct {
+		name          string
+		width, height int
+		frame         []string
+	}{
+		{"graph", 100, 30, nil},
+		{"diff", 100, 30, []string{"selected=4f19196", "tab=diff"}},
+		{"narrow", 60, 30, []string{"selected=3"}},
+		{"zoomed files", 80, 20, []string{"selected=6", "focus=2", "tab=files", "zoom"}},
+		{"wrapped info", 36, 16, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderFrame(options{repoPath: dir}, tt.width, tt.height, tt.frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden.RequireEqual(t, []byte(out))
+		})
+	}
+}
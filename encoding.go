@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// Git re-encodes messages that have an encoding header to
+// i18n.logOutputEncoding, UTF-8 unless set otherwise. gitraffe always
+// asks for UTF-8, and decodes the rest itself: messages without a header
+// that aren't UTF-8 anyway, and what go-git reads without re-encoding.
+
+// logOutputUTF8 makes every git command gitraffe runs give messages in
+// UTF-8, whatever i18n.logOutputEncoding is set to for the terminal. It
+// is added to the config git takes from the environment, after any that
+// is there already.
+func logOutputUTF8() {
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	os.Setenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", n), "i18n.logOutputEncoding")
+	os.Setenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", n), "UTF-8")
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(n+1))
+}
+
+// lookupEncoding finds an encoding by any name git takes for it:
+// "ISO-8859-1", "latin1", "CP1251", "windows-1251", "Shift_JIS".
+func lookupEncoding(name string) encoding.Encoding {
+	if enc, err := htmlindex.Get(name); err == nil {
+		return enc
+	}
+	if enc, err := ianaindex.IANA.Encoding(name); err == nil && enc != nil {
+		return enc
+	}
+	return nil
+}
+
+// fromEncoding decodes s from the encoding named, as in a commit's
+// encoding header. A name it doesn't know leaves s as it is.
+func fromEncoding(s, name string) string {
+	if name == "" || strings.EqualFold(name, "utf-8") || strings.EqualFold(name, "utf8") {
+		return s
+	}
+	enc := lookupEncoding(name)
+	if enc == nil {
+		log.Printf("Unknown encoding %q, showing the message as it is\n", name)
+		return s
+	}
+	decoded, err := enc.NewDecoder().String(s)
+	if err != nil {
+		return s
+	}
+	return decoded
+}
+
+// validUTF8 returns s as it is when it is valid UTF-8. Otherwise it was
+// written in another encoding without saying so, and is decoded from
+// fallback, i18n.commitEncoding, or else from Latin-1 like git commit
+// does with such messages.
+func validUTF8(s, fallback string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	if enc := lookupEncoding(fallback); enc != nil {
+		if decoded, err := enc.NewDecoder().String(s); err == nil && utf8.ValidString(decoded) {
+			return decoded
+		}
+	}
+	decoded, _ := charmap.ISO8859_1.NewDecoder().String(s)
+	return decoded
+}
+
+// toUTF8 makes the text fields of a commit valid UTF-8, see validUTF8.
+func (c *commit) toUTF8(fallback string) {
+	c.Message = validUTF8(c.Message, fallback)
+	c.Author = validUTF8(c.Author, fallback)
+	c.Committer = validUTF8(c.Committer, fallback)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type exportDoneMsg struct {
+	path string
+	err  error
+}
+
+// exportMenu offers the formats the selected commit can be written out in,
+// for pasting into review documents and tickets.
+func (m *model) exportMenu() *menu {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	format := func(ext string) func(m *model) tea.Cmd {
+		return func(m *model) tea.Cmd {
+			note := m.notes.notes[c.FullHash.String()]
+			m.prompt = newPrompt("Export to", "file to write", func(m *model, path string) tea.Cmd {
+				if path == "" {
+					return nil
+				}
+				return exportCommit(m.repoPath, c, note, path)
+			})
+			m.prompt.input.SetValue(c.Hash + ext)
+			m.prompt.input.CursorEnd()
+			return nil
+		}
+	}
+	return &menu{
+		title: "Export " + c.Hash,
+		options: []menuOption{
+			{key: "m", label: "Markdown", action: format(".md")},
+			{key: "h", label: "HTML", action: format(".html")},
+		},
+	}
+}
+
+// exportCommit writes a commit's metadata, message and diff to path, as
+// HTML when it ends in .html or .htm and as Markdown otherwise. The diff
+// is loaded again in full; the Diff tab only keeps the start of it.
+func exportCommit(repoPath string, c commit, note, path string) tea.Cmd {
+	return func() tea.Msg {
+		message, err := gitRead(repoPath, "show", "-s", "--format=%B", c.FullHash.String())
+		if err != nil {
+			return exportDoneMsg{path: path, err: err}
+		}
+		var diff string
+		if c.DiffParent > 0 {
+			diff, err = gitRead(repoPath, "diff", "--no-color", "--stat", "-p", fmt.Sprintf("%s^%d", c.FullHash.String(), c.DiffParent), c.FullHash.String())
+		} else {
+			diff, err = gitRead(repoPath, "show", "--format=", "--no-color", "--stat", "-p", c.FullHash.String())
+		}
+		if err != nil {
+			return exportDoneMsg{path: path, err: err}
+		}
+
+		message = strings.TrimSpace(message)
+		var doc string
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".html", ".htm":
+			doc = exportHTML(c, message, note, diff)
+		default:
+			doc = exportMarkdown(c, message, note, diff)
+		}
+		return exportDoneMsg{path: path, err: os.WriteFile(path, []byte(doc), 0644)}
+	}
+}
+
+// exportFields are the metadata rows of an export.
+func exportFields(c commit) [][2]string {
+	fields := [][2]string{
+		{"Commit", c.FullHash.String()},
+		{"Author", formatIdent(c.Author, c.AuthorEmail)},
+		{"Date", c.Date.Format("2006-01-02 15:04:05 -0700")},
+	}
+	if c.Committer != "" && (c.Committer != c.Author || c.CommitterEmail != c.AuthorEmail) {
+		fields = append(fields, [2]string{"Committer", formatIdent(c.Committer, c.CommitterEmail)})
+	}
+	if len(c.Parents) > 0 {
+		fields = append(fields, [2]string{"Parents", strings.Join(c.Parents, ", ")})
+	}
+	if c.Refs != "" {
+		fields = append(fields, [2]string{"Refs", c.Refs})
+	}
+	if c.DiffParent > 0 {
+		fields = append(fields, [2]string{"Diff against", fmt.Sprintf("^%d %s", c.DiffParent, c.Parents[c.DiffParent-1])})
+	}
+	return fields
+}
+
+// exportMarkdown renders the commit as Markdown. The diff goes in a diff
+// code block, which GitHub, GitLab and most trackers color.
+func exportMarkdown(c commit, message, note, diff string) string {
+	subject, body, _ := strings.Cut(message, "\n")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", subject)
+	sb.WriteString("| | |\n|---|---|\n")
+	for _, f := range exportFields(c) {
+		fmt.Fprintf(&sb, "| **%s** | `%s` |\n", f[0], strings.ReplaceAll(f[1], "|", `\|`))
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		fmt.Fprintf(&sb, "\n%s\n", body)
+	}
+	if note != "" {
+		sb.WriteString("\n> **Note:** ")
+		sb.WriteString(strings.ReplaceAll(note, "\n", "\n> "))
+		sb.WriteString("\n")
+	}
+	// A fence longer than any run of backticks in the diff can't be
+	// closed by it
+	fence := "```"
+	for strings.Contains(diff, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(&sb, "\n%sdiff\n%s\n%s\n", fence, diff, fence)
+	return sb.String()
+}
+
+const exportCSS = `body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 60em; color: #2E3440; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th { text-align: left; padding-right: 1em; color: #4C566A; }
+td { font-family: monospace; }
+.message { white-space: pre-wrap; }
+.note { border-left: 3px solid #EBCB8B; padding-left: 1em; white-space: pre-wrap; }
+pre { background: #ECEFF4; padding: 1em; overflow-x: auto; }
+.add { color: #2E7D32; background: #E8F5E9; }
+.del { color: #C62828; background: #FFEBEE; }
+.hunk { color: #5E81AC; }
+.file { font-weight: bold; }`
+
+// exportHTML renders the commit as a standalone HTML page, styled inline
+// so it can be mailed or attached as one file. The diff lines are colored
+// as in the Diff tab.
+func exportHTML(c commit, message, note, diff string) string {
+	subject, body, _ := strings.Cut(message, "\n")
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&sb, "<title>%s %s</title>\n", c.Hash, html.EscapeString(subject))
+	fmt.Fprintf(&sb, "<style>\n%s\n</style>\n</head>\n<body>\n", exportCSS)
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<table>\n", html.EscapeString(subject))
+	for _, f := range exportFields(c) {
+		fmt.Fprintf(&sb, "<tr><th>%s</th><td>%s</td></tr>\n", f[0], html.EscapeString(f[1]))
+	}
+	sb.WriteString("</table>\n")
+	if body = strings.TrimSpace(body); body != "" {
+		fmt.Fprintf(&sb, "<p class=\"message\">%s</p>\n", html.EscapeString(body))
+	}
+	if note != "" {
+		fmt.Fprintf(&sb, "<p class=\"note\">%s</p>\n", html.EscapeString(note))
+	}
+	sb.WriteString("<pre>")
+	for _, line := range strings.Split(diff, "\n") {
+		class := ""
+		switch {
+		case strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++"):
+			class = "add"
+		case strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---"):
+			class = "del"
+		case strings.HasPrefix(line, "@@"):
+			class = "hunk"
+		case strings.HasPrefix(line, "diff "):
+			class = "file"
+		}
+		if class == "" {
+			sb.WriteString(html.EscapeString(line))
+		} else {
+			fmt.Fprintf(&sb, "<span class=\"%s\">%s</span>", class, html.EscapeString(line))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The file viewer shows a file as it is at a commit, with line numbers
+// and a cursor line, over the details panel. Other views open it at a
+// line, as grep does at a match.
+
+var (
+	lineNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#4C566A"))
+	cursorLineStyle = lipgloss.NewStyle().Background(lipgloss.Color("#3C3C3C"))
+)
+
+// fileView is a file open in the viewer.
+type fileView struct {
+	rev, short string // the commit, full and short
+	path       string
+	lines      [][]token // highlighted, tabs expanded
+	binary     bool
+	cursor     int // line, from 0
+}
+
+type fileViewMsg struct {
+	view *fileView
+	err  error
+}
+
+// loadFileView reads a file at a commit, for the viewer to open at line,
+// counted from 1.
+func loadFileView(repoPath, rev, short, path string, line int) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "show", rev+":"+path)
+		if err != nil {
+			return fileViewMsg{err: err}
+		}
+		v := &fileView{rev: rev, short: short, path: path}
+		// As git tells binary files: a NUL in the first 8000 bytes
+		if strings.IndexByte(out[:min(len(out), 8000)], 0) >= 0 {
+			v.binary = true
+			return fileViewMsg{view: v}
+		}
+		text := strings.TrimSuffix(strings.ReplaceAll(out, "\r\n", "\n"), "\r")
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.Map(printable, strings.ReplaceAll(line, "\t", "    "))
+		}
+		v.lines = syntaxFor(path).highlight(lines)
+		v.cursor = max(min(line-1, len(v.lines)-1), 0)
+		return fileViewMsg{view: v}
+	}
+}
+
+// printable replaces the control characters of a file's line, which
+// would move the cursor or recolor the screen, with �.
+func printable(r rune) rune {
+	if r < ' ' || r == 0x7f {
+		return '�'
+	}
+	return r
+}
+
+// openFile opens a file of a commit in the viewer at line, from 1.
+func (m *model) openFile(rev, path string, line int) tea.Cmd {
+	m.status = "Opening " + path + "…"
+	return loadFileView(m.repoPath, rev, m.shortOf(rev), path, line)
+}
+
+// updateFileView handles a key while the file viewer is open.
+func (m *model) updateFileView(msg tea.KeyMsg) tea.Cmd {
+	v := m.fileView
+	page := max(m.windowHeight/2, 1)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.fileView = nil
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = max(min(v.cursor+1, len(v.lines)-1), 0)
+	case "u", "ctrl+u", "pgup":
+		v.cursor = max(v.cursor-page, 0)
+	case "d", "ctrl+d", "pgdown":
+		v.cursor = max(min(v.cursor+page, len(v.lines)-1), 0)
+	case "g", "home":
+		v.cursor = 0
+	case "G", "end":
+		v.cursor = max(len(v.lines)-1, 0)
+	case "c":
+		return m.compareFile(v.rev, v.path)
+	}
+	return nil
+}
+
+// renderFileView shows the lines of the open file around the cursor,
+// cut to width.
+func (m *model) renderFileView(width, height int) string {
+	v := m.fileView
+	header := sectionHeader(v.path) + " " + commitHashStyle.Render(v.short)
+	hint := helpStyle.Render(fmt.Sprintf("line %d/%d  j/k, d/u, g/G: move  c: compare with another commit  esc: close", v.cursor+1, len(v.lines)))
+	lines := []string{header, "", hint, ""}
+	if v.binary {
+		return strings.Join(append(lines, helpStyle.Render("Binary file, not shown.")), "\n")
+	}
+
+	gutter := len(fmt.Sprint(len(v.lines)))
+	textWidth := max(width-gutter-1, 1)
+	rows := max(height-len(lines), 1)
+	first := max(min(v.cursor-rows/2, len(v.lines)-rows), 0)
+	for i := first; i < len(v.lines) && i < first+rows; i++ {
+		base := lipgloss.NewStyle()
+		if i == v.cursor {
+			base = cursorLineStyle
+		}
+		number := lineNumberStyle.Render(fmt.Sprintf("%*d ", gutter, i+1))
+		lines = append(lines, number+renderTokens(v.lines[i], textWidth, base))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// filter is a named preset of limits on the commits in the graph, from
+// gitraffe.filter.<name>: terms like "author:me since:1.month" or
+// "grep:hotfix path:src/", applied together in one reload.
+type filter struct {
+	name  string
+	spec  string
+	args  []string // git log options
+	paths []string // pathspecs, after the options
+}
+
+// filterTerms maps each term of a filter to the git log option it sets.
+// path: is handled apart, as it goes after "--".
+var filterTerms = map[string]string{
+	"author":    "--author=",
+	"committer": "--committer=",
+	"since":     "--since=",
+	"after":     "--since=",
+	"until":     "--until=",
+	"before":    "--until=",
+	"grep":      "--grep=",
+}
+
+// parseFilter reads a filter preset. Terms are separated by spaces; a
+// value with spaces is put in double quotes, as in grep:"hot fix". The
+// author and committer "me" stand for user.email, looked up when the
+// filter is applied.
+func parseFilter(name, spec string) (filter, error) {
+	f := filter{name: name, spec: spec}
+	terms, err := splitTerms(spec)
+	if err != nil {
+		return f, err
+	}
+	if len(terms) == 0 {
+		return f, fmt.Errorf("no terms")
+	}
+	for _, term := range terms {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			return f, fmt.Errorf("%q is not a key:value term", term)
+		}
+		if key == "path" {
+			f.paths = append(f.paths, value)
+			continue
+		}
+		option, ok := filterTerms[key]
+		if !ok {
+			return f, fmt.Errorf("unknown term %q", key)
+		}
+		f.args = append(f.args, option+value)
+	}
+	return f, nil
+}
+
+// splitTerms splits s on spaces outside double quotes, dropping the
+// quotes.
+func splitTerms(s string) ([]string, error) {
+	var terms []string
+	var term strings.Builder
+	quoted, inTerm := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted, inTerm = !quoted, true
+		case r == ' ' && !quoted:
+			if inTerm {
+				terms = append(terms, term.String())
+				term.Reset()
+				inTerm = false
+			}
+		default:
+			term.WriteRune(r)
+			inTerm = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unclosed quote")
+	}
+	if inTerm {
+		terms = append(terms, term.String())
+	}
+	return terms, nil
+}
+
+// resolveMe replaces author:me and committer:me with the user's email.
+func (f filter) resolveMe(repoPath string) (filter, error) {
+	var email string
+	args := make([]string, len(f.args))
+	for i, arg := range f.args {
+		if arg == "--author=me" || arg == "--committer=me" {
+			if email == "" {
+				email, _ = gitRead(repoPath, "config", "user.email")
+			}
+			if email == "" {
+				return f, fmt.Errorf("user.email isn't set, so who \"me\" is isn't known")
+			}
+			arg = strings.TrimSuffix(arg, "me") + email
+		}
+		args[i] = arg
+	}
+	f.args = args
+	return f, nil
+}
+
+// filterName is the name of the filter applied, empty for none.
+func (m *model) filterName() string {
+	if m.filter == nil {
+		return ""
+	}
+	return m.filter.name
+}
+
+// filterPaths are the paths the graph is limited to, if any.
+func (m *model) filterPaths() []string {
+	if m.filter == nil || m.highlight {
+		return nil
+	}
+	return m.filter.paths
+}
+
+// filterMatches lists the commits a highlighted filter matches: those
+// it would show if it hid the others.
+func (m *model) filterMatches() (map[plumbing.Hash]bool, error) {
+	hiding := *m
+	hiding.highlight = false
+	hashes, err := hiding.coAuthoredHistory()
+	if err == nil && hashes == nil {
+		hashes, err = hiding.filterHashes(hiding.logScope())
+	}
+	if err != nil {
+		return nil, err
+	}
+	matches := make(map[plumbing.Hash]bool, len(hashes))
+	for _, h := range hashes {
+		matches[plumbing.NewHash(h)] = true
+	}
+	return matches, nil
+}
+
+// filterMatch tells whether a commit matches the highlighted filter,
+// which dims those it doesn't. Without one every commit matches.
+func (m *model) filterMatch(c commit) bool {
+	return m.matches == nil || m.matches[c.FullHash]
+}
+
+// filterMenu picks a filter preset to apply, or clears the one applied.
+func (m *model) filterMenu() *menu {
+	if len(m.cfg.Filters) == 0 {
+		return &menu{title: "No filters", detail: sectionHeader("Filters") + "\n\n" + helpStyle.Render(
+			"Define one in git config, e.g.\n\n"+
+				"  git config gitraffe.filter.mine 'author:me since:1.month'\n"+
+				"  git config gitraffe.filter.hotfixes 'grep:hotfix path:src/'\n\n"+
+				"Terms: author, committer, since, until, grep and path.")}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Filters"))
+	sb.WriteString("\n\n")
+	names := make([]string, len(m.cfg.Filters))
+	for i, f := range m.cfg.Filters {
+		names[i] = f.name
+	}
+	keys := pickerKeys(append([]string{"x", "h"}, names...))[2:]
+	nameWidth := 0
+	for _, name := range names {
+		nameWidth = max(nameWidth, len(name))
+	}
+	var options []menuOption
+	for i, f := range m.cfg.Filters {
+		marker := "  "
+		if m.filter != nil && m.filter.name == f.name {
+			marker = "> "
+		}
+		sb.WriteString(fmt.Sprintf("%s%-*s  %s\n", marker, nameWidth, f.name, helpStyle.Render(f.spec)))
+		options = append(options, menuOption{key: keys[i], label: f.name, action: func(m *model) tea.Cmd {
+			if m.backend == backendGoGit {
+				m.status, m.statusErr = "Can't filter by "+f.name+": "+errGoGitFilter.Error(), true
+				return nil
+			}
+			applied, err := f.resolveMe(m.repoPath)
+			if err != nil {
+				m.status, m.statusErr = "Can't filter by "+f.name+": "+err.Error(), true
+				return nil
+			}
+			m.filter = &applied
+			m.status = "Filtered by " + f.name
+			if m.highlight {
+				m.status = "Highlighting " + f.name
+			}
+			return m.reload()
+		}})
+	}
+	// Highlighting keeps the whole graph around the matches
+	mode, other := "A filter hides the commits it doesn't match; h dims them instead.", "highlight"
+	if m.highlight {
+		mode, other = "A filter dims the commits it doesn't match; h hides them instead.", "hide"
+	}
+	sb.WriteString("\n" + helpStyle.Render(mode))
+	options = append(options, menuOption{key: "h", label: other, action: func(m *model) tea.Cmd {
+		m.highlight = !m.highlight
+		if m.filter == nil {
+			m.status = "Filters will " + other
+			return nil
+		}
+		m.status = "Filtered by " + m.filter.name
+		if m.highlight {
+			m.status = "Highlighting " + m.filter.name
+		}
+		return m.reload()
+	}})
+	if m.filter != nil {
+		options = append(options, menuOption{key: "x", label: "clear", action: func(m *model) tea.Cmd {
+			m.filter = nil
+			m.status = "Filter cleared"
+			return m.reload()
+		}})
+	}
+	return &menu{title: "Filter", options: options, detail: sb.String()}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestFilterBothBackends loads the history filtered by a preset: git
+// log limits it to the matching commits, co-authored ones included, and
+// go-git refuses the filter instead of showing everything.
+func TestFilterBothBackends(t *testing.T) {
+	dir := filterRepo(t)
+	for _, backend := range []string{backendCLI, backendGoGit} {
+		t.Run(backend, func(t *testing.T) {
+			m := initialModel(options{repoPath: dir, backend: backend})
+			m = settle(m, loadRepo(m.repoPath, m.backend)).(model)
+			if m.err != nil {
+				t.Fatal(m.err)
+			}
+			m = settle(m, m.filterMenu().options[0].action(&m)).(model)
+			var subjects []string
+			for _, c := range m.commits {
+				subjects = append(subjects, c.Message)
+			}
+			if backend == backendGoGit {
+				if !m.statusErr || m.filter != nil {
+					t.Errorf("go-git filtered: status %q", m.status)
+				}
+				if len(subjects) != 4 {
+					t.Errorf("commits %q, want all 4", subjects)
+				}
+				return
+			}
+			if m.filterName() != "bo" || !slices.Equal(subjects, []string{"Pair", "Bo's"}) {
+				t.Errorf("filter %q, commits %q, want Bo's and the pair's", m.filterName(), subjects)
+			}
+
+			// The activity counts the same commits, for the co-author too
+			m.now = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+			msg := m.loadActivity()().(activityMsg)
+			if msg.err != nil {
+				t.Fatal(msg.err)
+			}
+			days := 0
+			for _, n := range msg.activity.authors["Bo Bolt"] {
+				days += n
+			}
+			if days != 2 || len(msg.activity.authors["Ada Graph"]) != 1 {
+				t.Errorf("activity by author %v", msg.activity.authors)
+			}
+		})
+	}
+}
+
+// filterRepo has commits by Ada, one by Bo and one Bo co-authored, and
+// the filter bo.
+func filterRepo(t *testing.T) string {
+	t.Helper()
+	d := testRepo(t)
+	d.commit("a.txt", "a\n", "First")
+	d.git("commit", "-q", "--allow-empty", "--author=Bo Bolt <bo@example.com>", "-m", "Bo's")
+	d.commit("a.txt", "b\n", "Second")
+	d.commit("a.txt", "c\n", "Pair\n\nCo-authored-by: Bo Bolt <bo@example.com>")
+	d.git("config", "gitraffe.filter.bo", "author:bo@example")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	return d.dir
+}
+
+// TestFilterHighlight keeps the whole graph with a highlighted filter,
+// with the commits it doesn't match dimmed.
+func TestFilterHighlight(t *testing.T) {
+	m := initialModel(options{repoPath: filterRepo(t), backend: backendCLI})
+	m = settle(m, loadRepo(m.repoPath, m.backend)).(model)
+	if m.err != nil {
+		t.Fatal(m.err)
+	}
+	pick := func(key string) {
+		for _, o := range m.filterMenu().options {
+			if o.key == key {
+				m = settle(m, o.action(&m)).(model)
+				return
+			}
+		}
+		t.Fatalf("no option %s in the filter menu", key)
+	}
+	pick("h")
+	pick("b")
+	if m.status != "Highlighting bo" {
+		t.Errorf("status %q", m.status)
+	}
+	if len(m.commits) != 4 || len(m.displayRows) == 0 {
+		t.Fatalf("%d commits, %d graph rows, want the whole graph", len(m.commits), len(m.displayRows))
+	}
+	for _, c := range m.commits {
+		want := c.Message == "Bo's" || c.Message == "Pair"
+		if m.filterMatch(c) != want {
+			t.Errorf("%s matches: %v", c.Message, !want)
+		}
+	}
+
+	pick("h")
+	if len(m.commits) != 2 || m.matches != nil {
+		t.Errorf("%d commits hiding, want 2", len(m.commits))
+	}
+}
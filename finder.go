@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// finder is the ctrl+f overlay: a fuzzy search, like fzf, over the
+// "hash subject author" lines of the loaded commits, narrowed with
+// every key typed.
+type finder struct {
+	input   textinput.Model
+	matches []finderMatch
+	cursor  int
+	version int // dataVersion the matches are for
+
+	// pick, when set, takes the chosen commit instead of jumping to it,
+	// for what pickLabel says
+	pick      func(m *model, index int) tea.Cmd
+	pickLabel string
+}
+
+type finderMatch struct {
+	index     int // into m.commits
+	score     int
+	positions map[int]bool // runes of the line that matched
+}
+
+var finderMatchStyle = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("#EBCB8B"))
+
+// finderLine is what the finder searches for a commit.
+func finderLine(c commit) string {
+	return c.Hash + " " + c.Message + " " + c.Author
+}
+
+// openFinder opens the finder over all commits.
+func (m *model) openFinder() {
+	ti := textinput.New()
+	ti.Prompt = "Find: "
+	ti.Placeholder = "hash, subject or author"
+	ti.PromptStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	ti.Focus()
+	m.finder = &finder{input: ti}
+	m.refilter()
+}
+
+// pickCommit opens the finder for choosing a commit to do something
+// with, as label says, instead of jumping to it.
+func (m *model) pickCommit(label string, pick func(m *model, index int) tea.Cmd) {
+	m.openFinder()
+	m.finder.pick, m.finder.pickLabel = pick, label
+}
+
+// refilter matches the commits against the query, best first; the ones
+// scoring alike keep their order in the graph.
+func (m *model) refilter() {
+	f := m.finder
+	f.matches, f.cursor, f.version = nil, 0, m.dataVersion
+	terms := strings.Fields(f.input.Value())
+	for i, c := range m.commits {
+		line := finderLine(c)
+		match := finderMatch{index: i, positions: map[int]bool{}}
+		ok := true
+		for _, term := range terms {
+			score, positions, found := fuzzyMatch(term, line)
+			if !found {
+				ok = false
+				break
+			}
+			match.score += score
+			for _, p := range positions {
+				match.positions[p] = true
+			}
+		}
+		if ok {
+			f.matches = append(f.matches, match)
+		}
+	}
+	sort.SliceStable(f.matches, func(a, b int) bool { return f.matches[a].score > f.matches[b].score })
+}
+
+// fuzzyMatch finds the runes of pattern in text, in order, and scores
+// how well they match: runes next to each other and at the start of a
+// word count more, gaps less. Like fzf it takes the first place the
+// pattern ends, then the shortest match ending there. Case is ignored
+// unless the pattern has capitals.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	p, t := []rune(pattern), []rune(text)
+	fold := func(r rune) rune { return r }
+	if strings.ToLower(pattern) == pattern {
+		fold = unicode.ToLower
+	}
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	end, j := -1, 0
+	for i := 0; i < len(t); i++ {
+		if fold(t[i]) == p[j] {
+			j++
+			if j == len(p) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	positions := make([]int, len(p))
+	j = len(p) - 1
+	for i := end; i >= 0 && j >= 0; i-- {
+		if fold(t[i]) == p[j] {
+			positions[j] = i
+			j--
+		}
+	}
+
+	score := 0
+	for k, i := range positions {
+		score += 16
+		if i == 0 || !unicode.IsLetter(t[i-1]) && !unicode.IsDigit(t[i-1]) {
+			score += 8
+		}
+		if k > 0 {
+			if gap := i - positions[k-1] - 1; gap == 0 {
+				score += 12
+			} else {
+				score -= min(gap, 10)
+			}
+		}
+	}
+	return score, positions, true
+}
+
+// updateFinder handles a key while the finder is open: enter jumps to
+// the chosen commit, ↑/↓ choose, anything else edits the query.
+func (m *model) updateFinder(msg tea.KeyMsg) tea.Cmd {
+	f := m.finder
+	if f.version != m.dataVersion {
+		// Reloaded since; the indexes are stale
+		m.refilter()
+	}
+	switch msg.String() {
+	case "esc", "ctrl+c", "ctrl+f":
+		m.finder = nil
+		return nil
+	case "enter":
+		m.finder = nil
+		if len(f.matches) == 0 {
+			return nil
+		}
+		if f.pick != nil {
+			return f.pick(m, f.matches[f.cursor].index)
+		}
+		return m.jumpTo(f.matches[f.cursor].index)
+	case "up", "ctrl+p", "ctrl+k":
+		f.cursor = max(f.cursor-1, 0)
+		return nil
+	case "down", "ctrl+n", "ctrl+j":
+		f.cursor = min(f.cursor+1, max(len(f.matches)-1, 0))
+		return nil
+	}
+	query := f.input.Value()
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	if f.input.Value() != query {
+		m.refilter()
+	}
+	return cmd
+}
+
+// renderFinder lists the matches around the chosen one, the matched
+// runes highlighted.
+func (m *model) renderFinder(width, height int) string {
+	f := m.finder
+	action := "jump"
+	if f.pick != nil {
+		action = f.pickLabel
+	}
+	lines := []string{helpStyle.Render(fmt.Sprintf("%d/%d commits  ↑/↓: choose  enter: %s  esc: close", len(f.matches), len(m.commits), action)), ""}
+	rows := max(height-len(lines), 1)
+	first := max(min(f.cursor-rows/2, len(f.matches)-rows), 0)
+	for i := first; i < len(f.matches) && i < first+rows; i++ {
+		match := f.matches[i]
+		if match.index >= len(m.commits) {
+			continue
+		}
+		c := m.commits[match.index]
+		marker := "  "
+		if i == f.cursor {
+			marker = "> "
+		}
+		subjectWidth := max(width-2-len(c.Hash)-1-lipgloss.Width(c.Author)-2, 10)
+		subject := truncate(c.Message, subjectWidth)
+		// Offsets of the fields in finderLine, in runes
+		subjectAt := len([]rune(c.Hash)) + 1
+		authorAt := subjectAt + len([]rune(c.Message)) + 1
+		lines = append(lines, marker+
+			highlightRunes(c.Hash, 0, match.positions, commitHashStyle)+" "+
+			highlightRunes(subject, subjectAt, match.positions, lipgloss.NewStyle())+"  "+
+			highlightRunes(c.Author, authorAt, match.positions, authorStyle))
+	}
+	if len(f.matches) == 0 {
+		lines = append(lines, helpStyle.Render("No commit matches."))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightRunes renders s in style, and the runes whose offset plus at
+// is in positions in finderMatchStyle.
+func highlightRunes(s string, at int, positions map[int]bool, style lipgloss.Style) string {
+	var sb, run strings.Builder
+	highlighted := false
+	flush := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if highlighted {
+			sb.WriteString(finderMatchStyle.Render(run.String()))
+		} else {
+			sb.WriteString(style.Render(run.String()))
+		}
+		run.Reset()
+	}
+	for i, r := range []rune(s) {
+		if positions[at+i] != highlighted {
+			flush()
+			highlighted = !highlighted
+		}
+		run.WriteRune(r)
+	}
+	flush()
+	return sb.String()
+}
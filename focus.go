@@ -0,0 +1,44 @@
+package main
+
+// focus moves the focus to a panel, remembering the one it leaves for
+// the last-panel toggle.
+func (m *model) focus(box int) {
+	if box != m.focusedBox {
+		m.lastFocus = m.focusedBox
+		m.focusedBox = box
+	}
+}
+
+// detailsPos is where a commit's details were left: the scroll of each
+// tab and the Files tab cursor.
+type detailsPos struct {
+	scroll      [numDetailTabs]int
+	filesCursor int
+}
+
+// savePosition remembers the details position of the selected commit,
+// for when it is selected again.
+func (m *model) savePosition() {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return
+	}
+	hash := m.commits[m.selected].FullHash.String()
+	pos := detailsPos{scroll: m.detailsScroll, filesCursor: m.filesCursor}
+	if pos == (detailsPos{}) {
+		delete(m.positions, hash)
+	} else {
+		m.positions[hash] = pos
+	}
+}
+
+// restorePosition picks up the details position the selected commit was
+// left at, so flipping between two commits to compare their diffs keeps
+// each one's place for the session.
+func (m *model) restorePosition() {
+	var pos detailsPos
+	if m.selected >= 0 && m.selected < len(m.commits) {
+		pos = m.positions[m.commits[m.selected].FullHash.String()]
+	}
+	m.detailsScroll, m.filesCursor = pos.scroll, pos.filesCursor
+	m.link = 0
+}
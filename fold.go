@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultCollapseLines is the size over which a file's diff starts out
+// collapsed, see gitraffe.collapseLines.
+const defaultCollapseLines = 200
+
+// foldKey identifies a file of a commit's diff in model.folds.
+func foldKey(c *commit, path string) string {
+	return c.FullHash.String() + "\x00" + path
+}
+
+// collapsed reports whether a file's diff of lines lines is folded away:
+// as it was toggled, or else when it is over gitraffe.collapseLines.
+func (m *model) collapsed(c *commit, path string, lines int) bool {
+	if folded, ok := m.folds[foldKey(c, path)]; ok {
+		return folded
+	}
+	return m.cfg.CollapseLines > 0 && lines > m.cfg.CollapseLines
+}
+
+// visibleDiff is the diff as the Diff tab shows it: each collapsed file
+// cut down to its header and a line saying how much is hidden.
+func (m *model) visibleDiff(c *commit) string {
+	lines := strings.Split(c.DiffBody, "\n")
+	outline := outlineDiff(c.DiffBody)
+	if len(outline.files) == 0 {
+		return c.DiffBody
+	}
+
+	visible := append([]string(nil), lines[:outline.files[0]]...)
+	for i, start := range outline.files {
+		end := start + 1 + outline.sizes[i]
+		if !m.collapsed(c, outline.paths[i], outline.sizes[i]) {
+			visible = append(visible, lines[start:end]...)
+			continue
+		}
+		visible = append(visible, lines[start],
+			helpStyle.Render(fmt.Sprintf("  ⋯ %d lines collapsed (enter: expand)", outline.sizes[i])))
+	}
+	return strings.Join(visible, "\n")
+}
+
+// toggleFold collapses or expands the file at the top of the Diff tab,
+// keeping its header in place.
+func (m *model) toggleFold() {
+	c := &m.commits[m.selected]
+	start := m.diffStart(c)
+	if start < 0 {
+		return
+	}
+	outline := outlineDiff(m.visibleDiff(c))
+	if len(outline.files) == 0 {
+		return
+	}
+	file := 0
+	for i, line := range outline.files {
+		if line+start <= m.detailsScroll[tabDiff] {
+			file = i
+		}
+	}
+
+	// The file's size comes from the full diff, not the folded one
+	size := outlineDiff(c.DiffBody).sizes[file]
+	path := outline.paths[file]
+	m.folds[foldKey(c, path)] = !m.collapsed(c, path, size)
+	m.detailsScroll[tabDiff] = outline.files[file] + start
+	m.dataVersion++
+}
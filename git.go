@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gitCommand is a git command to run in the repository, to set input on
+// or add to the environment of before running it with cmdOutput.
+func gitCommand(repoPath string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	if noReplaceObjects.Load() {
+		cmd.Env = append(os.Environ(), "GIT_NO_REPLACE_OBJECTS=1")
+	}
+	return cmd
+}
+
+// gitRead runs a git command that only reads the repository, and returns
+// its output without the final newline.
+func gitRead(repoPath string, args ...string) (string, error) {
+	return cmdOutput(gitCommand(repoPath, args...))
+}
+
+// gitRun runs a git command that changes the repository, and returns what
+// it wrote to stdout and stderr, trimmed, to show as the outcome.
+func gitRun(repoPath string, args ...string) (string, error) {
+	log.Printf("Running: git %s\n", strings.Join(args, " "))
+	out, err := gitCommand(repoPath, args...).CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// cmdOutput runs a git command for its output, without the final
+// newline. When git says why it failed, that is the error.
+func cmdOutput(cmd *exec.Cmd) (string, error) {
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		err = &gitError{ExitError: exitErr, stderr: strings.TrimSpace(string(exitErr.Stderr))}
+	}
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+// gitError is a git command that failed, told by what it wrote to stderr.
+// It is still an *exec.ExitError to errors.As, for the exit code.
+type gitError struct {
+	*exec.ExitError
+	stderr string
+}
+
+func (e *gitError) Error() string { return e.stderr }
+
+func (e *gitError) Unwrap() error { return e.ExitError }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// GIT_DIR and GIT_WORK_TREE, or --git-dir and --work-tree, point gitraffe
+// at a repository laid out some other way than a .git directory in the
+// work tree: a bare repository, a dotfiles repository whose work tree is
+// $HOME, and the like. The git CLI takes them from the environment, so
+// every command gitraffe runs honors them; go-git is pointed at the git
+// dir instead of the path.
+
+// applyGitDir sets GIT_DIR and GIT_WORK_TREE from the flags, and makes
+// them absolute: git commands run in the repository path, where a path
+// relative to where gitraffe was started would point elsewhere. With a
+// work tree and no path, the work tree is the repository path.
+func applyGitDir(opts *options) error {
+	for _, v := range []struct{ name, flag string }{
+		{"GIT_DIR", opts.gitDir},
+		{"GIT_WORK_TREE", opts.workTree},
+	} {
+		path := v.flag
+		if path == "" {
+			path = os.Getenv(v.name)
+		}
+		if path == "" {
+			continue
+		}
+		abs, err := filepath.Abs(normalizeRepoPath(path))
+		if err != nil {
+			return err
+		}
+		if err := os.Setenv(v.name, abs); err != nil {
+			return err
+		}
+	}
+	if wt := os.Getenv("GIT_WORK_TREE"); wt != "" && opts.repoPath == "" {
+		opts.repoPath = wt
+	}
+	return nil
+}
+
+// goGitPath is the path go-git opens the repository at: the git dir when
+// one is set, which go-git opens like a bare repository, as it has no
+// use for the work tree.
+func goGitPath(repoPath string) string {
+	if dir := os.Getenv("GIT_DIR"); dir != "" {
+		return dir
+	}
+	return repoPath
+}
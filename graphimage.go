@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sizes of the graph image, in pixels, for a 14px monospace font.
+const (
+	svgFontSize   = 14
+	svgCharWidth  = 8.4 // monospace advance at 0.6em
+	svgLineHeight = 20
+	svgPadding    = 16
+)
+
+// svgConverters turn an SVG into a PNG, tried in order: the command and
+// its arguments, reading the SVG on stdin and writing the PNG to stdout.
+var svgConverters = [][]string{
+	{"rsvg-convert", "--format=png"},
+	{"magick", "svg:-", "png:-"},
+	{"convert", "svg:-", "png:-"},
+}
+
+// runExport implements `gitraffe export`: the graph with its refs and
+// subjects, drawn as in the commit list, written as an image for
+// architecture docs and release announcements.
+func runExport(args []string) error {
+	// The UI logs to gitraffe.log; here the log would go to stderr
+	log.SetOutput(io.Discard)
+
+	var opts options
+	var format, output string
+	var limit int
+	fs := flag.NewFlagSet("gitraffe export", flag.ContinueOnError)
+	fs.StringVar(&format, "format", "svg", "image `format`: svg, or png (converted with rsvg-convert or ImageMagick)")
+	fs.StringVar(&opts.revRange, "range", "", "only draw the commits in `revspec`, e.g. v1.2.0..v1.3.0")
+	fs.StringVar(&opts.ref, "ref", "", "draw the history of `ref` instead of all refs")
+	fs.StringVar(&output, "o", "", "write the image to `file` instead of stdout")
+	fs.IntVar(&limit, "n", 200, "draw at most `n` commits")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gitraffe export [flags] [path]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		exitUsage(err)
+	}
+	opts.repoPath = "."
+	if fs.NArg() > 0 {
+		opts.repoPath = normalizeRepoPath(fs.Arg(0))
+	}
+	if format != "svg" && format != "png" {
+		return fmt.Errorf("unknown format %q, use svg or png", format)
+	}
+
+	m := initialModel(opts)
+	m.remotes = loadRemotes(m.repoPath) // tells branch tips from remote ones
+	if err := m.loadGraphData(); err != nil {
+		return err
+	}
+	if len(m.commits) == 0 {
+		return fmt.Errorf("no commits to draw")
+	}
+
+	var image bytes.Buffer
+	m.writeGraphSVG(&image, limit)
+	if format == "png" {
+		png, err := convertSVG(image.Bytes())
+		if err != nil {
+			return err
+		}
+		image.Reset()
+		image.Write(png)
+	}
+
+	if output == "" && format == "svg" {
+		w, done := startPager(opts.repoPath)
+		defer done()
+		_, err := w.Write(image.Bytes())
+		return err
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(image.Bytes())
+		return err
+	}
+	return os.WriteFile(output, image.Bytes(), 0644)
+}
+
+// writeGraphSVG draws the display rows of the first limit commits as
+// lines of monospace text, in the colors of the commit list.
+func (m *model) writeGraphSVG(w io.Writer, limit int) {
+	rows := m.displayRows
+	for i, row := range rows {
+		if row.CommitIdx >= limit {
+			rows = rows[:i]
+			break
+		}
+	}
+
+	type span struct{ text, color string }
+	var lines [][]span
+	columns := 0
+	for _, row := range rows {
+		var line []span
+		switch {
+		case row.Separator:
+			line = []span{{"  " + strings.Repeat("┄", m.maxGraphWidth+1+m.hashWidth), "#626262"}}
+		case row.CommitIdx < 0:
+			for _, s := range m.graphSpans(row, row.GraphChars) {
+				line = append(line, span(s))
+			}
+		default:
+			c := m.commits[row.CommitIdx]
+			graph := row.GraphChars + strings.Repeat(" ", m.maxGraphWidth-row.GraphWidth)
+			for _, s := range m.graphSpans(row, graph) {
+				line = append(line, span(s))
+			}
+			line = append(line, span{c.Hash, "#FFA500"})
+			if c.Refs != "" {
+				refs, _ := shortRefs(c.Refs, rowRefsWidth)
+				line = append(line, span{" (" + refs + ")", "#88C0D0"})
+			}
+			line = append(line, span{" " + c.Message, "#E5E9F0"})
+		}
+		width := 0
+		for _, s := range line {
+			width += utf8.RuneCountInString(s.text)
+		}
+		columns = max(columns, width)
+		lines = append(lines, line)
+	}
+
+	width := int(float64(columns)*svgCharWidth) + 2*svgPadding
+	height := len(lines)*svgLineHeight + 2*svgPadding
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(w, "<rect width=\"100%%\" height=\"100%%\" fill=\"#2E3440\"/>\n")
+	fmt.Fprintf(w, "<g font-family=\"ui-monospace, Menlo, Consolas, 'DejaVu Sans Mono', monospace\" font-size=\"%d\" xml:space=\"preserve\">\n", svgFontSize)
+	for i, line := range lines {
+		y := svgPadding + i*svgLineHeight + svgFontSize
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">", svgPadding, y)
+		for _, s := range line {
+			fmt.Fprintf(w, "<tspan fill=\"%s\">%s</tspan>", s.color, html.EscapeString(s.text))
+		}
+		fmt.Fprintln(w, "</text>")
+	}
+	fmt.Fprintln(w, "</g>\n</svg>")
+}
+
+// convertSVG renders an SVG to PNG with the first converter installed.
+func convertSVG(svg []byte) ([]byte, error) {
+	for _, conv := range svgConverters {
+		if _, err := exec.LookPath(conv[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(conv[0], conv[1:]...)
+		cmd.Stdin = bytes.NewReader(svg)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %v %s", conv[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("PNG export needs rsvg-convert or ImageMagick; --format svg works without")
+}
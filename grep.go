@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// / greps the files of the selected commit with git grep, whatever is
+// checked out, and lists the matching lines; enter opens one in the file
+// viewer. A pattern in lowercase matches either case, as vim's
+// smartcase.
+
+// grepLimit is how many matching lines a grep lists at most.
+const grepLimit = 2000
+
+type grepMatch struct {
+	path string
+	line int
+	text string
+}
+
+// grepView is the result of a grep, with a cursor on a match.
+type grepView struct {
+	pattern    string
+	rev, short string
+	matches    []grepMatch
+	cut        bool // more matched than grepLimit
+	cursor     int
+}
+
+type grepMsg struct {
+	view *grepView
+	err  error
+}
+
+// ignoreCase is smartcase: a pattern without capitals matches either
+// case.
+func ignoreCase(pattern string) bool {
+	return strings.ToLower(pattern) == pattern
+}
+
+// loadGrep runs git grep for a pattern in the tree of a commit.
+func loadGrep(repoPath, rev, short, pattern string) tea.Cmd {
+	return func() tea.Msg {
+		args := []string{"grep", "-z", "-n", "-I", "--full-name", "--no-color"}
+		if ignoreCase(pattern) {
+			args = append(args, "-i")
+		}
+		out, err := gitRead(repoPath, append(args, "-e", pattern, rev, "--")...)
+		v := &grepView{pattern: pattern, rev: rev, short: short}
+		// Exit status 1 with nothing said is no match
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(exitErr.Stderr) == 0 {
+			return grepMsg{view: v}
+		}
+		if err != nil {
+			return grepMsg{err: err}
+		}
+		v.matches, v.cut = parseGrep(out, rev)
+		return grepMsg{view: v}
+	}
+}
+
+// parseGrep reads git grep -z -n output for a commit: rev:path, the
+// line number and the line, NUL between them. It keeps grepLimit
+// matches, telling whether there were more.
+func parseGrep(out, rev string) (matches []grepMatch, cut bool) {
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if len(matches) == grepLimit {
+			return matches, true
+		}
+		n, _ := strconv.Atoi(fields[1])
+		path := strings.TrimPrefix(fields[0], rev+":")
+		matches = append(matches, grepMatch{path: path, line: n, text: fields[2]})
+	}
+	return matches, false
+}
+
+// grepSelected asks for a pattern and greps the selected commit.
+func (m *model) grepSelected() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	m.prompt = newPrompt("Grep "+c.Hash, "pattern, a regex; lowercase for any case", func(m *model, value string) tea.Cmd {
+		if value == "" {
+			return nil
+		}
+		m.status = "Grepping " + c.Hash + " for " + value + "…"
+		return loadGrep(m.repoPath, c.FullHash.String(), c.Hash, value)
+	})
+	if m.grep != nil && m.grep.rev == c.FullHash.String() {
+		m.prompt.input.SetValue(m.grep.pattern)
+		m.prompt.input.CursorEnd()
+	}
+	return nil
+}
+
+// updateGrep handles a key while the grep results are open: enter opens
+// the match in the file viewer, / greps again.
+func (m *model) updateGrep(msg tea.KeyMsg) tea.Cmd {
+	v := m.grep
+	page := max(m.windowHeight/2, 1)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.grep = nil
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = max(min(v.cursor+1, len(v.matches)-1), 0)
+	case "u", "ctrl+u", "pgup":
+		v.cursor = max(v.cursor-page, 0)
+	case "d", "ctrl+d", "pgdown":
+		v.cursor = max(min(v.cursor+page, len(v.matches)-1), 0)
+	case "g", "home":
+		v.cursor = 0
+	case "G", "end":
+		v.cursor = max(len(v.matches)-1, 0)
+	case "/":
+		return m.grepSelected()
+	case "enter":
+		if len(v.matches) == 0 {
+			return nil
+		}
+		match := v.matches[v.cursor]
+		return m.openFile(v.rev, match.path, match.line)
+	}
+	return nil
+}
+
+// renderGrep lists the matches around the cursor, the matched text
+// highlighted.
+func (m *model) renderGrep(width, height int) string {
+	v := m.grep
+	count := fmt.Sprintf("%d matching lines", len(v.matches))
+	switch {
+	case len(v.matches) == 1:
+		count = "1 matching line"
+	case v.cut:
+		count = fmt.Sprintf("the first %d matching lines", grepLimit)
+	}
+	lines := []string{
+		sectionHeader("Grep "+v.pattern) + " " + commitHashStyle.Render(v.short),
+		"",
+		helpStyle.Render(count + "  ↑/↓: choose  enter: open the file  /: grep again  esc: close"),
+		"",
+	}
+	if len(v.matches) == 0 {
+		return strings.Join(append(lines, helpStyle.Render("Nothing matches.")), "\n")
+	}
+
+	// Go's regexps take most of what git grep's basic ones do; where they
+	// differ, the lines go without highlights
+	expr := v.pattern
+	if ignoreCase(expr) {
+		expr = "(?i)" + expr
+	}
+	re, _ := regexp.Compile(expr)
+
+	rows := max(height-len(lines), 1)
+	first := max(min(v.cursor-rows/2, len(v.matches)-rows), 0)
+	for i := first; i < len(v.matches) && i < first+rows; i++ {
+		match := v.matches[i]
+		marker := "  "
+		if i == v.cursor {
+			marker = "> "
+		}
+		where := match.path + helpStyle.Render(fmt.Sprintf(":%d", match.line)) + " "
+		text := truncate(strings.TrimSpace(strings.ReplaceAll(match.text, "\t", " ")), max(width-2-lipgloss.Width(where), 10))
+		positions := map[int]bool{}
+		if re != nil {
+			for _, loc := range re.FindAllStringIndex(text, -1) {
+				for at := len([]rune(text[:loc[0]])); at < len([]rune(text[:loc[1]])); at++ {
+					positions[at] = true
+				}
+			}
+		}
+		lines = append(lines, marker+where+highlightRunes(text, 0, positions, lipgloss.NewStyle()))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGrep(t *testing.T) {
+	rev := "0123456789abcdef0123456789abcdef01234567"
+	out := rev + ":main.go\x0012\x00\tfoo := bar\n" +
+		rev + ":dir/a b.txt\x003\x00a: b\x00c\n"
+	matches, cut := parseGrep(out, rev)
+	want := []grepMatch{
+		{path: "main.go", line: 12, text: "\tfoo := bar"},
+		{path: "dir/a b.txt", line: 3, text: "a: b\x00c"},
+	}
+	if cut || !reflect.DeepEqual(matches, want) {
+		t.Errorf("parseGrep = %q, %v; want %q, false", matches, cut, want)
+	}
+	if matches, _ := parseGrep("", rev); len(matches) != 0 {
+		t.Errorf("parseGrep of nothing = %q", matches)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The key bindings, settings and range syntax are described once here.
+// The ? overlay, `gitraffe help <topic>` and the man page are all
+// generated from these tables.
+
+type keyHelp struct {
+	keys string
+	desc string
+}
+
+type keyGroup struct {
+	title string
+	keys  []keyHelp
+}
+
+var keyGroups = []keyGroup{
+	{"Everywhere", []keyHelp{
+		{"0/1/2", "focus the repo info, list or details panel"},
+		{"`", "switch back to the panel focused before"},
+		{"z", "zoom the focused panel to the full window"},
+		{"w", "toggle the working tree view"},
+		{"p", "pull the current branch, choosing rebase, merge or fast-forward only"},
+		{"P", "push the current branch, setting an upstream or forcing with lease"},
+		{"L", "list the largest files in the history"},
+		{"A", "activity: commits per day over the last year as a heat strip, of everyone or of one author"},
+		{"n", "write a note on the selected commit"},
+		{"I", "show or hide the impact column: lines added and deleted by each commit"},
+		{"H", "show or hide the minimap: the whole history in a column, the part on screen lit; click it to jump"},
+		{"D", "mark commits whose change is in the graph more than once, as after cherry-picks (≡)"},
+		{"X", "export the selected commit and its diff to a Markdown or HTML file"},
+		{"O", "open a pull request (gh) or merge request (glab) for the selected commit's branch"},
+		{"b", "local branches with their upstreams, ahead/behind and gone ones; delete the merged, clean up those in the base, or restore one from its reflog"},
+		{"f", "apply a filter preset from gitraffe.filter.<name>, or clear it; h there dims the other commits instead of hiding them"},
+		{"T", "releases: each version tag with its commits since the one before; enter shows them, x every commit again, / only tags matching a glob"},
+		{"/", "grep the selected commit's files; enter opens a match in the file viewer, where c compares the file with another commit"},
+		{"t", "tree of the selected commit, with each entry's last change; enter opens a directory or file, backspace goes up, c compares a file with another commit"},
+		{"e", "edit the selected commit of the checked-out branch: r rewords its message, a changes its author; the commits after it are rebased"},
+		{"!", "run a git or shell command in the repository and scroll through its output; the graph is reloaded after"},
+		{"B", "stacked branches: each local branch against the base, rebase onto it or create a PR"},
+		{"R", "ignore replace refs and show the original history, or honor them again"},
+		{"?", "show these keys"},
+		{"esc", "leave the working tree view, or quit"},
+		{"q, ctrl+c", "quit"},
+	}},
+	{"Commit list", []keyHelp{
+		{"j/k, ↓/↑", "select the next/previous commit"},
+		{"d/u", "move half a page down/up"},
+		{"g/G", "jump to the first/last commit"},
+		{"]/[", "jump to the next/previous merge"},
+		{"3j, 15G, 2]", "a count before a motion repeats it; before G, selects the n-th commit"},
+		{"ctrl+f", "fuzzy find a commit by hash, subject or author, and jump to it"},
+		{"ctrl+o/ctrl+n", "go back/forward through the jump list: where g/G, ]/[, V and ctrl+f jumped from (ctrl+i is tab in a terminal)"},
+		{"v", "mark the commit reviewed, or not"},
+		{"V", "jump to the next unreviewed commit"},
+		{"M", "mark the commit, or unmark it; with two marked, their merge-base is marked ⊥"},
+		{"'", "jump to the merge-base of the two marked commits"},
+		{"=", "jump to the next copy of the selected commit, once D marks them"},
+	}},
+	{"Details", []keyHelp{
+		{"tab, shift+tab", "switch between the Commit, Diff, Files and Refs tabs"},
+		{"alt+1…alt+4", "go to the Commit, Diff, Files or Refs tab"},
+		{"j/k, d/u, g", "scroll"},
+		{"c", "on the Refs tab, list every containing branch and tag"},
+		{"←/→, h/l", "on the Commit and Refs tabs, focus the previous/next link: a tag, parent, containing ref, issue or URL"},
+		{"enter", "on the Commit and Refs tabs, follow the focused link: jump to its commit (ctrl+o comes back) or open it in the browser"},
+		{"m", "for a merge, diff against each parent in turn"},
+		{"{/}, (/)", "on the Diff tab, jump to the previous/next file or hunk"},
+		{"enter", "on the Diff tab, collapse or expand the file at the top"},
+		{"B", "on the Diff tab, blame the hunk at the top as it was before the commit: who last changed each line it removes or keeps, passing over .git-blame-ignore-revs (I: show them)"},
+		{"v", "mark the commit reviewed, or on the Files tab the file under the cursor (j/k)"},
+	}},
+	{"Working tree", []keyHelp{
+		{"j/k, g/G", "select a file"},
+		{"space", "stage or unstage the file, or in the diff the selected hunk"},
+		{"(/)", "in the diff, select the previous/next hunk"},
+		{"x", "mark the file"},
+		{"S", "stash everything, the staged changes or the marked files"},
+		{"c", "write a commit"},
+	}},
+	{"Commit screen", []keyHelp{
+		{"ctrl+s", "commit"},
+		{"alt+a", "toggle --amend"},
+		{"alt+s", "toggle --signoff"},
+		{"esc", "close, keeping the message for next time"},
+	}},
+}
+
+type configHelp struct {
+	key  string
+	def  string
+	desc string
+}
+
+var configKeys = []configHelp{
+	{"gitraffe.relativeDates", "false", "Show relative dates (\"3 hours ago\") next to commit dates."},
+	{"gitraffe.ref", "", "Ref to start the graph at, like --ref, which takes precedence."},
+	{"gitraffe.exclude", "", "Ref pattern to hide from the graph, like --exclude. Can be set several times."},
+	{"gitraffe.fetchInterval", "0", "Minutes between background fetches into refs/prefetch; 0 turns them off."},
+	{"gitraffe.protectedBranch", "", "Branch pattern, like main or release/*, that isn't deleted with the merged branches, and is only force-pushed or restored once its name is typed. Can be set several times."},
+	{"gitraffe.shell", "sh", "Shell that runs the commands of !, given -c and the command line."},
+	{"gitraffe.base", "", "Ref to compare local branches with, like --base; each branch tip shows its commits above it."},
+	{"gitraffe.backend", "auto", "What loads the history and diffs, like --backend: auto, cli or go-git."},
+	{"gitraffe.issueURL", "", "Page of an issue, %s standing for its number, for #123 in messages to link to; by default the issues of the origin remote on GitHub, GitLab and alike."},
+	{"gitraffe.hyperlinks", "auto", "Make hashes, tags, origin's branches and issue numbers ctrl+clickable links (OSC 8) to their pages on the origin remote's site; on by default in terminals known to support them."},
+	{"gitraffe.timeZone", "local", "Time zone commit dates are shown in: local, author (the offset each date was recorded with) or utc. Outside the author's zone their own time is shown too."},
+	{"gitraffe.commitSymbol", "●", "Node of an ordinary commit in the graph; also mergeSymbol (●), octopusSymbol (✱), rootSymbol (○) and selectedSymbol (◉). Symbols should be one column wide."},
+	{"gitraffe.tagSymbol", "", "Node of tagged commits, like ⚑; branchSymbol likewise marks local branch tips."},
+	{"gitraffe.lineSymbols", "│─╮", "Characters for git's graph lines | - and ., or five to also replace / and \\."},
+	{"gitraffe.graphColors", "lanes", "Colors of the graph: lanes, a color per lane kept through crossings; colorblind, the same in a palette told apart with color blindness; or single, all orange."},
+	{"gitraffe.impact", "false", "Show the impact column, the lines added and deleted by each commit, from the start."},
+	{"gitraffe.minimap", "false", "Show the minimap, the whole history in a column left of the list, from the start."},
+	{"gitraffe.collapseLines", "200", "Files whose diff is longer than this many lines start out collapsed in the Diff tab; 0 never collapses."},
+	{"gitraffe.memoryBudget", "256", "MB of diffs kept in memory; past it, those selected longest ago are loaded again when needed. 0 keeps them all."},
+	{"gitraffe.notesRef", "", "Keep notes on commits as git notes on this ref (e.g. refs/notes/gitraffe) instead of in .git/gitraffe/notes."},
+	{"gitraffe.commitTemplate", "", "Extra commit message template, offered next to commit.template. Can be set several times."},
+	{"gitraffe.conventionalCommits", "false", "Pick a conventional commit type and scope before writing a message."},
+	{"gitraffe.commitType", strings.Join(defaultCommitTypes, ", "), "Conventional commit type to offer. Can be set several times."},
+	{"gitraffe.filter.<name>", "", "Filter preset applied with f, e.g. author:me since:1.month, or grep:hotfix path:src/. Terms: author, committer, since, until, grep, path."},
+	{"gitraffe.commitScope", "", "Conventional commit scope to offer. Can be set several times; without any, the scope is typed in."},
+}
+
+const rangesHelp = `A range limits the graph to some of the history. It is given as a
+positional argument or with --range, and is anything git log accepts:
+
+  v1.2.0..HEAD      commits since v1.2.0
+  main...feature    commits on either side but not both
+  --since=2.weeks   (with --range) recent commits only
+
+--ref starts the graph at one ref instead of all of them; --ref HEAD
+shows just the current branch. --exclude (and gitraffe.exclude) hide
+refs matching a pattern from the all-refs graph, using git's --exclude
+glob syntax: refs/tags/nightly-*, refs/remotes/origin/dependabot/*.
+
+The go-git backend shows the same history, but takes only ranges of
+the form A..B.`
+
+var helpTopics = []struct {
+	name string
+	desc string
+	show func(w io.Writer)
+}{
+	{"keys", "key bindings", writeKeysHelp},
+	{"config", "git config settings", writeConfigHelp},
+	{"ranges", "ranges, --ref and --exclude patterns", func(w io.Writer) { fmt.Fprintln(w, rangesHelp) }},
+	{"man", "the man page, in roff", writeManPage},
+}
+
+// runHelp implements `gitraffe help [topic]`.
+func runHelp(args []string) {
+	if len(args) > 0 {
+		for _, t := range helpTopics {
+			if t.name == args[0] {
+				w, done := startPager(".")
+				t.show(w)
+				done()
+				return
+			}
+		}
+		fmt.Fprintf(os.Stderr, "gitraffe: no help topic %q\n\n", args[0])
+	}
+	w := os.Stdout
+	if len(args) > 0 {
+		w = os.Stderr
+	}
+	fmt.Fprintln(w, "Usage: gitraffe help <topic>\n\nTopics:")
+	for _, t := range helpTopics {
+		fmt.Fprintf(w, "  %-8s %s\n", t.name, t.desc)
+	}
+	if len(args) > 0 {
+		os.Exit(2)
+	}
+}
+
+func writeKeysHelp(w io.Writer) {
+	for i, g := range keyGroups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", g.title)
+		for _, k := range g.keys {
+			fmt.Fprintf(w, "  %-16s %s\n", k.keys, k.desc)
+		}
+	}
+}
+
+func writeConfigHelp(w io.Writer) {
+	fmt.Fprintln(w, "Settings are read from git config, globally or per repository:")
+	fmt.Fprintln(w, "\n  git config --global gitraffe.relativeDates true")
+	for _, c := range configKeys {
+		fmt.Fprintf(w, "\n%s", c.key)
+		if c.def != "" {
+			fmt.Fprintf(w, " (default %s)", c.def)
+		}
+		fmt.Fprintf(w, "\n  %s\n", c.desc)
+	}
+	fmt.Fprintln(w, "\nRefs and diffs take git's own colors where set: color.decorate.<slot>")
+	fmt.Fprintln(w, "(HEAD, branch, remoteBranch, tag, stash) and color.diff.<slot> (meta,")
+	fmt.Fprintln(w, "frag, func, old, new, context).")
+}
+
+// roff escapes text for a man page.
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+func writeManPage(w io.Writer) {
+	fmt.Fprintln(w, ".TH GITRAFFE 1")
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintln(w, `gitraffe \- terminal git graph viewer`)
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintln(w, `.B gitraffe`)
+	fmt.Fprintln(w, `[\fIflags\fR] [\fIpath\fR] [\fIrange\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B gitraffe help`)
+	fmt.Fprintln(w, `\fItopic\fR`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B gitraffe large\-files`)
+	fmt.Fprintln(w, `[\fIpath\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B gitraffe demo`)
+	fmt.Fprintln(w, `[\fIpath\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B gitraffe render`)
+	fmt.Fprintln(w, `[\fB\-width\fR \fIn\fR] [\fB\-height\fR \fIn\fR] [\fB\-frame\fR \fIkey\fR=\fIvalue\fR] [\fIpath\fR]`)
+	fmt.Fprintln(w, ".br")
+	fmt.Fprintln(w, `.B gitraffe export`)
+	fmt.Fprintln(w, `[\fB\-format\fR svg|png] [\fB\-range\fR \fIrevspec\fR] [\fB\-o\fR \fIfile\fR] [\fIpath\fR]`)
+
+	fmt.Fprintln(w, ".SH OPTIONS")
+	newFlagSet(&options{}).VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".BI \\-%s \" %s\"\n", roff(f.Name), roff(name))
+		fmt.Fprintln(w, roff(usage))
+	})
+
+	fmt.Fprintln(w, ".SH KEYS")
+	for _, g := range keyGroups {
+		fmt.Fprintf(w, ".SS %s\n", roff(g.title))
+		for _, k := range g.keys {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".B %s\n", roff(k.keys))
+			fmt.Fprintln(w, roff(k.desc))
+		}
+	}
+
+	fmt.Fprintln(w, ".SH CONFIGURATION")
+	fmt.Fprintln(w, "Settings are read from the gitraffe section of git config.")
+	for _, c := range configKeys {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %s\n", roff(c.key))
+		desc := c.desc
+		if c.def != "" {
+			desc += " Default: " + c.def + "."
+		}
+		fmt.Fprintln(w, roff(desc))
+	}
+
+	fmt.Fprintln(w, ".SH RANGES")
+	fmt.Fprintln(w, ".nf")
+	for _, line := range strings.Split(rangesHelp, "\n") {
+		fmt.Fprintln(w, roff(line))
+	}
+	fmt.Fprintln(w, ".fi")
+}
+
+// keysOverlay renders the key bindings for the ? overlay.
+func keysOverlay() string {
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	var lines []string
+	for _, g := range keyGroups {
+		lines = append(lines, sectionHeader(g.title))
+		for _, k := range g.keys {
+			lines = append(lines, fmt.Sprintf("  %s %s", keyStyle.Render(fmt.Sprintf("%-16s", k.keys)), k.desc))
+		}
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The file viewer colors comments, strings, numbers and keywords. It
+// knows languages by the file's extension, or name, and nothing of their
+// grammar beyond that: enough to read code by, in the languages git
+// repositories mostly hold, without a lexer library for each.
+
+type tokenKind int
+
+const (
+	tokenPlain tokenKind = iota
+	tokenComment
+	tokenString
+	tokenNumber
+	tokenKeyword
+)
+
+var tokenStyles = map[tokenKind]lipgloss.Style{
+	tokenPlain:   lipgloss.NewStyle(),
+	tokenComment: lipgloss.NewStyle().Foreground(lipgloss.Color("#616E88")).Italic(true),
+	tokenString:  lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C")),
+	tokenNumber:  lipgloss.NewStyle().Foreground(lipgloss.Color("#B48EAD")),
+	tokenKeyword: lipgloss.NewStyle().Foreground(lipgloss.Color("#81A1C1")).Bold(true),
+}
+
+// token is a run of a line's text of one kind.
+type token struct {
+	text string
+	kind tokenKind
+}
+
+// syntax is what the highlighter knows of a language.
+type syntax struct {
+	lineComments []string
+	blocks       [][3]string // open, close, and "c" for a comment or "s" for a string, across lines
+	quotes       string      // the quotes of strings within a line
+	keywords     map[string]bool
+	anyCase      bool // keywords match in either case, as SQL's
+}
+
+func keywords(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	cBlock   = [3]string{"/*", "*/", "c"}
+	cKeyword = "auto break case char const continue default do double else enum extern float for goto if inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while bool true false NULL nullptr class namespace template typename public private protected virtual override new delete this using try catch throw"
+
+	goSyntax = &syntax{lineComments: []string{"//"}, blocks: [][3]string{cBlock, {"`", "`", "s"}}, quotes: `"'`,
+		keywords: keywords("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota")}
+	cSyntax    = &syntax{lineComments: []string{"//"}, blocks: [][3]string{cBlock}, quotes: `"'`, keywords: keywords(cKeyword)}
+	javaSyntax = &syntax{lineComments: []string{"//"}, blocks: [][3]string{cBlock}, quotes: `"'`,
+		keywords: keywords("abstract assert boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long native new package private protected public return short static super switch synchronized this throw throws try void volatile while true false null var val fun object when override data sealed internal open lateinit companion")}
+	jsSyntax = &syntax{lineComments: []string{"//"}, blocks: [][3]string{cBlock, {"`", "`", "s"}}, quotes: `"'`,
+		keywords: keywords("async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while yield true false null undefined interface type enum implements private public protected readonly as")}
+	rustSyntax = &syntax{lineComments: []string{"//"}, blocks: [][3]string{cBlock}, quotes: `"`,
+		keywords: keywords("as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while")}
+	pySyntax = &syntax{lineComments: []string{"#"}, blocks: [][3]string{{`"""`, `"""`, "s"}, {"'''", "'''", "s"}}, quotes: `"'`,
+		keywords: keywords("and as assert async await break class continue def del elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return True try while with yield")}
+	rubySyntax = &syntax{lineComments: []string{"#"}, quotes: `"'`,
+		keywords: keywords("alias and begin break case class def defined? do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require attr_reader attr_accessor")}
+	shellSyntax = &syntax{lineComments: []string{"#"}, quotes: `"'`,
+		keywords: keywords("if then else elif fi case esac for while until do done in function return local export readonly set unset shift exit")}
+	hashSyntax = &syntax{lineComments: []string{"#"}, quotes: `"`, keywords: keywords("true false null yes no on off")}
+	sqlSyntax  = &syntax{lineComments: []string{"--"}, blocks: [][3]string{cBlock}, quotes: `'"`,
+		keywords: keywords("select from where and or not insert into values update set delete create table index view drop alter add primary key foreign references join left right inner outer on group by order having limit as null is in distinct union all case when then else end"), anyCase: true}
+	luaSyntax = &syntax{lineComments: []string{"--"}, quotes: `"'`,
+		keywords: keywords("and break do else elseif end false for function goto if in local nil not or repeat return then true until while")}
+	markupSyntax = &syntax{blocks: [][3]string{{"<!--", "-->", "c"}}, quotes: `"`}
+	proseSyntax  = &syntax{blocks: [][3]string{{"<!--", "-->", "c"}, {"```", "```", "s"}}}
+	cssSyntax    = &syntax{blocks: [][3]string{cBlock}, quotes: `"'`}
+)
+
+var syntaxByExtension = map[string]*syntax{
+	".go": goSyntax,
+	".c":  cSyntax, ".h": cSyntax, ".cc": cSyntax, ".cpp": cSyntax, ".cxx": cSyntax, ".hpp": cSyntax, ".m": cSyntax, ".cs": cSyntax, ".swift": cSyntax, ".proto": cSyntax,
+	".java": javaSyntax, ".kt": javaSyntax, ".kts": javaSyntax, ".scala": javaSyntax, ".groovy": javaSyntax, ".gradle": javaSyntax, ".dart": javaSyntax,
+	".js": jsSyntax, ".jsx": jsSyntax, ".mjs": jsSyntax, ".cjs": jsSyntax, ".ts": jsSyntax, ".tsx": jsSyntax, ".json": jsSyntax, ".php": jsSyntax,
+	".rs": rustSyntax,
+	".py": pySyntax, ".pyi": pySyntax,
+	".rb": rubySyntax, ".rake": rubySyntax, ".gemspec": rubySyntax,
+	".sh": shellSyntax, ".bash": shellSyntax, ".zsh": shellSyntax, ".fish": shellSyntax,
+	".yaml": hashSyntax, ".yml": hashSyntax, ".toml": hashSyntax, ".ini": hashSyntax, ".conf": hashSyntax, ".cfg": hashSyntax, ".mk": hashSyntax, ".cmake": hashSyntax, ".pl": hashSyntax, ".r": hashSyntax, ".tf": hashSyntax, ".nix": hashSyntax,
+	".sql": sqlSyntax, ".lua": luaSyntax, ".hs": luaSyntax,
+	".html": markupSyntax, ".htm": markupSyntax, ".xml": markupSyntax, ".svg": markupSyntax, ".md": proseSyntax, ".vue": markupSyntax,
+	".css": cssSyntax, ".scss": cssSyntax, ".less": cssSyntax,
+}
+
+var syntaxByName = map[string]*syntax{
+	"Makefile": hashSyntax, "makefile": hashSyntax, "GNUmakefile": hashSyntax, "Dockerfile": hashSyntax, "Containerfile": hashSyntax,
+	"CMakeLists.txt": hashSyntax, "Rakefile": rubySyntax, "Gemfile": rubySyntax, "Jenkinsfile": javaSyntax,
+	".gitignore": hashSyntax, ".gitattributes": hashSyntax, ".gitmodules": hashSyntax, ".editorconfig": hashSyntax, "go.mod": goSyntax,
+}
+
+// syntaxFor is the syntax of a file, nil for one the highlighter doesn't
+// know.
+func syntaxFor(file string) *syntax {
+	name := path.Base(file)
+	if s, ok := syntaxByName[name]; ok {
+		return s
+	}
+	return syntaxByExtension[strings.ToLower(path.Ext(name))]
+}
+
+// highlight splits each line into tokens, carrying comments and strings
+// that span lines over to the next. Without a syntax, each line is one
+// plain token.
+func (s *syntax) highlight(lines []string) [][]token {
+	out := make([][]token, len(lines))
+	if s == nil {
+		for i, line := range lines {
+			out[i] = []token{{text: line}}
+		}
+		return out
+	}
+	var open *[3]string // the block the line starts in
+	for i, line := range lines {
+		out[i], open = s.highlightLine(line, open)
+	}
+	return out
+}
+
+// highlightLine tokenizes a line that starts in block open, or none, and
+// returns the block it ends in.
+func (s *syntax) highlightLine(line string, open *[3]string) ([]token, *[3]string) {
+	var tokens []token
+	add := func(text string, kind tokenKind) {
+		if text == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].kind == kind {
+			tokens[n-1].text += text
+			return
+		}
+		tokens = append(tokens, token{text, kind})
+	}
+	blockKind := func(b *[3]string) tokenKind {
+		if b[2] == "c" {
+			return tokenComment
+		}
+		return tokenString
+	}
+
+	pos := 0
+	if open != nil {
+		end := strings.Index(line, open[1])
+		if end < 0 {
+			add(line, blockKind(open))
+			return tokens, open
+		}
+		add(line[:end+len(open[1])], blockKind(open))
+		pos = end + len(open[1])
+		open = nil
+	}
+
+scan:
+	for pos < len(line) {
+		rest := line[pos:]
+		for _, prefix := range s.lineComments {
+			if strings.HasPrefix(rest, prefix) {
+				add(rest, tokenComment)
+				break scan
+			}
+		}
+		for i := range s.blocks {
+			b := &s.blocks[i]
+			if !strings.HasPrefix(rest, b[0]) {
+				continue
+			}
+			end := strings.Index(rest[len(b[0]):], b[1])
+			if end < 0 {
+				add(rest, blockKind(b))
+				return tokens, b
+			}
+			n := len(b[0]) + end + len(b[1])
+			add(rest[:n], blockKind(b))
+			pos += n
+			continue scan
+		}
+
+		c := rest[0]
+		switch {
+		case strings.IndexByte(s.quotes, c) >= 0:
+			n := 1
+			for n < len(rest) && rest[n] != c {
+				if rest[n] == '\\' {
+					n++
+				}
+				n++
+			}
+			n = min(n+1, len(rest))
+			add(rest[:n], tokenString)
+			pos += n
+		case isWordByte(c):
+			n := 1
+			for n < len(rest) && (isWordByte(rest[n]) || rest[n] == '.' && c >= '0' && c <= '9') {
+				n++
+			}
+			word := rest[:n]
+			switch {
+			case c >= '0' && c <= '9':
+				add(word, tokenNumber)
+			case (s.keywords[word] || s.anyCase && s.keywords[strings.ToLower(word)]) && (pos == 0 || line[pos-1] != '.'):
+				add(word, tokenKeyword)
+			default:
+				add(word, tokenPlain)
+			}
+			pos += n
+		default:
+			add(rest[:1], tokenPlain)
+			pos++
+		}
+	}
+	return tokens, open
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// renderTokens renders a line's tokens cut to width, ending in "…" when
+// cut, each in its style over base, and pads it with base to width.
+func renderTokens(tokens []token, width int, base lipgloss.Style) string {
+	total := 0
+	for _, t := range tokens {
+		total += lipgloss.Width(t.text)
+	}
+	left := width
+	if total > width {
+		left = width - 1
+	}
+	var sb strings.Builder
+	for _, t := range tokens {
+		text := t.text
+		if w := lipgloss.Width(text); w > left {
+			var cut strings.Builder
+			for _, r := range text {
+				if lipgloss.Width(cut.String()+string(r)) > left {
+					break
+				}
+				cut.WriteRune(r)
+			}
+			text = cut.String()
+		}
+		sb.WriteString(tokenStyles[t.kind].Inherit(base).Render(text))
+		if left -= lipgloss.Width(text); left <= 0 && total > width {
+			break
+		}
+	}
+	if total > width {
+		sb.WriteString(base.Render("…"))
+	}
+	used := min(total, width)
+	return sb.String() + base.Render(strings.Repeat(" ", max(width-used, 0)))
+}
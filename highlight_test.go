@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	lines := []string{
+		`if x := "a\"b"; x != 0x1F { // done`,
+		"/* spans",
+		"lines */ return `raw",
+		"string` + y.if",
+	}
+	want := [][]token{
+		{{"if", tokenKeyword}, {" x := ", tokenPlain}, {`"a\"b"`, tokenString}, {"; x != ", tokenPlain}, {"0x1F", tokenNumber}, {" { ", tokenPlain}, {"// done", tokenComment}},
+		{{"/* spans", tokenComment}},
+		{{"lines */", tokenComment}, {" ", tokenPlain}, {"return", tokenKeyword}, {" ", tokenPlain}, {"`raw", tokenString}},
+		{{"string`", tokenString}, {" + y.if", tokenPlain}},
+	}
+	if got := syntaxFor("dir/main.go").highlight(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("highlight =\n%v\nwant\n%v", got, want)
+	}
+	if got := syntaxFor("notes.unknown").highlight([]string{"if // x"}); !reflect.DeepEqual(got, [][]token{{{"if // x", tokenPlain}}}) {
+		t.Errorf("highlight without a syntax = %v", got)
+	}
+}
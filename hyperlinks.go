@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Terminals that know OSC 8 make text a hyperlink, opened with a
+// ctrl+click (cmd+click on macOS). Commit hashes then link to the commit
+// on the origin remote's site, tags and origin's branches to theirs, and
+// issue numbers to the issue. Terminals that don't would print the
+// escapes, so they are only written to those known to take them, or with
+// gitraffe.hyperlinks set.
+
+// hyperlinksSupported tells from the environment whether the terminal
+// takes OSC 8 hyperlinks.
+func hyperlinksSupported() bool {
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby", "rio":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("WEZTERM_EXECUTABLE") != "" {
+		return true
+	}
+	if strings.HasPrefix(os.Getenv("TERM"), "xterm-kitty") || os.Getenv("TERM") == "foot" {
+		return true
+	}
+	// GNOME Terminal and the other VTE ones, since 0.50
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	return false
+}
+
+// hyperlink makes text a link to url where hyperlinks are on, and leaves
+// it as is otherwise or without a url.
+func (m *model) hyperlink(url, text string) string {
+	if !m.cfg.Hyperlinks || url == "" || text == "" {
+		return text
+	}
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// webPage is the page of path on the origin remote's site, with GitLab's
+// /-/ before it; empty when the origin isn't on one.
+func (m *model) webPage(path string) string {
+	web := m.cfg.WebURL
+	switch {
+	case web == "":
+		return ""
+	case strings.Contains(web, "gitlab"):
+		return web + "/-/" + path
+	}
+	return web + "/" + path
+}
+
+// commitURL is the page of a commit on the origin remote's site.
+func (m *model) commitURL(c commit) string {
+	return m.webPage("commit/" + c.FullHash.String())
+}
+
+// refURL is the page of a ref as decorations name it: of a tag, or a
+// branch of the origin remote. Local branches may not be on the remote,
+// nor other remotes' branches on the origin, so they have none.
+func (m *model) refURL(ref string) string {
+	ref = strings.TrimPrefix(ref, "HEAD -> ")
+	if tag, ok := strings.CutPrefix(ref, "tag: "); ok {
+		return m.webPage("tree/" + tag)
+	}
+	if branch, ok := strings.CutPrefix(ref, "origin/"); ok && branch != "HEAD" {
+		return m.webPage("tree/" + branch)
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// impactWidth is the width of the impact column, " +1.2k -96".
+const impactWidth = 12
+
+// impact is the size of a commit: lines added and deleted, over all its
+// files.
+type impact struct {
+	added, deleted int
+}
+
+// impactMsg brings the counts of one chunk, or those read from the
+// cache when cached is set.
+type impactMsg struct {
+	impact map[string]impact
+	cached bool
+	err    error
+}
+
+// loadImpactCache reads the counts of earlier runs.
+func loadImpactCache(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		stats := make(map[string]impact)
+		for hash, value := range impactCache.load(repoPath) {
+			var im impact
+			if n, _ := fmt.Sscanf(value, "%d %d", &im.added, &im.deleted); n == 2 {
+				stats[hash] = im
+			}
+		}
+		return impactMsg{impact: stats, cached: true}
+	}
+}
+
+// countImpact sums the lines changed by a chunk of commits, in one
+// numstat pass. Merges have no diff of their own here and count as
+// nothing.
+func countImpact(repoPath string, hashes []string) tea.Msg {
+	cmd := gitCommand(repoPath, "log", "--no-walk=unsorted", "--stdin", "--numstat", "--format=%x00%H", "--no-renames")
+	cmd.Stdin = strings.NewReader(strings.Join(hashes, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return impactMsg{err: err}
+	}
+
+	stats := make(map[string]impact)
+	var hash string
+	var cur impact
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if h, ok := strings.CutPrefix(line, "\x00"); ok {
+			if hash != "" {
+				stats[hash] = cur
+			}
+			hash, cur = h, impact{}
+			continue
+		}
+		// "<added>\t<deleted>\t<path>", "-" for binary files
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		cur.added += added
+		cur.deleted += deleted
+	}
+	if hash != "" {
+		stats[hash] = cur
+	}
+	values := make(map[string]string, len(stats))
+	for h, im := range stats {
+		values[h] = fmt.Sprintf("%d %d", im.added, im.deleted)
+	}
+	if err := impactCache.save(repoPath, values); err != nil {
+		log.Printf("Not caching changed lines: %v\n", err)
+	}
+	return impactMsg{impact: stats}
+}
+
+// maybeLoadImpact starts counting when the impact column is shown and
+// some commit in the graph isn't counted yet: first reading the cache,
+// then the rest in chunks, on a few workers. What was counted is kept
+// across reloads; commits don't change.
+func (m *model) maybeLoadImpact() tea.Cmd {
+	if !m.showImpact || m.impactPending > 0 {
+		return nil
+	}
+	if !m.impactCached {
+		m.impactPending = 1
+		return loadImpactCache(m.repoPath)
+	}
+	var missing []string
+	for _, c := range m.commits {
+		if _, ok := m.impact[c.FullHash.String()]; !ok {
+			missing = append(missing, c.FullHash.String())
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	m.impactTotal, m.impactDone = len(m.commits), len(m.commits)-len(missing)
+	repoPath := m.repoPath
+	cmd, chunks := inChunks(missing, func(hashes []string) tea.Msg {
+		return countImpact(repoPath, hashes)
+	})
+	m.impactPending = chunks
+	return cmd
+}
+
+// handleImpact takes in the counts of a chunk, or of the cache.
+func (m *model) handleImpact(msg impactMsg) tea.Cmd {
+	m.impactPending--
+	if msg.err != nil {
+		m.status, m.statusErr = "Counting changed lines failed: "+msg.err.Error(), true
+	}
+	for hash, im := range msg.impact {
+		m.impact[hash] = im
+	}
+	m.impactDone += len(msg.impact)
+	m.dataVersion++
+	if msg.cached {
+		m.impactCached = true
+		return m.maybeLoadImpact()
+	}
+	return nil
+}
+
+// impactProgress is a bar of the commits counted so far, while counting.
+func (m *model) impactProgress() string {
+	if m.impactPending == 0 || m.impactTotal == 0 {
+		return ""
+	}
+	const width = 10
+	done := min(max(m.impactDone, 0), m.impactTotal)
+	filled := done * width / m.impactTotal
+	return fmt.Sprintf("counting lines %s%s %d/%d",
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled), done, m.impactTotal)
+}
+
+// impactLabel is the impact column of a commit's row, blank while its
+// size isn't known yet.
+func (m *model) impactLabel(c commit) string {
+	if !m.showImpact {
+		return ""
+	}
+	im, ok := m.impact[c.FullHash.String()]
+	if !ok || im.added+im.deleted == 0 {
+		return strings.Repeat(" ", impactWidth)
+	}
+	return fmt.Sprintf(" %s %s",
+		statAddStyle.Render(fmt.Sprintf("%5s", "+"+compactCount(im.added))),
+		statDelStyle.Render(fmt.Sprintf("%-5s", "-"+compactCount(im.deleted))))
+}
+
+// compactCount shortens large counts to fit the column: 950, 1.2k, 48k.
+func compactCount(n int) string {
+	switch {
+	case n < 1000:
+		return strconv.Itoa(n)
+	case n < 10000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	case n < 1000000:
+		return fmt.Sprintf("%dk", n/1000)
+	default:
+		return fmt.Sprintf("%dM", n/1000000)
+	}
+}
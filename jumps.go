@@ -0,0 +1,59 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxJumps is how far back the jump list goes.
+const maxJumps = 100
+
+// jumpList remembers where the selection jumped from, like an editor's
+// jump list: g/G, ]/[ and other jumps are recorded, j/k are not. Commits
+// are kept by full hash so the list survives reloads.
+type jumpList struct {
+	back    []string
+	forward []string
+}
+
+// jumpTo selects commit i, recording the commit it leaves in the jump
+// list.
+func (m *model) jumpTo(i int) tea.Cmd {
+	i = max(min(i, len(m.commits)-1), 0)
+	if i != m.selected && m.selected >= 0 && m.selected < len(m.commits) {
+		m.jumps.back = append(m.jumps.back, m.commits[m.selected].FullHash.String())
+		if len(m.jumps.back) > maxJumps {
+			m.jumps.back = m.jumps.back[1:]
+		}
+		m.jumps.forward = nil
+	}
+	return m.selectCommit(i)
+}
+
+// jumpBack goes back to where the last jump came from (ctrl+o), or with
+// forward, undoes that (ctrl+n, as the ctrl+i of vim arrives as tab,
+// which moves the focus). Commits that are gone since, after a rebase
+// say, are skipped.
+func (m *model) jumpBack(forward bool) tea.Cmd {
+	from, to := &m.jumps.back, &m.jumps.forward
+	if forward {
+		from, to = to, from
+	}
+	for len(*from) > 0 {
+		hash := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		i := m.commitIndex(hash)
+		if i < 0 || i == m.selected {
+			continue
+		}
+		if m.selected >= 0 && m.selected < len(m.commits) {
+			*to = append(*to, m.commits[m.selected].FullHash.String())
+		}
+		return m.selectCommit(i)
+	}
+	if forward {
+		m.status = "No newer jumps"
+	} else {
+		m.status = "No older jumps"
+	}
+	return nil
+}
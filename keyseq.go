@@ -0,0 +1,91 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// countPrefix is a vim-style count typed before a motion, as in "15j" or
+// "3]". 1 and 2 also focus their panels, so they switch at once as
+// always; when a motion follows, the focus goes back to the panel the
+// count was typed in.
+type countPrefix struct {
+	n     int
+	focus int // focused panel before the first digit
+	last  int // and the one before that
+}
+
+// countMotions are the keys a count applies to.
+var countMotions = map[string]bool{
+	"j": true, "down": true, "k": true, "up": true,
+	"d": true, "ctrl+d": true, "u": true, "ctrl+u": true,
+	"G": true, "end": true, "]": true, "[": true,
+}
+
+// parseCount feeds a key to the count prefix. It reports whether the key
+// was used up as a digit of the count, and otherwise the count that
+// applies to the key, 0 for none.
+func (m *model) parseCount(msg tea.KeyMsg) (count int, consumed bool) {
+	key := msg.String()
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+		digit := int(key[0] - '0')
+		if m.count.n == 0 {
+			if digit == 0 {
+				// Not a count, the repo info panel
+				return 0, false
+			}
+			m.count = countPrefix{n: digit, focus: m.focusedBox, last: m.lastFocus}
+			// 1 and 2 focus their panels too, except where presentation
+			// mode keeps the focus on the graph
+			return 0, m.present || digit > 2
+		}
+		m.count.n = min(m.count.n*10+digit, 99999)
+		return 0, true
+	}
+
+	if m.count.n == 0 {
+		return 0, false
+	}
+	c := m.count
+	m.count = countPrefix{}
+	switch {
+	case key == "esc":
+		// Cancels the count, like in vim
+		return 0, true
+	case countMotions[key]:
+		m.focusedBox, m.lastFocus = c.focus, c.last
+		return c.n, false
+	}
+	return 0, false
+}
+
+// selectCommit moves the selection to commit i, kept in range.
+func (m *model) selectCommit(i int) tea.Cmd {
+	i = max(min(i, len(m.commits)-1), 0)
+	if i != m.selected {
+		m.savePosition()
+		m.selected = i
+		m.restorePosition()
+	}
+	return m.maybeLoadDetails()
+}
+
+// nextMerge selects the n-th merge commit down the graph, or up it for
+// negative n, stopping at the last one there is.
+func (m *model) nextMerge(n int) tea.Cmd {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	target := m.selected
+	for i := m.selected + step; i >= 0 && i < len(m.commits) && n > 0; i += step {
+		if len(m.commits[i].Parents) > 1 {
+			target = i
+			n--
+		}
+	}
+	if target == m.selected {
+		m.status = "No more merges that way"
+		return nil
+	}
+	return m.jumpTo(target)
+}
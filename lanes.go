@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Each lane of the graph is drawn in a color of its own, which it keeps
+// as it moves between columns, so a branch can be followed through
+// crossings. The colors are git's: git log --graph --color hands a color
+// from log.graphColors to each new lane, and new ones to the parents of
+// a merge, and keeps them with the lanes. gitraffe sets that to the
+// numbers of its own palette's colors and reads them back off the lines.
+
+// lanePalettes are the palettes gitraffe.graphColors picks from. The
+// first color is the trunk's, orange as the graph used to be all over.
+var lanePalettes = map[string][]string{
+	"lanes": {"#FFA500", "#88C0D0", "#A3BE8C", "#B48EAD", "#EBCB8B", "#BF616A", "#5E81AC", "#D08770"},
+	// Okabe and Ito's palette, told apart with every kind of color
+	// blindness; grey in place of its black
+	"colorblind": {"#E69F00", "#56B4E9", "#009E73", "#F0E442", "#0072B2", "#D55E00", "#CC79A7", "#999999"},
+	// The graph in one color, as before lanes had their own
+	"single": nil,
+}
+
+// parseGraphColors reads gitraffe.graphColors.
+func parseGraphColors(value string) ([]string, error) {
+	palette, ok := lanePalettes[strings.ToLower(value)]
+	if !ok {
+		return nil, fmt.Errorf("%q is not lanes, colorblind or single", value)
+	}
+	return palette, nil
+}
+
+// graphColorArgs make git log color each lane with the number of its
+// color in the palette, none for a single color.
+func graphColorArgs(palette []string) []string {
+	if len(palette) == 0 {
+		return nil
+	}
+	numbers := make([]string, len(palette))
+	for i := range palette {
+		numbers[i] = strconv.Itoa(i)
+	}
+	return []string{"-c", "log.graphColors=" + strings.Join(numbers, ","), "-c", "color.ui=always"}
+}
+
+// splitGraphColors takes git's color codes out of the graph part of a
+// line, and returns the lane color of each character left: the number
+// graphColorArgs gave it, or -1 where git left a character uncolored,
+// as it does the commit's *.
+func splitGraphColors(s string) (string, []int8) {
+	var sb strings.Builder
+	var colors []int8
+	color := int8(-1)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+			end := strings.IndexByte(s[i:], 'm')
+			if end < 0 {
+				break
+			}
+			color = sgrColor(s[i+2 : i+end])
+			i += end
+			continue
+		}
+		sb.WriteByte(s[i])
+		colors = append(colors, color)
+	}
+	return sb.String(), colors
+}
+
+// sgrColor reads the foreground color of a color code's parameters:
+// 3N for colors 0 to 7, 38;5;N for the rest; anything else, a reset
+// included, is no color.
+func sgrColor(params string) int8 {
+	fields := strings.Split(params, ";")
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i]; {
+		case f == "38" && i+2 < len(fields) && fields[i+1] == "5":
+			if n, err := strconv.Atoi(fields[i+2]); err == nil && n < 128 {
+				return int8(n)
+			}
+			return -1
+		case len(f) == 2 && f[0] == '3' && f[1] >= '0' && f[1] <= '7':
+			return int8(f[1] - '0')
+		}
+	}
+	return -1
+}
+
+// colorNodes gives each commit's node the color of its lane, which git
+// leaves out: that of the line coming into it from above, straight or
+// slanting in from the next column, or else of the line going on below.
+func colorNodes(rows []displayRow) {
+	laneNear := func(row displayRow, col int) int8 {
+		for _, c := range []int{col, col - 1, col + 1} {
+			if c >= 0 && c < len(row.Colors) && row.Colors[c] >= 0 {
+				return row.Colors[c]
+			}
+		}
+		return -1
+	}
+	for i := range rows {
+		row := &rows[i]
+		if row.CommitIdx < 0 || row.NodeAt < 0 || row.Colors == nil {
+			continue
+		}
+		col := utf8.RuneCountInString(row.GraphChars[:row.NodeAt])
+		if col >= len(row.Colors) {
+			continue
+		}
+		color := int8(-1)
+		if i > 0 {
+			color = laneNear(rows[i-1], col)
+		}
+		if color < 0 && i+1 < len(rows) {
+			color = laneNear(rows[i+1], col)
+		}
+		row.Colors[col] = max(color, 0)
+	}
+}
+
+// graphSpan is a run of graph characters of one color.
+type graphSpan struct {
+	text, color string
+}
+
+// graphSpans splits a row's graph, as drawn, into runs of a color each:
+// the lane colors, or all orange with a single color. Each character of
+// git's graph is drawn as one symbol.
+func (m *model) graphSpans(row displayRow, graph string) []graphSpan {
+	const single = "#FFA500"
+	palette := m.cfg.LanePalette
+	if len(palette) == 0 || row.Colors == nil {
+		return []graphSpan{{graph, single}}
+	}
+	var spans []graphSpan
+	var run strings.Builder
+	runColor := ""
+	col := 0
+	for _, r := range graph {
+		color := single
+		if col < len(row.Colors) && row.Colors[col] >= 0 {
+			color = palette[int(row.Colors[col])%len(palette)]
+		}
+		if color != runColor && run.Len() > 0 {
+			spans = append(spans, graphSpan{run.String(), runColor})
+			run.Reset()
+		}
+		run.WriteRune(r)
+		runColor = color
+		col++
+	}
+	if run.Len() > 0 {
+		spans = append(spans, graphSpan{run.String(), runColor})
+	}
+	return spans
+}
+
+// renderGraph draws a row's graph in its lane colors.
+func (m *model) renderGraph(row displayRow, graph string) string {
+	var sb strings.Builder
+	for _, s := range m.graphSpans(row, graph) {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(s.color)).Render(s.text))
+	}
+	return sb.String()
+}
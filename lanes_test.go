@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitGraphColors(t *testing.T) {
+	tests := []struct {
+		in     string
+		plain  string
+		colors []int8
+	}{
+		{"* ", "* ", []int8{-1, -1}},
+		{"\x1b[30m|\x1b[m * ", "| * ", []int8{0, -1, -1, -1}},
+		{"\x1b[32m|\x1b[m\x1b[33m\\\x1b[m \x1b[31m\\\x1b[m  ", "|\\ \\  ", []int8{2, 3, -1, 1, -1, -1}},
+		{"\x1b[1;38;5;12m|\x1b[m", "|", []int8{12}},
+	}
+	for _, tt := range tests {
+		plain, colors := splitGraphColors(tt.in)
+		if plain != tt.plain || !reflect.DeepEqual(colors, tt.colors) {
+			t.Errorf("splitGraphColors(%q) = %q, %v; want %q, %v", tt.in, plain, colors, tt.plain, tt.colors)
+		}
+	}
+}
+
+func TestColorNodes(t *testing.T) {
+	// A merge, and the branch it merged coming in from the next column:
+	//   *
+	//   |\
+	//   | *
+	//   * |
+	rows := []displayRow{
+		{GraphChars: "● ", NodeAt: 0, CommitIdx: 0, Colors: []int8{-1, -1}},
+		{GraphChars: "│╲", CommitIdx: -1, Colors: []int8{0, 1}},
+		{GraphChars: "│ ● ", NodeAt: len("│ "), CommitIdx: 1, Colors: []int8{0, -1, -1, -1}},
+		{GraphChars: "● │ ", NodeAt: 0, CommitIdx: 2, Colors: []int8{-1, -1, 1, -1}},
+	}
+	colorNodes(rows)
+	got := []int8{rows[0].Colors[0], rows[2].Colors[2], rows[3].Colors[0]}
+	if want := []int8{0, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("node colors %v, want %v", got, want)
+	}
+}
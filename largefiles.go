@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// largeBlob is one of the biggest files anywhere in the history.
+type largeBlob struct {
+	sha      string
+	size     int64 // uncompressed
+	diskSize int64 // in the object store, after compression and deltas
+	path     string
+	inHead   bool   // still part of the current tree
+	commit   string // commit that added it: short hash, date and subject
+}
+
+// largeBlobCount is how many blobs the large file report lists.
+const largeBlobCount = 25
+
+// findLargeBlobs lists the n largest blobs reachable from any ref, like
+// git-sizer or git filter-repo --analyze would, with the path each was
+// seen at and the commit that added it.
+func findLargeBlobs(repoPath string, n int) ([]largeBlob, error) {
+	// rev-list names every object with a path it appears at; cat-file
+	// sizes them in one batch
+	revList := gitCommand(repoPath, "rev-list", "--objects", "--all")
+	catFile := gitCommand(repoPath, "cat-file", "--batch-check=%(objecttype) %(objectname) %(objectsize) %(objectsize:disk) %(rest)")
+	pipe, err := revList.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	catFile.Stdin = pipe
+	out, err := catFile.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := revList.Start(); err != nil {
+		return nil, err
+	}
+	if err := catFile.Start(); err != nil {
+		revList.Process.Kill()
+		revList.Wait()
+		return nil, err
+	}
+
+	var blobs []largeBlob
+	scanner := bufio.NewScanner(out)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 5)
+		if len(fields) < 5 || fields[0] != "blob" {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[2], 10, 64)
+		disk, _ := strconv.ParseInt(fields[3], 10, 64)
+		blobs = append(blobs, largeBlob{sha: fields[1], size: size, diskSize: disk, path: fields[4]})
+	}
+	if err := scanner.Err(); err != nil {
+		// Nothing reads the rest, so the commands are stopped, not waited on
+		catFile.Process.Kill()
+		revList.Process.Kill()
+		catFile.Wait()
+		revList.Wait()
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	if err := revList.Wait(); err != nil {
+		return nil, fmt.Errorf("git rev-list: %w", err)
+	}
+	if err := catFile.Wait(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].size > blobs[j].size })
+	if len(blobs) > n {
+		blobs = blobs[:n]
+	}
+
+	inHead := map[string]bool{}
+	if treeOut, err := gitRead(repoPath, "ls-tree", "-r", "HEAD"); err == nil {
+		for _, line := range strings.Split(treeOut, "\n") {
+			// <mode> SP <type> SP <sha> TAB <path>
+			if fields := strings.Fields(line); len(fields) >= 3 {
+				inHead[fields[2]] = true
+			}
+		}
+	}
+	added := blobsAdded(repoPath, blobs)
+	for i := range blobs {
+		blobs[i].inHead = inHead[blobs[i].sha]
+		blobs[i].commit = added[blobs[i].sha]
+	}
+	return blobs, nil
+}
+
+// blobsAdded finds the commit that added each blob, in one walk of the
+// history looking for all of them: --find-object keeps the commits that
+// add or remove one, and --raw tells which, with the blob after the
+// change. Newest first, so the last commit seen with a blob added it.
+func blobsAdded(repoPath string, blobs []largeBlob) map[string]string {
+	added := make(map[string]string)
+	if len(blobs) == 0 {
+		return added
+	}
+	// --no-abbrev lengthens %h too, so the hash is shortened here
+	args := []string{"log", "--all", "--raw", "--no-abbrev", "--format=%x00%H %ad %s", "--date=short"}
+	for _, b := range blobs {
+		args = append(args, "--find-object="+b.sha)
+	}
+	out, err := gitRead(repoPath, args...)
+	if err != nil {
+		return added
+	}
+	short := abbrevLength(repoPath)
+	var commit string
+	for _, line := range strings.Split(out, "\n") {
+		if rest, ok := strings.CutPrefix(line, "\x00"); ok {
+			hash, rest, _ := strings.Cut(rest, " ")
+			commit = hash[:min(short, len(hash))] + " " + rest
+			continue
+		}
+		// ":<old mode> <new mode> <old sha> <new sha> <status>\t<path>"
+		if fields := strings.Fields(line); len(fields) >= 5 && strings.HasPrefix(line, ":") {
+			added[fields[3]] = commit
+		}
+	}
+	return added
+}
+
+// formatSize formats a byte count the way du -h does.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// writeLargeBlobs writes the report as a table.
+func writeLargeBlobs(w io.Writer, blobs []largeBlob) {
+	if len(blobs) == 0 {
+		fmt.Fprintln(w, "No files in the history.")
+		return
+	}
+	for _, b := range blobs {
+		where := "history only"
+		if b.inHead {
+			where = "in HEAD"
+		}
+		fmt.Fprintf(w, "%9s  %9s packed  %-12s  %s\n", formatSize(b.size), formatSize(b.diskSize), where, b.path)
+		if b.commit != "" {
+			fmt.Fprintf(w, "%36s added in %s\n", "", b.commit)
+		}
+	}
+}
+
+// runLargeFiles implements `gitraffe large-files [path]`.
+func runLargeFiles(args []string) error {
+	repoPath := "."
+	if len(args) > 0 {
+		repoPath = args[0]
+	}
+	blobs, err := findLargeBlobs(repoPath, largeBlobCount)
+	if err != nil {
+		return err
+	}
+	w, done := startPager(repoPath)
+	writeLargeBlobs(w, blobs)
+	done()
+	return nil
+}
+
+type largeBlobsMsg struct {
+	blobs []largeBlob
+	err   error
+}
+
+func loadLargeBlobs(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		blobs, err := findLargeBlobs(repoPath, largeBlobCount)
+		return largeBlobsMsg{blobs: blobs, err: err}
+	}
+}
+
+// largeBlobsMenu shows the report over the panels.
+func largeBlobsMenu(blobs []largeBlob) *menu {
+	var sb strings.Builder
+	sb.WriteString(sectionHeader(fmt.Sprintf("Largest files in the history (top %d)", largeBlobCount)))
+	sb.WriteString("\n\n")
+	writeLargeBlobs(&sb, blobs)
+	sb.WriteString("\nFiles only in the history still take up space in every clone.\n")
+	sb.WriteString("Removing them means rewriting history, e.g. with git filter-repo.")
+	return &menu{title: "Large files", detail: sb.String()}
+}
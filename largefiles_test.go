@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFindLargeBlobs checks the largest blobs are listed biggest first,
+// each with the commit that added it, and whether HEAD still has it.
+func TestFindLargeBlobs(t *testing.T) {
+	d := testRepo(t)
+	d.commit("small.txt", "small\n", "Add a small file")
+	d.commit("big.bin", strings.Repeat("big\n", 1000), "Add a big file")
+	d.commit("big.bin", strings.Repeat("bigger\n", 1000), "Grow the big file")
+	d.commit("small.txt", "still small\n", "Touch the small file")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	short := gitOutput(t, d.dir, "rev-parse", "--short", "HEAD~2")
+	shorter := gitOutput(t, d.dir, "rev-parse", "--short", "HEAD~1")
+
+	blobs, err := findLargeBlobs(d.dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		size   int64
+		inHead bool
+		commit string
+	}{
+		{7000, true, shorter + " 2024-01-08 Grow the big file"},
+		{4000, false, short + " 2024-01-08 Add a big file"},
+	}
+	if len(blobs) != len(want) {
+		t.Fatalf("%d blobs, want %d", len(blobs), len(want))
+	}
+	for i, w := range want {
+		b := blobs[i]
+		if b.size != w.size || b.inHead != w.inHead || b.commit != w.commit || b.path != "big.bin" {
+			t.Errorf("blob %d: %d bytes at %s, in HEAD %v, added in %q; want %d bytes at big.bin, in HEAD %v, added in %q",
+				i, b.size, b.path, b.inHead, b.commit, w.size, w.inHead, w.commit)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// lfsSpec is the first line of every Git LFS pointer file.
+const lfsSpec = "version https://git-lfs.github.com/spec/v1"
+
+// lfsPointer is what a Git LFS pointer file says about the real file.
+type lfsPointer struct {
+	oid  string
+	size int64
+}
+
+// parseLFSPointer reads a pointer file's key/value lines.
+func parseLFSPointer(lines []string) (lfsPointer, bool) {
+	var p lfsPointer
+	found := false
+	for _, line := range lines {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "version":
+			found = line == lfsSpec
+		case "oid":
+			p.oid = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			p.size, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return p, found && p.oid != ""
+}
+
+// describe says what the pointer stands for, e.g.
+// "LFS object (image/png, 4.2 MB, 3f2a9c1e)".
+func (p lfsPointer) describe(file string) string {
+	kind := mime.TypeByExtension(path.Ext(file))
+	if kind == "" {
+		kind = "unknown type"
+	}
+	kind, _, _ = strings.Cut(kind, ";")
+	oid := p.oid
+	if len(oid) > 8 {
+		oid = oid[:8]
+	}
+	return fmt.Sprintf("LFS object (%s, %s, %s)", kind, formatSize(p.size), oid)
+}
+
+// summarizeLFSDiff replaces the text diff of LFS pointer files, which
+// only shows oid and size lines changing, with a line for the old and the
+// new object. Other files' diffs are left as they are.
+func summarizeLFSDiff(diff string) string {
+	if !strings.Contains(diff, lfsSpec) {
+		return diff
+	}
+
+	var out []string
+	var section []string
+	flush := func() {
+		out = append(out, summarizeLFSFile(section)...)
+		section = nil
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") && section != nil {
+			flush()
+		}
+		section = append(section, line)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// summarizeLFSFile does summarizeLFSDiff for one file's part of a diff.
+func summarizeLFSFile(section []string) []string {
+	var header, oldLines, newLines []string
+	file := ""
+	inHunks := false
+	for _, line := range section {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunks = true
+		case !inHunks:
+			header = append(header, line)
+			if name, ok := strings.CutPrefix(line, "+++ b/"); ok {
+				file = name
+			} else if name, ok := strings.CutPrefix(line, "--- a/"); ok && file == "" {
+				file = name
+			}
+		case strings.HasPrefix(line, "-"):
+			oldLines = append(oldLines, line[1:])
+		case strings.HasPrefix(line, "+"):
+			newLines = append(newLines, line[1:])
+		case strings.HasPrefix(line, " "):
+			oldLines = append(oldLines, line[1:])
+			newLines = append(newLines, line[1:])
+		}
+	}
+
+	oldPtr, oldOK := parseLFSPointer(oldLines)
+	newPtr, newOK := parseLFSPointer(newLines)
+	if !oldOK && !newOK {
+		return section
+	}
+	summary := header
+	if oldOK {
+		summary = append(summary, "-"+oldPtr.describe(file))
+	} else {
+		// A file that just moved to LFS: keep what it used to contain
+		for _, line := range oldLines {
+			summary = append(summary, "-"+line)
+		}
+	}
+	if newOK {
+		summary = append(summary, "+"+newPtr.describe(file))
+	}
+	return summary
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Commit and Refs tabs of the details are links into the history:
+// the tag a commit is described from, its parents, the branches and tags
+// that contain it, and the issues and URLs in its message. left and
+// right (or h and l) focus them in turn and enter follows the focused
+// one, jumping to its commit, from where ctrl+o comes back, or opening
+// it in the browser.
+
+// linkKind is what following a link does.
+type linkKind int
+
+const (
+	linkRev linkKind = iota // jump to a commit or ref
+	linkURL                 // open in the browser
+)
+
+type detailLink struct {
+	kind   linkKind
+	target string
+}
+
+// linker collects the links of a tab as it is rendered, in the order
+// they are focused in.
+type linker struct {
+	focus int // the focused link, from 1; 0 when none is
+	links []detailLink
+}
+
+// link renders text in style as the next link: underlined, or reversed
+// when focused.
+func (l *linker) link(d detailLink, text string, style lipgloss.Style) string {
+	l.links = append(l.links, d)
+	if len(l.links) == l.focus {
+		return style.Reverse(true).Render(text)
+	}
+	return style.Underline(true).Render(text)
+}
+
+// messageLinkPattern finds URLs, ending before punctuation that closes a
+// sentence, and issue numbers like #123.
+var messageLinkPattern = regexp.MustCompile(`https?://[^\s<>"]*[^\s<>".,;:!?')\]]|#[0-9]+\b`)
+
+// linkText renders text in style with its URLs and issue numbers as
+// links. Issue numbers are links with an issue tracker to go to.
+func (m *model) linkText(l *linker, text string, style lipgloss.Style) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range messageLinkPattern.FindAllStringIndex(text, -1) {
+		token := text[loc[0]:loc[1]]
+		target := token
+		if token[0] == '#' {
+			// Not in the middle of a word, as in C# or a URL's fragment
+			if loc[0] > 0 && !strings.ContainsRune(" \t\n([", rune(text[loc[0]-1])) {
+				continue
+			}
+			if target = m.issueURL(token[1:]); target == "" {
+				continue
+			}
+		}
+		sb.WriteString(renderLines(style, text[last:loc[0]]))
+		sb.WriteString(m.hyperlink(target, l.link(detailLink{kind: linkURL, target: target}, token, style)))
+		last = loc[1]
+	}
+	sb.WriteString(renderLines(style, text[last:]))
+	return sb.String()
+}
+
+// renderLines renders text in style, empty text as nothing.
+func renderLines(style lipgloss.Style, text string) string {
+	if text == "" {
+		return ""
+	}
+	return style.Render(text)
+}
+
+// issueURL is the page of an issue on the tracker of gitraffe.issueURL,
+// or else of the origin remote's host; empty when there is none.
+func (m *model) issueURL(number string) string {
+	if m.cfg.IssueURL == "" {
+		return ""
+	}
+	return strings.ReplaceAll(m.cfg.IssueURL, "%s", number)
+}
+
+// remoteIssueURL is the issue URL of a remote, "%s" standing for the
+// number: GitHub's, or GitLab's, whose pages are under /-/. Remotes on
+// other hosts are taken to be like GitHub, as Gitea and Forgejo are.
+func remoteIssueURL(remote string) string {
+	web := webURL(remote)
+	switch {
+	case web == "":
+		return ""
+	case strings.Contains(web, "gitlab"):
+		return web + "/-/issues/%s"
+	}
+	return web + "/issues/%s"
+}
+
+// webURL is the https address of a remote's repository page, from its
+// URL in any of git's forms; empty for local paths.
+func webURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if !strings.Contains(remote, "://") {
+		// scp-like: [user@]host:path
+		host, path, ok := strings.Cut(remote, ":")
+		if !ok || strings.ContainsAny(host, "/\\") || len(host) == 1 {
+			return ""
+		}
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	switch u.Scheme {
+	case "http", "https", "ssh", "git":
+	default:
+		return ""
+	}
+	return "https://" + u.Hostname() + u.Path
+}
+
+// tabLinks are the links of the selected commit's details tab.
+func (m *model) tabLinks() []detailLink {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := &m.commits[m.selected]
+	var l *linker
+	switch m.detailTab {
+	case tabCommit:
+		_, l = m.renderCommitTab(c, m.windowWidth)
+	case tabRefs:
+		_, l = m.renderRefsTab(c)
+	default:
+		return nil
+	}
+	return l.links
+}
+
+// updateLinks handles the keys that focus and follow the links of the
+// Commit and Refs tabs.
+func (m *model) updateLinks(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if m.detailTab != tabCommit && m.detailTab != tabRefs {
+		return nil, false
+	}
+	switch msg.String() {
+	case "right", "l", "left", "h":
+		links := m.tabLinks()
+		if len(links) == 0 {
+			m.status = "No links here"
+			return nil, true
+		}
+		switch {
+		case msg.String() == "right" || msg.String() == "l":
+			m.link = m.link%len(links) + 1
+		case m.link <= 1 || m.link > len(links):
+			m.link = len(links)
+		default:
+			m.link--
+		}
+		return nil, true
+	case "enter":
+		links := m.tabLinks()
+		if m.link < 1 || m.link > len(links) {
+			m.status = "Focus a link with ← and → first"
+			return nil, true
+		}
+		switch d := links[m.link-1]; d.kind {
+		case linkURL:
+			m.status = "Opening " + d.target
+			return openURL(d.target), true
+		default:
+			return resolveLink(m.repoPath, d.target), true
+		}
+	}
+	return nil, false
+}
+
+type linkTargetMsg struct {
+	rev, hash string
+	err       error
+}
+
+// resolveLink finds the commit a followed ref or hash is at.
+func resolveLink(repoPath, rev string) tea.Cmd {
+	return func() tea.Msg {
+		hash, err := gitRead(repoPath, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+		if err != nil {
+			return linkTargetMsg{rev: rev, err: fmt.Errorf("%s is no commit", rev)}
+		}
+		return linkTargetMsg{rev: rev, hash: hash}
+	}
+}
+
+// handleLinkTarget jumps to the commit of a followed link.
+func (m *model) handleLinkTarget(msg linkTargetMsg) tea.Cmd {
+	if msg.err != nil {
+		m.status, m.statusErr = "Can't follow the link: "+msg.err.Error(), true
+		return nil
+	}
+	if i := m.commitIndex(msg.hash); i >= 0 {
+		m.status = "Jumped to " + strings.TrimPrefix(msg.rev, "refs/tags/") + "; ctrl+o goes back"
+		return m.jumpTo(i)
+	}
+	m.status, m.statusErr = msg.rev+" isn't in the graph", true
+	return nil
+}
+
+type urlOpenedMsg struct {
+	url string
+	err error
+}
+
+// openURL opens a URL in the browser.
+func openURL(target string) tea.Cmd {
+	return func() tea.Msg {
+		var cmd *exec.Cmd
+		switch {
+		case runtime.GOOS == "darwin":
+			cmd = exec.Command("open", target)
+		case runtime.GOOS == "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+		case inWSL():
+			// The Windows browser, through Explorer
+			cmd = exec.Command("explorer.exe", target)
+		default:
+			cmd = exec.Command("xdg-open", target)
+		}
+		if err := cmd.Start(); err != nil {
+			return urlOpenedMsg{url: target, err: err}
+		}
+		go cmd.Wait()
+		return urlOpenedMsg{url: target}
+	}
+}
+
+// describedTag is the tag of git describe's name for a commit, as in
+// v1.2-3-gabc1234.
+func describedTag(describe string) string {
+	if m := describeSuffix.FindStringIndex(describe); m != nil {
+		return describe[:m[0]]
+	}
+	return describe
+}
+
+var describeSuffix = regexp.MustCompile(`-[0-9]+-g[0-9a-f]+$`)
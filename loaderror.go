@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logFileName is the debug log gitraffe writes in the directory it is
+// started from.
+const logFileName = "gitraffe.log"
+
+// errGoGitFilter refuses a filter with the go-git backend, rather than
+// showing the whole history as if it were filtered: the terms go to git
+// log, which also reads dates like "1.month" and simplifies the history
+// of a path.
+var errGoGitFilter = errors.New("go-git can't filter the history; the cli backend can")
+
+// loadHistory loads the commits of the opened repository with the
+// chosen backend, see backend.go. Which loader it took is logged and
+// kept for crash reports.
+func (m *model) loadHistory() error {
+	if m.backend == backendGoGit {
+		if m.repo == nil {
+			return fmt.Errorf("go-git can't open the repository")
+		}
+		if m.filter != nil {
+			return errGoGitFilter
+		}
+		commits, err := m.loadCommits()
+		if err != nil {
+			return fmt.Errorf("go-git: %w", err)
+		}
+		m.commits, m.displayRows = commits, nil
+		m.loadedWith = "go-git"
+		log.Println("Loaded the history with go-git")
+		return nil
+	}
+	m.matches = nil
+	if m.filter != nil && m.highlight {
+		matches, err := m.filterMatches()
+		if err != nil {
+			return err
+		}
+		m.matches = matches
+	}
+	coAuthored, err := m.coAuthoredHistory()
+	if err != nil {
+		return err
+	}
+	if coAuthored != nil {
+		// git log draws no graph of a list of commits
+		commits, err := m.loadCommitList(coAuthored)
+		if err != nil {
+			return err
+		}
+		m.commits, m.displayRows = commits, nil
+		m.coAuthored = coAuthored
+		m.loadedWith = "git log --no-walk"
+		log.Printf("Loaded the history with %s, as co-authors widen the filter\n", m.loadedWith)
+		return nil
+	}
+	m.coAuthored = nil
+	m.loadedWith = "git log --graph"
+	if err := m.loadGraphData(); err != nil {
+		log.Printf("Graph loading failed: %v, trying simple load...\n", err)
+		commits, err2 := m.loadCommitsFromGitCLI()
+		if err2 != nil {
+			return fmt.Errorf("graph: %v, fallback: %v", err, err2)
+		}
+		m.commits = commits
+		m.loadedWith = "git log"
+	}
+	log.Printf("Loaded the history with %s\n", m.loadedWith)
+	return nil
+}
+
+// opened finishes loading once the repository is opened with go-git, or
+// go-git failed with openErr or wasn't used and the git CLI takes over. A failure to
+// load the history leaves the error screen, with ways out of it.
+func (m *model) opened(openErr error) tea.Cmd {
+	infoDone := m.prof.step("repository info")
+	if m.repo == nil {
+		m.loadRepoInfoFromCLI()
+	} else {
+		m.loadRepoInfo()
+	}
+	infoDone()
+	m.ready = true
+	if m.unborn {
+		// Nothing to draw yet: start on the working tree, where the
+		// first commit is made
+		m.workTree = true
+		m.dataVersion++
+		return m.maybeLoadWorkTreeDiff(true)
+	}
+
+	if err := m.loadHistory(); err != nil {
+		if openErr != nil {
+			err = fmt.Errorf("%v (%v)", openErr, err)
+		}
+		m.err = err
+		m.menu = m.errorMenu(false)
+		return nil
+	}
+	m.selected = 0
+	m.dataVersion++
+	return tea.Batch(m.maybeLoadDetails(), m.maybeLoadImpact(), m.maybeLoadPatchIDs())
+}
+
+// updateError handles a key on the error screen, where the error menu
+// stays open.
+func (m *model) updateError(msg tea.KeyMsg) tea.Cmd {
+	if msg.String() == "ctrl+c" && m.prompt == nil {
+		return tea.Quit
+	}
+	cmd, _ := m.updateOverlay(msg)
+	if m.err != nil && m.menu == nil && m.prompt == nil {
+		m.menu = m.errorMenu(false)
+	}
+	return cmd
+}
+
+// errorMenu offers the ways out of a failed load: trying again, maybe
+// with the other backend, or another repository, and the log for why.
+func (m *model) errorMenu(showLog bool) *menu {
+	options := []menuOption{{key: "r", label: "retry", action: retryLoad}}
+	switch {
+	case m.backend == backendGoGit:
+		options = append(options, menuOption{key: "b", label: "retry with the git CLI", action: func(m *model) tea.Cmd {
+			m.backend = backendAuto
+			return retryLoad(m)
+		}})
+	case m.repo != nil || m.backend == backendCLI:
+		// go-git can read a repository the git CLI fails on, or work
+		// without a git in the PATH. With the cli backend it wasn't
+		// tried.
+		options = append(options, menuOption{key: "b", label: "retry with go-git", action: func(m *model) tea.Cmd {
+			m.backend = backendGoGit
+			return retryLoad(m)
+		}})
+	}
+	options = append(options, menuOption{key: "o", label: "open another path", action: openOther})
+	if showLog {
+		options = append(options, menuOption{key: "l", label: "hide the log", action: func(m *model) tea.Cmd {
+			m.menu = m.errorMenu(false)
+			return nil
+		}})
+	} else {
+		options = append(options, menuOption{key: "l", label: "show the log", action: func(m *model) tea.Cmd {
+			m.menu = m.errorMenu(true)
+			return nil
+		}})
+	}
+	options = append(options, menuOption{key: "q", label: "quit", action: func(m *model) tea.Cmd { return tea.Quit }})
+
+	mn := &menu{title: "Error", options: options}
+	if showLog {
+		mn.detail = logTail(max(m.windowHeight-12, 5))
+	}
+	return mn
+}
+
+// retryLoad opens the repository again, as at startup.
+func retryLoad(m *model) tea.Cmd {
+	m.err, m.ready, m.menu = nil, false, nil
+	m.status = ""
+	return m.openCmds()
+}
+
+// openOther asks for another repository to open in place of this one,
+// as if gitraffe were started there.
+func openOther(m *model) tea.Cmd {
+	m.prompt = newPrompt("Open", "path of a repository", func(m *model, value string) tea.Cmd {
+		if value == "" {
+			return nil
+		}
+		next := initialModel(options{repoPath: value, backend: m.backend, present: m.present})
+		next.windowWidth, next.windowHeight = m.windowWidth, m.windowHeight
+		next.prof = m.prof
+		*m = next
+		return m.openCmds()
+	})
+	m.prompt.input.SetValue(m.repoPath)
+	m.prompt.input.CursorEnd()
+	return nil
+}
+
+// logTail is the end of the log, n lines at most.
+func logTail(n int) string {
+	path, _ := filepath.Abs(logFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return helpStyle.Render("Can't read the log: " + err.Error())
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	lines = lines[max(len(lines)-n, 0):]
+	return sectionHeader(path) + "\n" + strings.Join(lines, "\n")
+}
+
+// renderError renders the error screen: what went wrong, the log when
+// asked for, and the error menu or the prompt in the status line.
+func (m *model) renderError() string {
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FF0000")).
+		Bold(true)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n  %s\n\n", errorStyle.Render("❌ Error loading repository"))
+	message := lipgloss.NewStyle().Width(max(m.windowWidth-4, 20)).Render("Error: " + m.err.Error())
+	for _, line := range strings.Split(message, "\n") {
+		sb.WriteString("  " + line + "\n")
+	}
+	sb.WriteString("\n")
+	if m.menu != nil && m.menu.detail != "" {
+		detail := lipgloss.NewStyle().MaxWidth(m.windowWidth - 2).Render(m.menu.detail)
+		for _, line := range strings.Split(detail, "\n") {
+			sb.WriteString("  " + line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	// The menu can't be cancelled here, so it goes without the esc hint
+	// of the status line
+	switch {
+	case m.prompt != nil:
+		sb.WriteString("  " + m.prompt.input.View())
+	case m.menu != nil:
+		keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+		var parts []string
+		for _, opt := range m.menu.options {
+			parts = append(parts, keyStyle.Render("["+opt.key+"]")+" "+opt.label)
+		}
+		sb.WriteString("  " + strings.Join(parts, "  "))
+	}
+	return sb.String()
+}
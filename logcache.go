@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The output of git log --graph is cached under the user cache dir, one
+// file per repository and scope, so reopening a large repository that
+// hasn't changed skips sorting and laying out its history. The file
+// starts with a line holding the cache key: a digest of the ref tips,
+// HEAD, the config (core.abbrev, say), .mailmap and the file of
+// mailmap.file, and GIT_NO_REPLACE_OBJECTS, anything that changes the
+// output. A log limited to a window of dates isn't cached, as the window
+// of since:1.month moves every day. When any of
+// them moved the log runs again: a new branch can add a lane next to
+// commits from long ago, so the layout can't be extended with the new
+// commits alone. The output is kept rather than the commits and rows
+// parsed from it, as parsing it again takes a fraction of what git log
+// takes on a history large enough to be worth caching.
+//
+// Each repository, work tree and scope leaves a file, so a release range
+// or --ref looked at once would stay for good. Files not used for
+// logCacheAge are removed whenever a log is written, and past that the
+// least recently used go until all of them fit in logCacheBudget.
+
+const (
+	logCacheAge    = 30 * 24 * time.Hour
+	logCacheBudget = 256 << 20
+)
+
+// logCacheDir is where the cached logs are kept.
+func logCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gitraffe"), nil
+}
+
+// logCacheKey returns the file a git log of the repository is cached in,
+// and the key it must hold to be current.
+func logCacheKey(repoPath string, args []string) (path, key string, err error) {
+	dir, err := logCacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	for _, arg := range args {
+		// A window like --since=1.month.ago moves by itself, without
+		// anything in the repository moving
+		for _, option := range []string{"--since=", "--after=", "--until=", "--before="} {
+			if strings.HasPrefix(arg, option) {
+				return "", "", fmt.Errorf("%s depends on the date", arg)
+			}
+		}
+	}
+
+	// The git dir and, in a work tree, the way up to it, then HEAD. A
+	// linked work tree has a git dir of its own, and a file of its own,
+	// as its HEAD isn't that of the main one
+	out, err := gitRead(repoPath, "rev-parse", "--path-format=absolute", "--git-dir", "--show-cdup", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(out, "\n")
+	gitDir, head := lines[0], lines[len(lines)-1]
+
+	refs, err := gitRead(repoPath, "for-each-ref", "--format=%(objectname) %(refname)")
+	if err != nil {
+		return "", "", err
+	}
+	config, _ := gitRead(repoPath, "config", "--list", "-z") // fails without any config at all
+
+	name := sha256.Sum256([]byte(gitDir + "\x00" + strings.Join(args, "\x00")))
+	path = filepath.Join(dir, hex.EncodeToString(name[:8])+".log")
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%v\x00", head, refs, config, os.Getenv("GIT_NO_REPLACE_OBJECTS"), noReplaceObjects.Load())
+	if len(lines) == 3 {
+		// Not bare: names and emails go through the .mailmap at the top
+		mailmap, _ := os.ReadFile(filepath.Join(repoPath, lines[1], ".mailmap"))
+		h.Write(mailmap)
+	}
+	// and the file of mailmap.file, which the config only names
+	if file, err := gitRead(repoPath, "config", "--path", "--get", "mailmap.file"); err == nil && file != "" {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(repoPath, file)
+		}
+		mailmap, _ := os.ReadFile(file)
+		h.Write(mailmap)
+	}
+	return path, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachedGitLog runs git log with args, ending in -- and any paths, in
+// the repository, or returns its output from the last time if nothing
+// changed since. Failing to use the
+// cache only makes it slower.
+func cachedGitLog(repoPath string, args []string) (string, error) {
+	path, key, err := logCacheKey(repoPath, args)
+	if err != nil {
+		log.Printf("Not caching the log: %v\n", err)
+	} else if data, err := os.ReadFile(path); err == nil {
+		if cached, ok := strings.CutPrefix(string(data), key+"\n"); ok {
+			log.Printf("Using the log cached in %s\n", path)
+			// The time it was last used is what keeps it from pruning
+			now := time.Now()
+			os.Chtimes(path, now, now)
+			return cached, nil
+		}
+	}
+
+	cmd := gitCommand(repoPath, args...)
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%v (%s)", err, strings.TrimSpace(errOut.String()))
+	}
+
+	if path != "" {
+		if err := writeLogCache(path, key, out.String()); err != nil {
+			log.Printf("Not caching the log: %v\n", err)
+		}
+		pruneLogCache(filepath.Dir(path), path, logCacheAge, logCacheBudget)
+	}
+	return out.String(), nil
+}
+
+// writeLogCache replaces the cached log all at once, so that another
+// gitraffe reading it never sees half a file.
+func writeLogCache(path, key, output string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".log-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(key + "\n" + output)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// pruneLogCache removes the cached logs in dir not used for maxAge, then
+// the least recently used until the rest fit in budget bytes, but never
+// keep, the one just written. Temporary files left by a gitraffe that
+// died while writing go after an hour.
+func pruneLogCache(dir, keep string, maxAge time.Duration, budget int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		path string
+		info fs.FileInfo
+	}
+	var files []cached
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case strings.HasPrefix(e.Name(), ".log-"):
+			if time.Since(info.ModTime()) > time.Hour {
+				os.Remove(path)
+			}
+		case strings.HasSuffix(e.Name(), ".log") && path != keep:
+			files = append(files, cached{path, info})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].info.ModTime().After(files[j].info.ModTime()) })
+
+	var size int64
+	if info, err := os.Stat(keep); err == nil {
+		size = info.Size()
+	}
+	for _, f := range files {
+		size += f.info.Size()
+		if size > budget || time.Since(f.info.ModTime()) > maxAge {
+			log.Printf("Pruning the cached log %s\n", f.path)
+			os.Remove(f.path)
+		}
+	}
+}
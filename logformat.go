@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// logFormat is the --pretty format of the commit fields gitraffe reads
+// from git log, separated by NULs. Unlike "|" or a tab, a NUL can't be in
+// a subject, name or ref. The subject comes last, the field most likely
+// to hold anything odd.
+//
+// %aN, %aE, %cN and %cE map names and emails through .mailmap, so one
+// person under several addresses shows up as one. %h and %p are
+// abbreviated by git, unambiguous in the repository. Strict ISO 8601
+// dates keep the author's and committer's offsets.
+const logFormat = "%H%x00%h%x00%P%x00%p%x00%aN%x00%aE%x00%aI%x00%cN%x00%cE%x00%cI%x00%D%x00%s"
+
+// logFields is how many fields logFormat has.
+const logFields = 12
+
+// splitLog splits the output of git log -z, whose format has n fields
+// separated by %x00, into its records. With -z the records are separated
+// by NULs too, so fields can hold anything else, like the newlines of a
+// whole message (%B).
+func splitLog(out string, n int) ([][]string, error) {
+	out = strings.TrimSuffix(out, "\x00")
+	if out == "" {
+		return nil, nil
+	}
+	fields := strings.Split(out, "\x00")
+	if len(fields)%n != 0 {
+		return nil, fmt.Errorf("git log gave %d fields, not records of %d", len(fields), n)
+	}
+	records := make([][]string, 0, len(fields)/n)
+	for len(fields) > 0 {
+		records = append(records, fields[:n:n])
+		fields = fields[n:]
+	}
+	return records, nil
+}
+
+// parseLogFields makes a commit of the fields of logFormat. The strings
+// kept are copied or interned, so they don't hold on to git's output.
+func parseLogFields(fields []string, in interner) (commit, error) {
+	if len(fields) != logFields {
+		return commit{}, fmt.Errorf("%d fields in a commit, want %d", len(fields), logFields)
+	}
+	if !plumbing.IsHash(fields[0]) {
+		return commit{}, fmt.Errorf("%q is not a commit hash", fields[0])
+	}
+	date, err := time.Parse(time.RFC3339, fields[6])
+	if err != nil {
+		return commit{}, fmt.Errorf("author date of %s: %w", fields[0], err)
+	}
+	commitDate, err := time.Parse(time.RFC3339, fields[9])
+	if err != nil {
+		return commit{}, fmt.Errorf("commit date of %s: %w", fields[0], err)
+	}
+	var parents []string
+	for _, p := range strings.Fields(fields[3]) {
+		parents = append(parents, in.intern(p))
+	}
+	refs, rewritten := splitRewritten(strings.TrimSpace(fields[10]))
+	return commit{
+		Hash:           strings.Clone(fields[1]),
+		FullHash:       plumbing.NewHash(fields[0]),
+		Author:         in.intern(fields[4]),
+		AuthorEmail:    in.intern(fields[5]),
+		Date:           date,
+		Committer:      in.intern(fields[7]),
+		CommitterEmail: in.intern(fields[8]),
+		CommitDate:     commitDate,
+		Message:        strings.Clone(fields[11]),
+		Parents:        parents,
+		Refs:           strings.Clone(refs),
+		Rewritten:      rewritten,
+	}, nil
+}
+
+// identity is a name and email as a commit gives them.
+type identity struct{ name, email string }
+
+// mailmap maps identities through .mailmap and mailmap.file like %aN
+// and %aE do, for loaders that read commits without git log: one git
+// check-mailmap for all of them. Without git, or when it fails, they
+// stay as written.
+func mailmap(repoPath string, ids []identity) map[identity]identity {
+	var in strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&in, "%s <%s>\n", id.name, id.email)
+	}
+	cmd := gitCommand(repoPath, "check-mailmap", "--stdin")
+	cmd.Stdin = strings.NewReader(in.String())
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != len(ids) {
+		return nil
+	}
+	mapped := make(map[identity]identity, len(ids))
+	for i, line := range lines {
+		name, email, ok := strings.Cut(strings.TrimSuffix(line, ">"), " <")
+		if ok {
+			mapped[ids[i]] = identity{name, email}
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitLog(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		n    int
+		want [][]string
+	}{
+		{"empty", "", 2, nil},
+		{"one", "a\x00b\x00", 2, [][]string{{"a", "b"}}},
+		{"no trailing NUL", "a\x00b", 2, [][]string{{"a", "b"}}},
+		{"two", "a\x00b\x00c\x00d\x00", 2, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"pipes", "a | b\x00|\x00", 2, [][]string{{"a | b", "|"}}},
+		{"newlines", "a\x00line 1\nline 2\n\x00", 2, [][]string{{"a", "line 1\nline 2\n"}}},
+		{"empty fields", "\x00\x00", 2, [][]string{{"", ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitLog(tt.out, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := splitLog("a\x00b\x00c\x00", 2); err == nil {
+		t.Error("no error for a record cut short")
+	}
+}
+
+// oddRepo has commits whose subjects, bodies and names hold what used to
+// trip the parsing up.
+func oddRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	d := &demoRepo{dir: dir, clock: time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)}
+	d.git("init", "-q")
+	d.git("symbolic-ref", "HEAD", "refs/heads/main")
+	d.commit("a", "1\n", "Split on | and || in the subject")
+	d.commit("b", "2\n", "Giraffe 🦒 in Zürich: Ünïcødé ✓\n\nA body\nover | several\n\nlines")
+	d.commit("c", "3\n", "Tabs\tand %x00 and a hash 0123456789012345678901234567890123456789")
+	d.git("tag", "tabé")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	return dir
+}
+
+var oddSubjects = []string{
+	"Tabs\tand %x00 and a hash 0123456789012345678901234567890123456789",
+	"Giraffe 🦒 in Zürich: Ünïcødé ✓",
+	"Split on | and || in the subject",
+}
+
+func TestParseLog(t *testing.T) {
+	dir := oddRepo(t)
+
+	m := initialModel(options{repoPath: dir})
+	fallback, err := m.loadCommitsFromGitCLI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	for name, commits := range map[string][]commit{"fallback": fallback, "graph": m.commits} {
+		if len(commits) != len(oddSubjects) {
+			t.Fatalf("%s: %d commits, want %d", name, len(commits), len(oddSubjects))
+		}
+		for i, c := range commits {
+			if c.Message != oddSubjects[i] {
+				t.Errorf("%s: subject %q, want %q", name, c.Message, oddSubjects[i])
+			}
+			if c.Author != "Ada Graph" || c.AuthorEmail != "ada@example.com" {
+				t.Errorf("%s: author %q <%s>", name, c.Author, c.AuthorEmail)
+			}
+			if c.Date.IsZero() || c.CommitDate.IsZero() {
+				t.Errorf("%s: dates %v and %v", name, c.Date, c.CommitDate)
+			}
+		}
+		if len(commits[0].Parents) != 1 || commits[0].Parents[0] != commits[1].Hash {
+			t.Errorf("%s: parents %q, want %s", name, commits[0].Parents, commits[1].Hash)
+		}
+		if commits[0].Refs != "HEAD -> main, tag: tabé" {
+			t.Errorf("%s: refs %q", name, commits[0].Refs)
+		}
+	}
+}
+
+// TestSplitLogBody reads whole messages, newlines and all.
+func TestSplitLogBody(t *testing.T) {
+	dir := oddRepo(t)
+	cmd := exec.Command("git", "log", "-z", "--format=%H%x00%B")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := splitLog(string(out), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("%d records, want 3", len(records))
+	}
+	want := "Giraffe 🦒 in Zürich: Ünïcødé ✓\n\nA body\nover | several\n\nlines\n"
+	if got := records[1][1]; got != want {
+		t.Errorf("message %q, want %q", got, want)
+	}
+}
+
+// TestParseLogFields covers fields git wouldn't give.
+func TestParseLogFields(t *testing.T) {
+	fields := []string{"0123456789abcdef0123456789abcdef01234567", "0123456", "", "", "Ada", "ada@example.com", "not a date", "Ada", "ada@example.com", "2024-01-08T09:00:00Z", "", "Subject"}
+	if _, err := parseLogFields(fields, interner{}); err == nil {
+		t.Error("no error for a bad date")
+	}
+	if _, err := parseLogFields(fields[:5], interner{}); err == nil {
+		t.Error("no error for missing fields")
+	}
+	fields[6] = "2024-01-08T11:00:00+02:00"
+	c, err := parseLogFields(fields, interner{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := c.Date.Zone(); offset != 2*60*60 {
+		t.Errorf("offset %d, want the author's +02:00", offset)
+	}
+	if c.Parents != nil {
+		t.Errorf("parents %q of a root commit", c.Parents)
+	}
+}
+
+// writeCommit writes a commit object as it is, message bytes and all,
+// and puts main on it.
+func writeCommit(t *testing.T, dir, header, message string) {
+	t.Helper()
+	git := func(stdin string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	tree := git("", "hash-object", "-w", "-t", "tree", "/dev/null")
+	parent := ""
+	if head := git("", "for-each-ref", "--format=%(objectname)", "refs/heads/main"); head != "" {
+		parent = "parent " + head + "\n"
+	}
+	object := "tree " + tree + "\n" + parent +
+		"author Ada Graph <ada@example.com> 1704704400 +0000\n" +
+		"committer Ada Graph <ada@example.com> 1704704400 +0000\n" +
+		header + "\n" + message
+	git("", "update-ref", "refs/heads/main", git(object, "hash-object", "-w", "-t", "commit", "--stdin"))
+}
+
+// TestEncodings reads messages in other encodings than UTF-8: with an
+// encoding header, without one but in i18n.commitEncoding, and without
+// either. The user's i18n.logOutputEncoding doesn't change them.
+func TestEncodings(t *testing.T) {
+	dir := t.TempDir()
+	d := &demoRepo{dir: dir}
+	d.git("init", "-q")
+	d.git("symbolic-ref", "HEAD", "refs/heads/main")
+	d.git("config", "i18n.logOutputEncoding", "ISO-8859-1")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	writeCommit(t, dir, "encoding ISO-8859-1\n", "Caf\xe9 cr\xe8me\n")
+	writeCommit(t, dir, "", "Stra\xdfe ohne Kopf\n")
+	writeCommit(t, dir, "encoding CP1251\n", "\xcf\xf0\xe8\xe2\xe5\xf2\n")
+
+	m := initialModel(options{repoPath: dir})
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Привет", "Straße ohne Kopf", "Café crème"}
+	for i, c := range m.commits {
+		if c.Message != want[i] {
+			t.Errorf("subject %q, want %q", c.Message, want[i])
+		}
+	}
+
+	// Without a header, in the encoding the project uses
+	d.git("config", "i18n.commitEncoding", "CP1251")
+	writeCommit(t, dir, "", "\xc6\xe8\xf0\xe0\xf4\xe0\n")
+	m = initialModel(options{repoPath: dir})
+	if err := m.loadGraphData(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.commits[0].Message; got != "Жирафа" {
+		t.Errorf("subject %q, want it read as CP1251", got)
+	}
+}
@@ -2,18 +2,20 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"regexp"
-	"strconv"
+	"runtime"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -28,6 +30,13 @@ var (
 			Foreground(lipgloss.Color("#FFA500")).
 			Bold(true)
 
+	incomingStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#A3BE8C")).
+			Bold(true)
+
+	freshStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#EBCB8B"))
+
 	authorStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#7DD3FC"))
 
@@ -46,57 +55,283 @@ var (
 )
 
 type commit struct {
-	Hash       string
-	FullHash   string
-	Author     string
-	Date       time.Time
-	Message    string
-	Parents    []string
-	Refs       string
-	GraphLine  string
-	DiffLoaded bool
-	DiffStat   string
-	DiffBody   string
+	Hash           string
+	FullHash       plumbing.Hash // 20 bytes rather than 40 hex digits; String() where git takes it
+	Author         string
+	AuthorEmail    string
+	Date           time.Time // author date
+	Committer      string
+	CommitterEmail string
+	CommitDate     time.Time
+	Message        string
+	Parents        []string
+	Refs           string
+	GraphLine      string
+	DiffLoaded     bool
+	DiffParent     int // parent a merge is diffed against, from 1; 0 for git's combined diff
+	DiffBody       string
+	Files          []fileChange
+	Describe       string // `git describe --tags`, empty when no tag is reachable
+	Body           string // message after the subject, trailers removed
+	Trailers       []trailer
+
+	Rewritten string // "replaced" by git replace, or "grafted" at the edge of a shallow clone
+
+	ContainsLoaded   bool
+	ContainsBranches []string
+	ContainsTags     []string
 }
 
 type displayRow struct {
 	GraphChars string // transliterated Unicode graph characters
+	Node       string // symbol drawn for the commit
+	NodeAt     int    // byte offset of Node in GraphChars
 	CommitIdx  int    // index into commits slice, -1 for graph-only lines
 	GraphWidth int    // visual width of the graph portion
+	Separator  bool   // line between unrelated histories, e.g. an orphan gh-pages branch
+	Colors     []int8 // lane color of each graph character, see lanes.go
+}
+
+// panelCache memoizes the rendered output of a single panel. View runs on
+// every message, so a panel is only re-rendered when its key (a summary of
+// everything that affects its output) changes.
+type panelCache struct {
+	key    string
+	output string
+}
+
+func (c *panelCache) get(key string, render func() string) string {
+	if c.key == key && c.output != "" {
+		return c.output
+	}
+	c.output = render()
+	c.key = key
+	return c.output
+}
+
+const (
+	narrowWidth      = 60 // below this the panels no longer sit side by side
+	minStackedHeight = 4  // smallest inner height of a stacked panel
+)
+
+type viewCache struct {
+	repoInfo panelCache
+	left     panelCache
+	right    panelCache
+	minimap  minimapArea // where the minimap is on screen, set by View
 }
 
 type model struct {
-	repo          *git.Repository
-	commits       []commit
-	ready         bool
-	repoPath      string
-	err           error
-	selected      int
-	windowHeight  int
-	windowWidth   int
-	repoName      string
-	currentBranch string
-	currentCommit string
-	focusedBox    int // 0 = repo info, 1 = commit list, 2 = commit details
-	detailsScroll int // scroll offset for the details panel
-	displayRows   []displayRow
-	maxGraphWidth int
+	repo           *git.Repository
+	commits        []commit
+	ready          bool
+	repoPath       string
+	ref            string                 // --ref / gitraffe.ref, empty for all refs
+	revRange       string                 // --range, overrides ref
+	backend        string                 // what loads the history and diffs, see backend.go
+	loadedWith     string                 // the loader that loaded the history, for reports
+	filter         *filter                // preset limiting the commits, nil for none
+	highlight      bool                   // the filter dims the commits it doesn't match rather than hiding them
+	matches        map[plumbing.Hash]bool // commits the highlighted filter matches, nil when none is highlighted
+	coAuthored     []string               // full hashes of the filtered commits when co-authors widen the filter, see coAuthoredHistory
+	exclude        []string
+	err            error
+	selected       int
+	windowHeight   int
+	windowWidth    int
+	repoName       string
+	currentBranch  string
+	currentCommit  string
+	remotes        []string
+	checkout       checkoutInfo          // partial clone and sparse checkout
+	focusedBox     int                   // 0 = repo info, 1 = commit list, 2 = commit details
+	lastFocus      int                   // panel focused before focusedBox, for the ` toggle
+	detailTab      int                   // active tab of the details panel
+	link           int                   // focused link of the Commit or Refs tab, from 1; 0 for none
+	detailsScroll  [numDetailTabs]int    // scroll offset of each details tab
+	showImpact     bool                  // impact column: lines added and deleted per commit
+	impact         map[string]impact     // by full hash, from loadImpact
+	impactCached   bool                  // the counts of earlier runs are read
+	impactPending  int                   // chunks of commits still being counted
+	impactDone     int                   // commits counted, for the progress bar
+	impactTotal    int                   // commits in the graph when counting started
+	showCopies     bool                  // mark commits whose change is in the graph more than once
+	patchIDs       map[string]string     // by full hash, from loadPatchIDs
+	patchIDsCached bool                  // the patch-ids of earlier runs are read
+	patchIDPending int                   // chunks of commits still being hashed
+	copies         map[string][]int      // commit indexes by patch-id, for those with copies
+	showMinimap    bool                  // the whole history in a column left of the list
+	marks          []string              // full hashes of the commits marked with M, at most two
+	mergeBase      *mergeBase            // of the two marked commits, nil until found
+	folds          map[string]bool       // files of a diff collapsed (true) or expanded by hand, see foldKey
+	positions      map[string]detailsPos // details scroll and cursor of commits selected before, by full hash
+	displayRows    []displayRow
+	maxGraphWidth  int
+	zoomed         bool // focused panel expanded to the full window
+	containsAll    bool // list every containing ref, not just the first few
+	cfg            config
+	now            time.Time // wall clock as of the last tick, for relative dates
+	workTree       bool      // showing the working tree instead of the graph
+	wtFiles        []workTreeFile
+	wtSelected     int
+	wtMarked       map[string]bool // paths marked with x for file actions
+	wtDiff         workTreeDiff
+	wtScroll       int
+	wtHunk         int               // selected hunk in the working tree diff
+	prompt         *prompt           // text input in the status line, nil when closed
+	commit         *commitEditor     // commit screen, nil when closed
+	commitDraft    string            // message kept when the commit screen is closed
+	incoming       map[string]bool   // commits brought in by the last pull, by full hash
+	tracking       trackingMsg       // the current branch against its upstream
+	fresh          map[string]bool   // commits fetched in the background, not yet in the upstream
+	unborn         bool              // HEAD is on a branch without commits, as in a new repository
+	rewritten      int               // commits whose parents come from a replace ref or a shallow graft
+	noReplace      bool              // ignore replace refs, like git --no-replace-objects
+	review         *reviewState      // commits and files marked reviewed, saved per repository
+	notes          *noteStore        // notes on commits
+	filesCursor    int               // file of the Files tab that v marks
+	base           string            // ref local branches are compared with, for stacked branches
+	stack          []stackBranch     // local branches compared with base
+	stackLabels    map[string]string // "+3" annotations by commit hash
+	stackWidth     int               // width of the widest annotation
+	count          countPrefix       // count typed before a motion
+	jumps          jumpList          // where g/G and other jumps came from, for ctrl+o/ctrl+n
+	present        bool              // presentation mode: the graph only, read-only, following HEAD
+	followRefs     string            // refs as last seen by presentation mode
+	menu           *menu             // key choices in the status line, nil when closed
+	finder         *finder           // ctrl+f fuzzy finder, nil when closed
+	releases       *releaseView      // T release overview, nil when closed
+	grep           *grepView         // / grep results, nil when closed
+	tree           *treeView         // t tree browser, nil when closed
+	fileView       *fileView         // file viewer, over the grep results or tree, nil when closed
+	compare        *compareView      // a file compared between two commits, over the file viewer or tree, nil when closed
+	shell          *shellView        // ! command output, nil when closed
+	shellLast      string            // the last command run with !, to start the next from
+	tagGlob        string            // tags the release overview lists, all when empty
+	tour           *tour             // first-run tour, nil when not showing
+	status         string            // outcome of the last action
+	statusErr      bool
+	hashWidth      int             // width of the longest short hash, see abbrev.go
+	diffOrder      []plumbing.Hash // full hashes of the loaded diffs, the selected longest ago first
+	dataVersion    int             // bumped whenever commits or displayRows change
+	cache          *viewCache      // shared across model copies, see panelCache
+	prof           *profiler       // startup timings for --profile, nil without it
 }
 
-func initialModel(repoPath string) model {
+func initialModel(opts options) model {
+	opts.repoPath = normalizeRepoPath(opts.repoPath)
+	cfg := loadConfig(opts.repoPath)
+	ref := opts.ref
+	if ref == "" {
+		ref = cfg.Ref
+	}
+	base := opts.base
+	if base == "" {
+		base = cfg.Base
+	}
+	backend := opts.backend
+	if backend == "" {
+		backend = cfg.Backend
+	}
 	return model{
-		repoPath:   repoPath,
-		focusedBox: 1, // default focus on commit list
+		repoPath:    opts.repoPath,
+		ref:         ref,
+		base:        base,
+		backend:     backend,
+		present:     opts.present,
+		revRange:    opts.revRange,
+		exclude:     append(cfg.Exclude, opts.exclude...),
+		focusedBox:  1, // default focus on commit list
+		lastFocus:   2,
+		positions:   make(map[string]detailsPos),
+		folds:       make(map[string]bool),
+		showImpact:  cfg.Impact,
+		showMinimap: cfg.Minimap,
+		impact:      make(map[string]impact),
+		patchIDs:    make(map[string]string),
+		cfg:         cfg,
+		now:         time.Now(),
+		wtMarked:    make(map[string]bool),
+		review:      loadReviewState(opts.repoPath),
+		notes:       loadNotes(opts.repoPath, cfg.NotesRef),
+		cache:       &viewCache{},
 	}
 }
 
+// logScope returns the revisions the graph is built from: every ref by
+// default, or just the chosen range or the history of the starting ref.
+// Excluded ref patterns are left out of --all and out of the decorations.
+// The options of the filter applied come first, unless it is highlighted;
+// its paths are apart, see filterPaths.
+func (m *model) logScope() []string {
+	args := m.decorateArgs()
+	if m.filter != nil && !m.highlight {
+		args = append(args, m.filter.args...)
+	}
+	if m.revRange != "" {
+		return append(args, m.revRange)
+	}
+	if m.ref != "" {
+		return append(args, m.ref)
+	}
+	for _, pattern := range m.scopeExcludes() {
+		args = append(args, "--exclude="+pattern)
+	}
+	return append(args, "--all")
+}
+
+// decorateArgs leave the excluded refs out of the decorations.
+func (m *model) decorateArgs() []string {
+	// Background fetches land in refs/prefetch; like git maintenance,
+	// keep them out of the decorations
+	args := []string{"--decorate-refs-exclude=refs/prefetch/"}
+	for _, pattern := range m.exclude {
+		args = append(args, "--decorate-refs-exclude="+pattern)
+	}
+	return args
+}
+
+// scopeLabel describes logScope for the repo info box, empty for all refs.
+func (m *model) scopeLabel() string {
+	if m.revRange != "" {
+		return m.revRange
+	}
+	return m.ref
+}
+
 func (m model) Init() tea.Cmd {
-	return loadRepo(m.repoPath)
+	var follow tea.Cmd
+	if m.present {
+		follow = followHead(m.repoPath)
+	}
+	return tea.Batch(tea.SetWindowTitle(windowTitle(m.repoPath)), m.openCmds(), tick(), scheduleFetch(m.cfg.FetchInterval), follow)
+}
+
+// openCmds open the repository and load what the screen shows of it.
+func (m *model) openCmds() tea.Cmd {
+	return tea.Batch(m.prof.stepCmd("open repository", loadRepo(m.repoPath, m.backend)), loadWorkTreeStatus(m.repoPath), loadTracking(m.repoPath), loadStack(m.repoPath, m.base))
+}
+
+// refreshInterval is how often relative dates and the working tree status
+// are brought up to date in long-running sessions.
+const refreshInterval = 30 * time.Second
+
+type tickMsg time.Time
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
 }
 
-func loadRepo(path string) tea.Cmd {
+// loadRepo opens the repository with go-git, unless the backend leaves
+// it to the git CLI.
+func loadRepo(path, backend string) tea.Cmd {
 	return func() tea.Msg {
-		repo, err := git.PlainOpen(path)
+		if backend == backendCLI {
+			return repoMsg{}
+		}
+		repo, err := git.PlainOpen(goGitPath(path))
 		if err != nil {
 			return errMsg{err}
 		}
@@ -118,172 +353,662 @@ func (e errMsg) Error() string {
 
 type diffLoadedMsg struct {
 	commitIdx int
-	diffStat  string
+	parent    int
 	diffBody  string
+	files     []fileChange
+	describe  string
+	body      string
 }
 
-func loadDiffCmd(repoPath string, fullHash string, idx int) tea.Cmd {
+// loadDiffCmd loads everything the details tabs show for a commit. In a
+// partial clone, git fetches the blobs a diff needs from the promisor
+// remote as it goes; that must not prompt, and when it fails the reason
+// is shown in place of the diff. parent picks the parent of a merge to
+// diff against, counting from 1; 0 is git's own combined view.
+func loadDiffCmd(repoPath string, fullHash string, idx int, partial bool, parent int) tea.Cmd {
 	return func() tea.Msg {
-		var stat, body string
-
-		cmd := exec.Command("git", "show", "--format=", "--stat", "--no-color", fullHash)
-		cmd.Dir = repoPath
-		if out, err := cmd.Output(); err == nil {
-			stat = strings.TrimSpace(string(out))
+		var body string
+		var env []string
+		if partial {
+			env = remoteEnv(repoPath)
+		}
+		diff := func(args ...string) *exec.Cmd {
+			if parent > 0 {
+				args = append(append([]string{"diff"}, args...), fmt.Sprintf("%s^%d", fullHash, parent), fullHash)
+			} else {
+				args = append(append([]string{"show", "--format="}, args...), fullHash)
+			}
+			cmd := gitCommand(repoPath, args...)
+			if env != nil {
+				cmd.Env = append(cmd.Environ(), env...)
+			}
+			return cmd
 		}
 
-		cmd = exec.Command("git", "show", "--format=", "--no-color", "-p", fullHash)
-		cmd.Dir = repoPath
-		if out, err := cmd.Output(); err == nil {
-			diff := string(out)
+		if out, err := diff("--no-color", "-p").Output(); err != nil && partial {
+			var stderr string
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				stderr = strings.TrimSpace(string(exitErr.Stderr))
+			}
+			body = "Couldn't fetch the objects this diff needs from the promisor remote:\n" + stderr
+		} else if err == nil {
+			diff := summarizeLFSDiff(string(out))
 			diffLines := strings.Split(diff, "\n")
-			if len(diffLines) > 300 {
-				diffLines = diffLines[:300]
+			// Long files are collapsed in the Diff tab, so it can hold
+			// a fair part of a large commit
+			if len(diffLines) > 2000 {
+				diffLines = diffLines[:2000]
 				diffLines = append(diffLines, "... (truncated)")
 			}
 			body = strings.Join(diffLines, "\n")
 		}
 
-		return diffLoadedMsg{commitIdx: idx, diffStat: stat, diffBody: body}
+		var files []fileChange
+		if out, err := diff("--no-color", "--name-status", "-M").Output(); err == nil {
+			files = parseNameStatus(string(out))
+		}
+		if out, err := diff("--no-color", "--numstat", "-M", "-z").Output(); err == nil {
+			addNumstat(files, string(out))
+		}
+
+		var message string
+		if out, err := gitCommand(repoPath, "show", "-s", "--format=%b", fullHash).Output(); err == nil {
+			message = string(out)
+		}
+
+		describe, _ := gitRead(repoPath, "describe", "--tags", fullHash)
+
+		return diffLoadedMsg{commitIdx: idx, parent: parent, diffBody: body, files: files, describe: describe, body: message}
 	}
 }
 
-func (m *model) maybeLoadDiff() tea.Cmd {
-	if m.selected >= 0 && m.selected < len(m.commits) && !m.commits[m.selected].DiffLoaded {
-		return loadDiffCmd(m.repoPath, m.commits[m.selected].FullHash, m.selected)
+type containsLoadedMsg struct {
+	commitIdx int
+	branches  []string
+	tags      []string
+}
+
+// loadContainsCmd lists the branches (local and remote) and tags that
+// contain the commit. This walks a lot of history in big repos, so it only
+// runs while the Refs tab is open.
+func loadContainsCmd(repoPath string, fullHash string, idx int) tea.Cmd {
+	return func() tea.Msg {
+		var branches, tags []string
+
+		if out, err := gitRead(repoPath, "branch", "-a", "--contains", fullHash, "--format=%(refname:short)"); err == nil {
+			branches = strings.Fields(out)
+		}
+
+		if out, err := gitRead(repoPath, "tag", "--contains", fullHash); err == nil {
+			tags = strings.Fields(out)
+			sortTags(tags)
+		}
+
+		return containsLoadedMsg{commitIdx: idx, branches: branches, tags: tags}
+	}
+}
+
+func (m *model) maybeLoadContains() tea.Cmd {
+	if m.detailTab == tabRefs && m.selected >= 0 && m.selected < len(m.commits) && !m.commits[m.selected].ContainsLoaded {
+		return loadContainsCmd(m.repoPath, m.commits[m.selected].FullHash.String(), m.selected)
 	}
 	return nil
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+// maybeLoadDetails starts loading whatever the details panel needs for the
+// selected commit and does not have yet.
+func (m *model) maybeLoadDetails() tea.Cmd {
+	return tea.Batch(m.maybeLoadDiff(), m.maybeLoadContains())
+}
+
+func (m *model) maybeLoadDiff() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	if c.DiffLoaded {
+		// Kept for longer than diffs selected before it
+		m.touchDiff(c.FullHash)
+		return nil
+	}
+	if m.backend == backendGoGit && m.repo != nil {
+		return loadDiffGoGit(m.repo, c.FullHash.String(), m.selected, c.DiffParent)
+	}
+	return loadDiffCmd(m.repoPath, c.FullHash.String(), m.selected, m.checkout.partial != "", c.DiffParent)
+}
+
+func (m model) Update(msg tea.Msg) (_ tea.Model, next tea.Cmd) {
+	defer func() {
+		// Bubble Tea recovers the panic and restores the terminal; write
+		// the report while the model is still at hand
+		if r := recover(); r != nil {
+			reportCrash(r, m.summary())
+			panic(r)
+		}
+		next = guardCmd(next)
+	}()
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.status = ""
+		if m.err != nil {
+			return m, m.updateError(msg)
+		}
+		if cmd, handled := m.updateOverlay(msg); handled {
+			return m, cmd
+		}
+		count, consumed := m.parseCount(msg)
+		if consumed {
+			return m, nil
+		}
+		if m.present && presentBlocked[msg.String()] {
+			m.status, m.statusErr = "Not available in presentation mode", true
+			return m, nil
+		}
+
 		switch msg.String() {
-		case "q", "ctrl+c", "esc":
+		case "esc":
+			if m.workTree {
+				m.workTree = false
+				m.dataVersion++
+				return m, nil
+			}
+			return m, tea.Quit
+		case "q", "ctrl+c":
 			return m, tea.Quit
+		case "?":
+			m.menu = &menu{title: "Keys (also gitraffe help keys)", detail: keysOverlay()}
+			return m, nil
+		case "P":
+			return m, loadPushInfo(m.repoPath)
+		case "L":
+			m.status = "Scanning the history for large files…"
+			return m, loadLargeBlobs(m.repoPath)
+		case "p":
+			return m, loadPullInfo(m.repoPath)
+		case "ctrl+f":
+			m.openFinder()
+			return m, nil
+		case "f":
+			m.menu = m.filterMenu()
+			return m, nil
+		case "B":
+			if m.focusedBox == 2 && m.detailTab == tabDiff && !m.workTree && m.ready {
+				return m, m.blameTopHunk()
+			}
+			m.menu = m.stackMenu()
+			return m, nil
+		case "b":
+			return m, loadBranches(m.repoPath)
+		case "O":
+			return m, m.openPR()
+		case "n":
+			return m, m.editNote()
+		case "I":
+			m.showImpact = !m.showImpact
+			m.dataVersion++
+			return m, m.maybeLoadImpact()
+		case "T":
+			m.status = "Counting the commits of each release…"
+			return m, loadReleases(m.repoPath, m.tagGlob)
+		case "/":
+			return m, m.grepSelected()
+		case "t":
+			return m, m.browseTree()
+		case "!":
+			return m, m.runCommand()
+		case "e":
+			m.menu = m.editMenu()
+			return m, nil
+		case "A":
+			return m, m.loadActivity()
+		case "H":
+			m.showMinimap = !m.showMinimap
+			m.dataVersion++
+			return m, nil
+		case "M":
+			return m, m.toggleMark()
+		case "'":
+			return m, m.jumpToMergeBase()
+		case "D":
+			return m, m.toggleCopies()
+		case "=":
+			return m, m.nextCopy()
+		case "X":
+			m.menu = m.exportMenu()
+			return m, nil
+		case "R":
+			// Every git command gitraffe runs from now on gets this, so
+			// the details and diffs follow the graph
+			m.noReplace = !m.noReplace
+			noReplaceObjects.Store(m.noReplace)
+			switch {
+			case m.loadedWith == "go-git":
+				m.status = "Replace refs only apply to the git CLI backend; go-git always shows the original history"
+			case m.noReplace:
+				m.status = "Ignoring replace refs, showing the original history"
+			default:
+				m.status = "Showing replaced commits"
+			}
+			return m, m.reload()
+		case "w":
+			m.workTree = !m.workTree
+			m.dataVersion++
+			return m, m.maybeLoadWorkTreeDiff(true)
 		case "0":
-			m.focusedBox = 0
+			m.focus(0)
 			return m, nil
 		case "1":
-			m.focusedBox = 1
+			m.focus(1)
 			return m, nil
 		case "2":
-			m.focusedBox = 2
+			m.focus(2)
+			return m, nil
+		case "`":
+			m.focus(m.lastFocus)
+			return m, nil
+		case "z":
+			m.zoomed = !m.zoomed
+			return m, nil
+		case "tab":
+			m.detailTab = (m.detailTab + 1) % numDetailTabs
+			m.link = 0
+			return m, m.maybeLoadDetails()
+		case "shift+tab":
+			m.detailTab = (m.detailTab + numDetailTabs - 1) % numDetailTabs
+			m.link = 0
+			return m, m.maybeLoadDetails()
+		case "alt+1", "alt+2", "alt+3", "alt+4":
+			// Plain digits focus panels and start counts, so the tabs
+			// take alt
+			m.detailTab = int(msg.String()[len("alt+")] - '1')
+			m.link = 0
+			return m, m.maybeLoadDetails()
+		}
+
+		if m.workTree {
+			if cmd, handled := m.updateWorkTree(msg); handled {
+				return m, cmd
+			}
 			return m, nil
 		}
 
 		// Handle scrolling within the focused box
 		if m.ready && len(m.commits) > 0 {
+			switch msg.String() {
+			case "ctrl+o":
+				return m, m.jumpBack(false)
+			case "ctrl+n":
+				return m, m.jumpBack(true)
+			}
 			switch m.focusedBox {
 			case 1: // commit list / graph
+				n := max(count, 1)
 				switch msg.String() {
 				case "j", "down":
-					if m.selected < len(m.commits)-1 {
-						m.selected++
-						m.detailsScroll = 0
-					}
-					return m, m.maybeLoadDiff()
+					return m, m.selectCommit(m.selected + n)
 				case "k", "up":
-					if m.selected > 0 {
-						m.selected--
-						m.detailsScroll = 0
-					}
-					return m, m.maybeLoadDiff()
+					return m, m.selectCommit(m.selected - n)
 				case "d", "ctrl+d":
-					m.selected += 10
-					if m.selected >= len(m.commits) {
-						m.selected = len(m.commits) - 1
-					}
-					m.detailsScroll = 0
-					return m, m.maybeLoadDiff()
+					return m, m.selectCommit(m.selected + 10*n)
 				case "u", "ctrl+u":
-					m.selected -= 10
-					if m.selected < 0 {
-						m.selected = 0
-					}
-					m.detailsScroll = 0
-					return m, m.maybeLoadDiff()
+					return m, m.selectCommit(m.selected - 10*n)
 				case "g", "home":
-					m.selected = 0
-					m.detailsScroll = 0
-					return m, m.maybeLoadDiff()
+					return m, m.jumpTo(0)
 				case "G", "end":
-					m.selected = len(m.commits) - 1
-					m.detailsScroll = 0
-					return m, m.maybeLoadDiff()
+					// With a count, the n-th commit, as vim goes to a line
+					if count > 0 {
+						return m, m.jumpTo(count - 1)
+					}
+					return m, m.jumpTo(len(m.commits) - 1)
+				case "]":
+					return m, m.nextMerge(n)
+				case "[":
+					return m, m.nextMerge(-n)
+				case "v":
+					m.review.toggleCommit(m.commits[m.selected])
+					m.saveReview()
+					return m, nil
+				case "V":
+					return m, m.nextUnreviewed()
 				}
 			case 2: // commit details
+				if m.detailTab == tabFiles && m.updateFilesTab(msg) {
+					return m, nil
+				}
+				if m.detailTab == tabDiff && m.updateDiffTab(msg) {
+					return m, nil
+				}
+				if cmd, handled := m.updateLinks(msg); handled {
+					return m, cmd
+				}
+				n := max(count, 1)
 				switch msg.String() {
 				case "j", "down":
-					m.detailsScroll++
+					m.detailsScroll[m.detailTab] += n
 					return m, nil
 				case "k", "up":
-					if m.detailsScroll > 0 {
-						m.detailsScroll--
-					}
+					m.detailsScroll[m.detailTab] = max(m.detailsScroll[m.detailTab]-n, 0)
 					return m, nil
 				case "d", "ctrl+d":
-					m.detailsScroll += 10
+					m.detailsScroll[m.detailTab] += 10 * n
 					return m, nil
 				case "u", "ctrl+u":
-					m.detailsScroll -= 10
-					if m.detailsScroll < 0 {
-						m.detailsScroll = 0
-					}
+					m.detailsScroll[m.detailTab] = max(m.detailsScroll[m.detailTab]-10*n, 0)
 					return m, nil
 				case "g", "home":
-					m.detailsScroll = 0
+					m.detailsScroll[m.detailTab] = 0
+					return m, nil
+				case "c":
+					if m.detailTab == tabRefs {
+						m.containsAll = !m.containsAll
+					}
+					return m, nil
+				case "v":
+					m.review.toggleCommit(m.commits[m.selected])
+					m.saveReview()
+					return m, nil
+				case "m":
+					// Step through the parents of a merge, then back to
+					// the combined diff
+					if m.selected < 0 || m.selected >= len(m.commits) {
+						return m, nil
+					}
+					if c := &m.commits[m.selected]; len(c.Parents) > 1 {
+						c.DiffParent = (c.DiffParent + 1) % (len(c.Parents) + 1)
+						c.DiffLoaded = false
+						m.detailsScroll[tabDiff] = 0
+						m.dataVersion++
+						return m, m.maybeLoadDiff()
+					}
 					return m, nil
 				}
 			}
 		}
 
+	case tea.MouseMsg:
+		return m, m.clickMinimap(msg)
+
 	case tea.WindowSizeMsg:
 		m.windowWidth = msg.Width
 		m.windowHeight = msg.Height
 
 	case repoMsg:
 		m.repo = msg.repo
-		log.Println("Repository opened successfully with go-git")
-		m.loadRepoInfo()
-
-		if err := m.loadGraphData(); err != nil {
-			log.Printf("Graph loading failed: %v, trying simple load...\n", err)
-			commits, err2 := m.loadCommitsFromGitCLI()
-			if err2 != nil {
-				m.err = fmt.Errorf("graph: %v, fallback: %v", err, err2)
-				m.ready = true
-				return m, nil
-			}
-			m.commits = commits
+		if m.repo != nil {
+			log.Println("Repository opened successfully with go-git")
 		}
-		m.ready = true
-		m.selected = 0
-		return m, m.maybeLoadDiff()
+		return m, m.opened(nil)
 
 	case errMsg:
 		log.Printf("Error from go-git: %v\n", msg.err)
-		m.loadRepoInfoFromCLI()
+		return m, m.opened(msg.err)
 
-		if err := m.loadGraphData(); err != nil {
-			log.Printf("Graph loading failed: %v, trying simple load...\n", err)
-			commits, err2 := m.loadCommitsFromGitCLI()
-			if err2 != nil {
-				m.err = fmt.Errorf("%v (graph: %v, fallback: %v)", msg.err, err, err2)
-				m.ready = true
-				return m, nil
+	case diffLoadedMsg:
+		if msg.commitIdx >= 0 && msg.commitIdx < len(m.commits) && msg.parent == m.commits[msg.commitIdx].DiffParent {
+			m.commits[msg.commitIdx].DiffLoaded = true
+			m.commits[msg.commitIdx].DiffBody = msg.diffBody
+			m.commits[msg.commitIdx].Files = msg.files
+			m.commits[msg.commitIdx].Describe = msg.describe
+			m.commits[msg.commitIdx].Body, m.commits[msg.commitIdx].Trailers = parseTrailers(validUTF8(msg.body, m.cfg.CommitEncoding))
+			m.touchDiff(m.commits[msg.commitIdx].FullHash)
+			m.trimDiffs()
+			m.dataVersion++
+		}
+		return m, nil
+
+	case tickMsg:
+		m.now = time.Time(msg)
+		return m, tea.Batch(loadWorkTreeStatus(m.repoPath), tick())
+
+	case workTreeMsg:
+		m.wtFiles = msg.files
+		if m.wtSelected >= len(m.wtFiles) {
+			m.wtSelected = len(m.wtFiles) - 1
+		}
+		if m.wtSelected < 0 {
+			m.wtSelected = 0
+		}
+		// Forget marks on files that are no longer changed
+		present := make(map[string]bool, len(m.wtFiles))
+		for _, f := range m.wtFiles {
+			present[f.Path] = true
+		}
+		for path := range m.wtMarked {
+			if !present[path] {
+				delete(m.wtMarked, path)
 			}
-			m.commits = commits
 		}
-		m.ready = true
-		m.selected = 0
-		return m, m.maybeLoadDiff()
+		m.dataVersion++
+		return m, m.maybeLoadWorkTreeDiff(true)
 
-	case diffLoadedMsg:
+	case pushInfoMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't push: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = m.pushMenu(upstreamInfo(msg))
+		return m, nil
+
+	case fetchTickMsg:
+		return m, backgroundFetch(m.repoPath)
+
+	case fetchDoneMsg:
+		next := scheduleFetch(m.cfg.FetchInterval)
+		if msg.err != nil || !msg.moved {
+			return m, next
+		}
+		return m, tea.Batch(m.reloadInBackground(), next)
+
+	case reloadedMsg:
+		return m, m.handleReloaded(msg)
+
+	case trackingMsg:
+		m.tracking = msg
+		m.fresh = map[string]bool{}
+		for _, hash := range msg.fresh {
+			m.fresh[hash] = true
+		}
+		m.dataVersion++
+		return m, nil
+
+	case prInfoMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't open a pull request: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = prMenu(prInfo(msg))
+		return m, nil
+
+	case branchesMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't list the branches: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = branchesMenu(msg.branches, m.cfg)
+		return m, nil
+
+	case reflogMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't read the reflog of "+msg.branch+": "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = m.reflogMenu(msg)
+		return m, nil
+
+	case reflogAtMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = fmt.Sprintf("Can't tell where %s was at %q: %v", msg.branch, msg.when, msg.err), true
+			return m, nil
+		}
+		m.menu = m.reflogEntryMenu(msg.branch, "@{"+msg.when+"}", msg.entry)
+		return m, nil
+
+	case cleanupMsg:
+		m.status = ""
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't look for merged branches: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = cleanupMenu(msg)
+		return m, nil
+
+	case prDoneMsg:
+		return m, m.handlePRDone(msg)
+
+	case followMsg:
+		return m, m.handleFollow(msg)
+
+	case impactMsg:
+		return m, m.handleImpact(msg)
+
+	case patchIDMsg:
+		return m, m.handlePatchIDs(msg)
+
+	case releasesMsg:
+		m.handleReleases(msg)
+		return m, nil
+
+	case mergeBaseMsg:
+		m.handleMergeBase(msg)
+		return m, nil
+
+	case grepMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Grep failed: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.status = ""
+		m.grep = msg.view
+		return m, nil
+
+	case treeMsg:
+		return m, m.handleTree(msg)
+
+	case shellMsg:
+		return m, m.handleShell(msg)
+
+	case treeLastMsg:
+		m.handleTreeLast(msg)
+		return m, nil
+
+	case compareMsg:
+		m.handleCompare(msg)
+		return m, nil
+
+	case fileViewMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't open the file: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.status = ""
+		m.fileView = msg.view
+		return m, nil
+
+	case blameMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Blame failed: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = m.blameMenu(msg)
+		return m, nil
+
+	case linkTargetMsg:
+		return m, m.handleLinkTarget(msg)
+
+	case urlOpenedMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't open "+msg.url+": "+msg.err.Error(), true
+		}
+		return m, nil
+
+	case activityMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Counting commits by day failed: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = m.activityMenu(msg.activity, "")
+		return m, nil
+
+	case exportDoneMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Export failed: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.status, m.statusErr = "Exported to "+msg.path, false
+		return m, nil
+
+	case stackMsg:
+		if msg.base != m.base {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.status, m.statusErr = "Comparing branches with the base failed: "+msg.err.Error(), true
+			m.setStack(nil)
+			return m, nil
+		}
+		m.setStack(msg.branches)
+		return m, nil
+
+	case largeBlobsMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Scanning for large files failed: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.status = ""
+		m.menu = largeBlobsMenu(msg.blobs)
+		return m, nil
+
+	case pullInfoMsg:
+		if msg.err != nil {
+			m.status, m.statusErr = "Can't pull: "+msg.err.Error(), true
+			return m, nil
+		}
+		m.menu = m.pullMenu(msg)
+		return m, nil
+
+	case pullDoneMsg:
+		if msg.err == nil {
+			m.incoming = map[string]bool{}
+			for _, hash := range msg.incoming {
+				m.incoming[hash] = true
+			}
+		}
+		return m, m.handleGitDone(msg.gitDoneMsg)
+
+	case commitTemplatesMsg:
+		return m, m.startCommit(msg)
+
+	case rewriteInfoMsg:
+		m.handleRewriteInfo(msg)
+		return m, nil
+
+	case headMessageMsg:
+		if m.commit != nil && m.commit.amend && strings.TrimSpace(m.commit.input.Value()) == "" {
+			m.commit.input.SetValue(string(msg))
+		}
+		return m, nil
+
+	case workTreeDiffMsg:
+		m.wtDiff = workTreeDiff(msg)
+		if m.wtHunk >= len(m.wtDiff.hunks) {
+			m.wtHunk = len(m.wtDiff.hunks) - 1
+		}
+		if m.wtHunk < 0 {
+			m.wtHunk = 0
+		}
+		m.dataVersion++
+		return m, nil
+
+	case gitDoneMsg:
+		return m, m.handleGitDone(msg)
+
+	case containsLoadedMsg:
 		if msg.commitIdx >= 0 && msg.commitIdx < len(m.commits) {
-			m.commits[msg.commitIdx].DiffLoaded = true
-			m.commits[msg.commitIdx].DiffStat = msg.diffStat
-			m.commits[msg.commitIdx].DiffBody = msg.diffBody
+			m.commits[msg.commitIdx].ContainsLoaded = true
+			m.commits[msg.commitIdx].ContainsBranches = msg.branches
+			m.commits[msg.commitIdx].ContainsTags = msg.tags
+			m.dataVersion++
 		}
 		return m, nil
 	}
@@ -291,16 +1016,82 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m *model) loadRepoInfo() {
-	// Get repository name from path
-	m.repoName = m.repoPath
-	if m.repoPath == "." {
-		if wd, err := os.Getwd(); err == nil {
-			m.repoName = wd[strings.LastIndex(wd, string(os.PathSeparator))+1:]
+// reload re-reads the repository after an action changed it, keeping the
+// selected commit when it still exists.
+func (m *model) reload() tea.Cmd {
+	selectedHash := ""
+	if m.selected >= 0 && m.selected < len(m.commits) {
+		selectedHash = m.commits[m.selected].FullHash.String()
+	}
+
+	m.loadRepoInfoFromCLI()
+	if err := m.loadHistory(); err != nil {
+		log.Printf("Reload failed, keeping the previous graph: %v\n", err)
+	}
+	return m.reselect(selectedHash)
+}
+
+// reloadedMsg is a copy of the model that loaded the repository again in
+// the background.
+type reloadedMsg struct {
+	loaded *model
+}
+
+// reloadInBackground is reload for a change nobody is waiting on, like a
+// background fetch: the repository is read again by a copy of the model
+// in a tea.Cmd, so the UI doesn't stall on it.
+func (m *model) reloadInBackground() tea.Cmd {
+	loaded := *m
+	loaded.prof = nil
+	return func() tea.Msg {
+		loaded.loadRepoInfoFromCLI()
+		if err := loaded.loadHistory(); err != nil {
+			log.Printf("Reload failed, keeping the previous graph: %v\n", err)
+			return nil
 		}
-	} else {
-		m.repoName = m.repoPath[strings.LastIndex(m.repoPath, string(os.PathSeparator))+1:]
+		return reloadedMsg{loaded: &loaded}
+	}
+}
+
+// handleReloaded takes the repository info and history a background
+// reload found.
+func (m *model) handleReloaded(msg reloadedMsg) tea.Cmd {
+	selectedHash := ""
+	if m.selected >= 0 && m.selected < len(m.commits) {
+		selectedHash = m.commits[m.selected].FullHash.String()
 	}
+	l := msg.loaded
+	m.repoName, m.remotes, m.checkout, m.currentBranch, m.currentCommit, m.unborn = l.repoName, l.remotes, l.checkout, l.currentBranch, l.currentCommit, l.unborn
+	m.commits, m.displayRows, m.loadedWith, m.coAuthored, m.matches = l.commits, l.displayRows, l.loadedWith, l.coAuthored, l.matches
+	m.maxGraphWidth, m.rewritten, m.diffOrder, m.hashWidth = l.maxGraphWidth, l.rewritten, l.diffOrder, l.hashWidth
+	return m.reselect(selectedHash)
+}
+
+// commitIndex is the index of the commit with a full hash in the graph,
+// -1 when it isn't there.
+func (m *model) commitIndex(hash string) int {
+	id := plumbing.NewHash(hash)
+	for i := range m.commits {
+		if m.commits[i].FullHash == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// reselect selects the commit that was selected before a reload, when it
+// is still there, and loads what goes with the new history.
+func (m *model) reselect(selectedHash string) tea.Cmd {
+	m.selected = max(m.commitIndex(selectedHash), 0)
+	m.dataVersion++
+	return tea.Batch(loadWorkTreeStatus(m.repoPath), loadTracking(m.repoPath), loadStack(m.repoPath, m.base), m.maybeLoadDetails(), m.maybeLoadWorkTreeDiff(true), m.maybeLoadImpact(), m.maybeLoadPatchIDs())
+}
+
+func (m *model) loadRepoInfo() {
+	m.repoName = repoDisplayName(m.repoPath)
+
+	m.remotes = loadRemotes(m.repoPath)
+	m.checkout = loadCheckoutInfo(m.repoPath)
 
 	// Get current branch and commit
 	if m.repo != nil {
@@ -312,8 +1103,13 @@ func (m *model) loadRepoInfo() {
 				m.currentBranch = "HEAD (detached)"
 			}
 			// Get commit hash
-			m.currentCommit = ref.Hash().String()[:7]
+			if short, err := shortHEAD(m.repoPath); err == nil {
+				m.currentCommit = short
+			} else {
+				m.currentCommit = ref.Hash().String()[:7]
+			}
 		}
+		m.detectUnborn()
 	} else {
 		// Use CLI to get branch and commit info
 		m.loadRepoInfoFromCLI()
@@ -321,58 +1117,63 @@ func (m *model) loadRepoInfo() {
 }
 
 func (m *model) loadRepoInfoFromCLI() {
-	// Get repository name from path
-	m.repoName = m.repoPath
-	if m.repoPath == "." {
-		if wd, err := os.Getwd(); err == nil {
-			m.repoName = wd[strings.LastIndex(wd, string(os.PathSeparator))+1:]
-		}
-	} else {
-		m.repoName = m.repoPath[strings.LastIndex(m.repoPath, string(os.PathSeparator))+1:]
-	}
+	m.repoName = repoDisplayName(m.repoPath)
+
+	m.remotes = loadRemotes(m.repoPath)
+	m.checkout = loadCheckoutInfo(m.repoPath)
 
 	// Get current branch
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	cmd.Dir = m.repoPath
-	if out, err := cmd.Output(); err == nil {
-		m.currentBranch = strings.TrimSpace(string(out))
+	if out, err := gitRead(m.repoPath, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		m.currentBranch = out
 	} else {
 		m.currentBranch = "unknown"
 	}
 
 	// Get current commit
-	cmd = exec.Command("git", "rev-parse", "--short=7", "HEAD")
-	cmd.Dir = m.repoPath
-	if out, err := cmd.Output(); err == nil {
-		m.currentCommit = strings.TrimSpace(string(out))
+	if short, err := shortHEAD(m.repoPath); err == nil {
+		m.currentCommit = short
 	} else {
 		m.currentCommit = "unknown"
 	}
+	m.detectUnborn()
 }
 
-func (m *model) loadCommits() ([]commit, error) {
-	const maxCommits = 5000 // Limit for large repos
-
-	log.Println("Loading commits...")
-	ref, err := m.repo.Head()
+// detectUnborn notices a HEAD that points at a branch with no commits,
+// which is where a freshly initialized repository starts.
+func (m *model) detectUnborn() {
+	if _, err := gitRead(m.repoPath, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		m.unborn = false
+		return
+	}
+	out, err := gitRead(m.repoPath, "symbolic-ref", "--quiet", "--short", "HEAD")
 	if err != nil {
-		log.Printf("Error getting HEAD: %v\n", err)
-		return nil, err
+		return
 	}
+	m.unborn = true
+	m.currentBranch = out
+	m.currentCommit = ""
+}
 
-	commitIter, err := m.repo.Log(&git.LogOptions{
-		From: ref.Hash(),
-	})
+// loadRemotes returns the names of the configured remotes.
+func loadRemotes(repoPath string) []string {
+	out, err := gitRead(repoPath, "remote")
 	if err != nil {
-		log.Printf("Error getting commit log: %v\n", err)
-		return nil, err
+		return nil
 	}
+	return strings.Fields(out)
+}
+
+func (m *model) loadCommits() ([]commit, error) {
+	const maxCommits = 5000 // Limit for large repos
+
+	log.Println("Loading commits...")
 
 	var commits []commit
-	commitMap := make(map[string]*commit)
+	var parentHashes [][]string // full hashes, abbreviated once all are known
 	count := 0
+	in := interner{}
 
-	err = commitIter.ForEach(func(c *object.Commit) error {
+	err := m.goGitWalk(func(c *object.Commit) error {
 		count++
 		if count > maxCommits {
 			log.Printf("Reached maximum commit limit (%d), stopping...\n", maxCommits)
@@ -381,20 +1182,25 @@ func (m *model) loadCommits() ([]commit, error) {
 
 		parents := make([]string, len(c.ParentHashes))
 		for i, p := range c.ParentHashes {
-			parents[i] = p.String()[:7]
+			parents[i] = p.String()
 		}
+		parentHashes = append(parentHashes, parents)
 
-		fullHash := c.Hash.String()
+		// go-git gives the commit as written, in its encoding
+		encoding := string(c.Encoding)
+		subject, _, _ := strings.Cut(c.Message, "\n")
 		commit := commit{
-			Hash:     fullHash[:7],
-			FullHash: fullHash,
-			Author:   c.Author.Name,
-			Date:     c.Author.When,
-			Message:  strings.Split(c.Message, "\n")[0],
-			Parents:  parents,
+			FullHash:       c.Hash,
+			Author:         in.intern(fromEncoding(c.Author.Name, encoding)),
+			AuthorEmail:    in.intern(c.Author.Email),
+			Date:           c.Author.When,
+			Committer:      in.intern(fromEncoding(c.Committer.Name, encoding)),
+			CommitterEmail: in.intern(c.Committer.Email),
+			CommitDate:     c.Committer.When,
+			Message:        strings.Clone(fromEncoding(subject, encoding)),
 		}
+		commit.toUTF8(m.cfg.CommitEncoding)
 		commits = append(commits, commit)
-		commitMap[commit.Hash] = &commits[len(commits)-1]
 
 		if count%1000 == 0 {
 			log.Printf("Loaded %d commits...\n", count)
@@ -416,6 +1222,53 @@ func (m *model) loadCommits() ([]commit, error) {
 
 	log.Printf("Successfully loaded %d commits\n", len(commits))
 
+	refs, err := m.goGitRefs()
+	if err != nil {
+		return nil, err
+	}
+	for i := range commits {
+		commits[i].Refs = refs[commits[i].FullHash]
+	}
+
+	// go-git doesn't read .mailmap; git maps the names, when it's there
+	seen := map[identity]bool{}
+	var ids []identity
+	for i := range commits {
+		for _, id := range []identity{{commits[i].Author, commits[i].AuthorEmail}, {commits[i].Committer, commits[i].CommitterEmail}} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if mapped := mailmap(m.repoPath, ids); mapped != nil {
+		for i := range commits {
+			c := &commits[i]
+			if id, ok := mapped[identity{c.Author, c.AuthorEmail}]; ok {
+				c.Author, c.AuthorEmail = in.intern(id.name), in.intern(id.email)
+			}
+			if id, ok := mapped[identity{c.Committer, c.CommitterEmail}]; ok {
+				c.Committer, c.CommitterEmail = in.intern(id.name), in.intern(id.email)
+			}
+		}
+	}
+
+	// Abbreviated like git would, and never two hashes alike
+	var hashes []string
+	for i := range commits {
+		hashes = append(hashes, commits[i].FullHash.String())
+		hashes = append(hashes, parentHashes[i]...)
+	}
+	// Copied out of the full hashes, which aren't kept
+	short := uniqueAbbrevs(hashes, abbrevLength(m.repoPath))
+	for i := range commits {
+		commits[i].Hash = strings.Clone(short[commits[i].FullHash.String()])
+		for _, p := range parentHashes[i] {
+			commits[i].Parents = append(commits[i].Parents, in.intern(short[p]))
+		}
+	}
+	m.hashWidth = widestHash(commits)
+
 	// Generate graph lines
 	m.generateGraph(commits)
 
@@ -423,16 +1276,30 @@ func (m *model) loadCommits() ([]commit, error) {
 }
 
 func (m *model) loadCommitsFromGitCLI() ([]commit, error) {
+	return m.loadCommitList(nil)
+}
+
+// loadCommitList loads the commits of the scope without the graph, or
+// just the listed ones, newest first, when hashes isn't nil.
+func (m *model) loadCommitList(hashes []string) ([]commit, error) {
 	const maxCommits = 5000
 
 	log.Println("Using git CLI to load commits...")
 
-	// Use git log with a custom format
-	cmd := exec.Command("git", "log",
+	args := []string{"log", "-z",
 		fmt.Sprintf("-n%d", maxCommits),
-		"--pretty=format:%H|%an|%at|%s|%P",
-		"--all")
-	cmd.Dir = m.repoPath
+		"--pretty=format:" + logFormat,
+	}
+	if hashes != nil {
+		args = append(append(args, "--no-walk=sorted", "--stdin"), m.decorateArgs()...)
+	} else {
+		args = append(args, m.logScope()...)
+		args = append(append(args, "--"), m.filterPaths()...)
+	}
+	cmd := gitCommand(m.repoPath, args...)
+	if hashes != nil {
+		cmd.Stdin = strings.NewReader(strings.Join(hashes, "\n") + "\n")
+	}
 
 	var out bytes.Buffer
 	var errOut bytes.Buffer
@@ -444,59 +1311,20 @@ func (m *model) loadCommitsFromGitCLI() ([]commit, error) {
 		return nil, fmt.Errorf("git command failed: %v", err)
 	}
 
-	lines := strings.Split(out.String(), "\n")
-	commits := make([]commit, 0, len(lines))
-
-	for i, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Split(line, "|")
-		if len(parts) < 4 {
+	records, err := splitLog(out.String(), logFields)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]commit, 0, len(records))
+	in := interner{}
+	for i, fields := range records {
+		c, err := parseLogFields(fields, in)
+		if err != nil {
+			log.Printf("Skipping a commit: %v\n", err)
 			continue
 		}
-
-		fullHash := parts[0]
-		shortHash := fullHash
-		if len(shortHash) > 7 {
-			shortHash = shortHash[:7]
-		}
-
-		author := parts[1]
-
-		timestamp := parts[2]
-		var date time.Time
-		if ts, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
-			date = time.Unix(ts, 0)
-		} else {
-			log.Printf("Warning: failed to parse timestamp '%s': %v\n", timestamp, err)
-			date = time.Now()
-		}
-
-		message := parts[3]
-
-		var parents []string
-		if len(parts) > 4 && parts[4] != "" {
-			parentHashes := strings.Fields(parts[4])
-			parents = make([]string, len(parentHashes))
-			for j, p := range parentHashes {
-				if len(p) > 7 {
-					parents[j] = p[:7]
-				} else {
-					parents[j] = p
-				}
-			}
-		}
-
-		commits = append(commits, commit{
-			Hash:     shortHash,
-			FullHash: fullHash,
-			Author:   author,
-			Date:     date,
-			Message:  message,
-			Parents:  parents,
-		})
+		c.toUTF8(m.cfg.CommitEncoding)
+		commits = append(commits, c)
 
 		if (i+1)%1000 == 0 {
 			log.Printf("Loaded %d commits from git CLI...\n", i+1)
@@ -504,6 +1332,7 @@ func (m *model) loadCommitsFromGitCLI() ([]commit, error) {
 	}
 
 	log.Printf("Successfully loaded %d commits from git CLI\n", len(commits))
+	m.hashWidth = widestHash(commits)
 
 	// Generate graph lines
 	m.generateGraph(commits)
@@ -514,111 +1343,83 @@ func (m *model) loadCommitsFromGitCLI() ([]commit, error) {
 func (m *model) generateGraph(commits []commit) {
 	// Basic graph generation (fallback when git log --graph is not available)
 	for i := range commits {
-		if len(commits[i].Parents) == 0 {
-			commits[i].GraphLine = "◉ "
-		} else if len(commits[i].Parents) == 1 {
-			commits[i].GraphLine = "● "
-		} else {
-			commits[i].GraphLine = "◆ "
-		}
+		commits[i].GraphLine = m.nodeSymbol(commits[i]) + " "
 	}
 }
 
-func transliterateGraph(s string) string {
-	r := strings.NewReplacer(
-		"*", "●",
-		"|", "│",
-	)
-	return r.Replace(s)
-}
-
 func (m *model) loadGraphData() error {
 	const maxCommits = 5000
 	log.Println("Loading graph data from git CLI...")
 
-	cmd := exec.Command("git", "log",
+	args := append(graphColorArgs(m.cfg.LanePalette), "log",
 		"--graph",
-		"--all",
 		fmt.Sprintf("-n%d", maxCommits),
-		"--pretty=format:%H%x00%an%x00%at%x00%s%x00%P%x00%D",
+		"--pretty=format:"+logFormat,
 	)
-	cmd.Dir = m.repoPath
-
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
+	args = append(args, m.logScope()...)
+	// The refs end here, even when one is named like a file
+	args = append(append(args, "--"), m.filterPaths()...)
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git log --graph failed: %v (%s)", err, errOut.String())
+	logDone := m.prof.step("git log")
+	out, err := cachedGitLog(m.repoPath, args)
+	if err != nil {
+		return fmt.Errorf("git log --graph failed: %v", err)
 	}
+	logDone()
+	defer m.prof.step("graph parse")()
 
-	lines := strings.Split(out.String(), "\n")
+	lines := strings.Split(out, "\n")
 	hashPattern := regexp.MustCompile(`[0-9a-f]{40}`)
 
 	m.commits = nil
 	m.displayRows = nil
 	m.maxGraphWidth = 0
+	m.rewritten = 0
+	m.diffOrder = nil
+	// Everything kept is copied out of the output, for it to be freed.
+	// Authors and the connector rows between commits repeat a lot.
+	in := interner{}
+
+	// git draws a history that starts after another one's root commit in
+	// the same column, as if they were connected. A separator goes
+	// between them when a root was the only lane left.
+	separate := false
 
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
+		if separate {
+			m.displayRows = append(m.displayRows, displayRow{CommitIdx: -1, Separator: true})
+			separate = false
+		}
 
 		loc := hashPattern.FindStringIndex(line)
 		if loc != nil {
 			// This is a commit line
-			graphPart := line[:loc[0]]
+			graphPart, colors := splitGraphColors(line[:loc[0]])
 			dataPart := line[loc[0]:]
 
-			// Parse commit data: hash\x00author\x00timestamp\x00subject\x00parents\x00refs
-			parts := strings.SplitN(dataPart, "\x00", 6)
-			if len(parts) < 4 {
+			c, err := parseLogFields(strings.SplitN(dataPart, "\x00", logFields), in)
+			if err != nil {
+				log.Printf("Skipping a commit: %v\n", err)
 				continue
 			}
-
-			fullHash := parts[0]
-			shortHash := fullHash
-			if len(shortHash) > 7 {
-				shortHash = shortHash[:7]
-			}
-
-			author := parts[1]
-			var date time.Time
-			if ts, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
-				date = time.Unix(ts, 0)
-			}
-
-			message := parts[3]
-
-			var parents []string
-			if len(parts) > 4 && parts[4] != "" {
-				for _, p := range strings.Fields(parts[4]) {
-					if len(p) > 7 {
-						parents = append(parents, p[:7])
-					} else {
-						parents = append(parents, p)
-					}
-				}
-			}
-
-			refs := ""
-			if len(parts) > 5 {
-				refs = strings.TrimSpace(parts[5])
+			c.toUTF8(m.cfg.CommitEncoding)
+			if c.Rewritten != "" {
+				m.rewritten++
 			}
-
 			commitIdx := len(m.commits)
-			m.commits = append(m.commits, commit{
-				Hash:     shortHash,
-				FullHash: fullHash,
-				Author:   author,
-				Date:     date,
-				Message:  message,
-				Parents:  parents,
-				Refs:     refs,
-			})
-
-			graphStr := transliterateGraph(graphPart)
+			m.commits = append(m.commits, c)
+
+			// An octopus merge is drawn by git as "*-." or "*---." with
+			// one dash per extra parent, fanning out into the "|\ \" row
+			// below it
+			node := m.nodeSymbol(m.commits[commitIdx])
+			graphStr, nodeAt := m.cfg.Symbols.drawGraph(graphPart, node)
+			if len(c.Parents) == 0 {
+				separate = strings.TrimSpace(graphPart) == "*"
+			}
 			gw := len(graphPart) // ASCII width
 			if gw > m.maxGraphWidth {
 				m.maxGraphWidth = gw
@@ -626,79 +1427,182 @@ func (m *model) loadGraphData() error {
 
 			m.displayRows = append(m.displayRows, displayRow{
 				GraphChars: graphStr,
+				Node:       node,
+				NodeAt:     nodeAt,
 				CommitIdx:  commitIdx,
 				GraphWidth: gw,
+				Colors:     colors,
 			})
 		} else {
 			// Graph-only line (branch/merge connectors)
-			graphStr := transliterateGraph(line)
+			line, colors := splitGraphColors(line)
+			graphStr, _ := m.cfg.Symbols.drawGraph(line, "")
 			gw := len(line)
 			if gw > m.maxGraphWidth {
 				m.maxGraphWidth = gw
 			}
 
 			m.displayRows = append(m.displayRows, displayRow{
-				GraphChars: graphStr,
+				GraphChars: in.intern(graphStr),
 				CommitIdx:  -1,
 				GraphWidth: gw,
+				Colors:     colors,
 			})
 		}
 	}
+	colorNodes(m.displayRows)
 
+	m.hashWidth = widestHash(m.commits)
 	log.Printf("Loaded %d commits, %d display rows, max graph width: %d\n",
 		len(m.commits), len(m.displayRows), m.maxGraphWidth)
 	return nil
 }
 
 func (m *model) renderRepoInfo() string {
-	var sb strings.Builder
+	// Fit the window by dropping the title first, then shortening the
+	// labels, then wrapping onto a second line
+	width := m.windowWidth - 2 - 2 // borders (2) + padding (2)
+	title := titleStyle.Render("🦒 Gitraffe - Git Graph Viewer")
+	line := strings.Join(m.repoInfoItems(false), "  ")
+	if spacing := width - lipgloss.Width(line) - lipgloss.Width(title); spacing >= 1 {
+		return line + strings.Repeat(" ", spacing) + title
+	}
+	if lipgloss.Width(line) <= width {
+		return line
+	}
+	items := m.repoInfoItems(true)
+	if line := strings.Join(items, "  "); lipgloss.Width(line) <= width {
+		return line
+	}
+
+	var lines []string
+	line = ""
+	for _, item := range items {
+		switch {
+		case line == "":
+			line = item
+		case lipgloss.Width(line+"  "+item) <= width:
+			line += "  " + item
+		default:
+			lines = append(lines, line)
+			line = item
+		}
+	}
+	lines = append(lines, line)
+	if len(lines) > 2 {
+		// What doesn't fit in two lines is cut off
+		lines[1] = strings.Join(lines[1:], "  ")
+		lines = lines[:2]
+	}
+	clip := lipgloss.NewStyle().MaxWidth(width)
+	for i := range lines {
+		lines[i] = clip.Render(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// repoInfoItems are the parts of the repo info box, with short labels
+// for narrow windows.
+func (m *model) repoInfoItems(short bool) []string {
+	label := func(long, abbrev string, color lipgloss.Color) string {
+		if short {
+			long = abbrev
+		}
+		return lipgloss.NewStyle().Bold(true).Foreground(color).Render(long)
+	}
+	var items []string
 
 	// Repository name
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")).Render("Repository: "))
-	sb.WriteString(m.repoName)
-	sb.WriteString("  ")
+	items = append(items, label("Repository: ", "Repo: ", "#7D56F4")+m.repoName)
 
 	// Branch
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0")).Render("Branch: "))
-	sb.WriteString(branchStyle.Render(m.currentBranch))
-	sb.WriteString("  ")
+	branch := label("Branch: ", "On: ", "#88C0D0") + branchStyle.Render(m.currentBranch)
+	if t := m.tracking; t.ahead > 0 || t.behind > 0 {
+		branch += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B")).Render(fmt.Sprintf("↑%d ↓%d", t.ahead, t.behind))
+	}
+	items = append(items, branch)
 
 	// Current commit
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500")).Render("Commit: "))
-	sb.WriteString(commitHashStyle.Render(m.currentCommit))
+	if m.unborn {
+		items = append(items, label("Commit: ", "At: ", "#FFA500")+helpStyle.Render("no commits yet"))
+	} else {
+		items = append(items, label("Commit: ", "At: ", "#FFA500")+commitHashStyle.Render(m.currentCommit))
+	}
 
-	leftContent := sb.String()
+	// Range or starting ref, when the graph isn't showing all refs
+	if scope := m.scopeLabel(); scope != "" {
+		items = append(items, label("Showing: ", "Only: ", "#88C0D0")+branchStyle.Render(scope))
+	}
 
-	// Title on the right
-	title := titleStyle.Render("🦒 Gitraffe - Git Graph Viewer")
+	if m.filter != nil {
+		filter := branchStyle.Render(m.filter.name)
+		if m.highlight {
+			filter += helpStyle.Render(" (highlight)")
+		}
+		items = append(items, label("Filter: ", "Filter: ", "#88C0D0")+filter)
+	}
+
+	if m.base != "" {
+		items = append(items, stackStyle.Render("base: "+m.base))
+	}
+	if reviewed, marked := m.reviewProgress(); reviewed > 0 || marked > 0 {
+		progress := fmt.Sprintf("✓ %d/%d reviewed", reviewed, len(m.commits))
+		if marked > 0 {
+			progress += fmt.Sprintf(", %d in progress", marked)
+		}
+		items = append(items, reviewedStyle.Render(progress))
+	}
+	if progress := m.impactProgress(); progress != "" {
+		items = append(items, helpStyle.Render(progress))
+	}
+	if m.noReplace {
+		items = append(items, rewrittenStyle.Render("≠ replace refs ignored"))
+	}
 
-	// Calculate available width for content (subtract borders and padding)
-	availableWidth := m.windowWidth - 2 - 2 // borders (2) + padding (2)
-	leftWidth := lipgloss.Width(leftContent)
-	rightWidth := lipgloss.Width(title)
+	// Clones that don't have everything locally
+	if m.checkout.partial != "" {
+		items = append(items, helpStyle.Render("◐ partial clone ("+m.checkout.partial+")"))
+	}
+	if m.checkout.sparse != "" {
+		items = append(items, helpStyle.Render("◧ sparse ("+m.checkout.sparse+")"))
+	}
 
-	// Add spacing to push title to the right
-	spacing := availableWidth - leftWidth - rightWidth
-	if spacing < 1 {
-		spacing = 1
+	// Uncommitted changes
+	if len(m.wtFiles) > 0 {
+		items = append(items, lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B")).
+			Render(fmt.Sprintf("● %d uncommitted", len(m.wtFiles))))
 	}
+	return items
+}
 
-	return lipgloss.JoinHorizontal(lipgloss.Top, leftContent, strings.Repeat(" ", spacing), title)
+// hashStyle styles the hash of an unselected commit in the list, picking
+// out the commits the last pull brought in.
+func (m *model) hashStyle(c commit) lipgloss.Style {
+	if !m.filterMatch(c) {
+		return helpStyle
+	}
+	if m.incoming[c.FullHash.String()] {
+		return incomingStyle
+	}
+	return commitHashStyle
 }
 
-func (m *model) renderCommitList() string {
-	log.Printf("renderCommitList: commits=%d, displayRows=%d, selected=%d, windowHeight=%d, maxGraphWidth=%d",
-		len(m.commits), len(m.displayRows), m.selected, m.windowHeight, m.maxGraphWidth)
+// renderCommitList renders the graph rows that fit in height lines.
+func (m *model) renderCommitList(height int) string {
+	log.Printf("renderCommitList: commits=%d, displayRows=%d, selected=%d, height=%d, maxGraphWidth=%d",
+		len(m.commits), len(m.displayRows), m.selected, height, m.maxGraphWidth)
 
 	if len(m.commits) == 0 {
+		if m.unborn {
+			return "No commits yet on branch " + branchStyle.Render(m.currentBranch)
+		}
 		return "No commits found"
 	}
 
 	var sb strings.Builder
 
-	// Calculate visible range based on window height
-	// Must match the contentHeight from View(): windowHeight - 8
-	visibleHeight := m.windowHeight - 8
+	// Calculate visible range based on the panel height
+	visibleHeight := height
 	if visibleHeight < 1 {
 		visibleHeight = 1
 	}
@@ -708,6 +1612,9 @@ func (m *model) renderCommitList() string {
 	selGraphColor := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
 	selHashStyle := commitHashStyle.Background(lipgloss.Color("#3C3C3C"))
 
+	// The rows on screen, for the minimap
+	var first, last, total int
+
 	if len(m.displayRows) > 0 {
 		// Graph mode: use displayRows from git log --graph
 
@@ -737,6 +1644,7 @@ func (m *model) renderCommitList() string {
 			}
 		}
 		log.Printf("renderCommitList graph mode: startIdx=%d, endIdx=%d", startIdx, endIdx)
+		first, last, total = startIdx, endIdx, len(m.displayRows)
 
 		linesWritten := 0
 		for i := startIdx; i < endIdx; i++ {
@@ -752,6 +1660,13 @@ func (m *model) renderCommitList() string {
 				continue
 			}
 
+			if row.Separator {
+				sb.WriteString(helpStyle.Render("  " + strings.Repeat("┄", m.maxGraphWidth+1+m.hashWidth)))
+				sb.WriteString("\n")
+				linesWritten++
+				continue
+			}
+
 			// Pad graph to max width for alignment
 			padLen := m.maxGraphWidth - row.GraphWidth
 			if padLen < 0 {
@@ -760,19 +1675,36 @@ func (m *model) renderCommitList() string {
 			graphPadded := row.GraphChars + strings.Repeat(" ", padLen)
 
 			if isSel {
-				highlighted := strings.ReplaceAll(graphPadded, "●", "◉")
+				highlighted := m.selectNode(row, graphPadded)
 				sb.WriteString("> ")
 				sb.WriteString(selGraphColor.Render(highlighted))
 				sb.WriteString(" ")
-				sb.WriteString(selHashStyle.Render(m.commits[row.CommitIdx].Hash))
+				sb.WriteString(m.hyperlink(m.commitURL(m.commits[row.CommitIdx]), selHashStyle.Render(m.commits[row.CommitIdx].Hash)))
+				sb.WriteString(m.hashPad(m.commits[row.CommitIdx]))
 			} else {
 				sb.WriteString("  ")
-				sb.WriteString(graphColor.Render(graphPadded))
+				sb.WriteString(m.renderGraph(row, graphPadded))
 				if isCommit {
 					sb.WriteString(" ")
-					sb.WriteString(commitHashStyle.Render(m.commits[row.CommitIdx].Hash))
+					sb.WriteString(m.hyperlink(m.commitURL(m.commits[row.CommitIdx]), m.hashStyle(m.commits[row.CommitIdx]).Render(m.commits[row.CommitIdx].Hash)))
+					sb.WriteString(m.hashPad(m.commits[row.CommitIdx]))
 				}
 			}
+			if isCommit {
+				sb.WriteString(m.impactLabel(m.commits[row.CommitIdx]))
+				sb.WriteString(rewrittenMarker(m.commits[row.CommitIdx]))
+				sb.WriteString(m.reviewMarker(m.commits[row.CommitIdx]))
+				sb.WriteString(m.noteMarker(m.commits[row.CommitIdx]))
+				sb.WriteString(m.copyMarker(row.CommitIdx))
+				sb.WriteString(m.markMarker(m.commits[row.CommitIdx]))
+				sb.WriteString(stackStyle.Render(m.stackLabels[m.commits[row.CommitIdx].FullHash.String()]))
+			}
+			if isCommit && m.fresh[m.commits[row.CommitIdx].FullHash.String()] {
+				sb.WriteString(freshStyle.Render(" new"))
+			}
+			if isCommit && m.present {
+				sb.WriteString(m.presentLabel(m.commits[row.CommitIdx]))
+			}
 			sb.WriteString("\n")
 			linesWritten++
 		}
@@ -791,6 +1723,7 @@ func (m *model) renderCommitList() string {
 		if endIdx > len(m.commits) {
 			endIdx = len(m.commits)
 		}
+		first, last, total = startIdx, endIdx, len(m.commits)
 
 		linesWritten := 0
 		for i := startIdx; i < endIdx; i++ {
@@ -800,12 +1733,26 @@ func (m *model) renderCommitList() string {
 				sb.WriteString("> ")
 				sb.WriteString(selGraphColor.Render(c.GraphLine))
 				sb.WriteString(" ")
-				sb.WriteString(selHashStyle.Render(c.Hash))
+				sb.WriteString(m.hyperlink(m.commitURL(c), selHashStyle.Render(c.Hash)))
 			} else {
 				sb.WriteString("  ")
 				sb.WriteString(graphColor.Render(c.GraphLine))
 				sb.WriteString(" ")
-				sb.WriteString(commitHashStyle.Render(c.Hash))
+				sb.WriteString(m.hyperlink(m.commitURL(c), m.hashStyle(c).Render(c.Hash)))
+			}
+			sb.WriteString(m.hashPad(c))
+			sb.WriteString(m.impactLabel(c))
+			sb.WriteString(rewrittenMarker(c))
+			sb.WriteString(m.reviewMarker(c))
+			sb.WriteString(m.noteMarker(c))
+			sb.WriteString(m.copyMarker(i))
+			sb.WriteString(m.markMarker(c))
+			sb.WriteString(stackStyle.Render(m.stackLabels[c.FullHash.String()]))
+			if m.fresh[c.FullHash.String()] {
+				sb.WriteString(freshStyle.Render(" new"))
+			}
+			if m.present {
+				sb.WriteString(m.presentLabel(c))
 			}
 			sb.WriteString("\n")
 			linesWritten++
@@ -821,131 +1768,16 @@ func (m *model) renderCommitList() string {
 	// Panel uses Height(contentHeight) with Padding(0,1) → 0 vertical padding.
 	result := sb.String()
 	resultLines := strings.Split(result, "\n")
-	maxLines := m.windowHeight - 8
-	if maxLines < 3 {
-		maxLines = 3
-	}
+	maxLines := visibleHeight
 	if len(resultLines) > maxLines {
 		resultLines = resultLines[:maxLines]
 	}
-	return strings.Join(resultLines, "\n")
-}
-
-func (m *model) renderCommitDetails() string {
-	log.Printf("renderCommitDetails: selected=%d, len(commits)=%d", m.selected, len(m.commits))
-	if len(m.commits) == 0 || m.selected < 0 || m.selected >= len(m.commits) {
-		log.Printf("renderCommitDetails: skipping (empty or out of bounds)")
-		return ""
-	}
-
-	c := m.commits[m.selected]
-
-	var sb strings.Builder
-
-	// SHA
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500")).Render("SHA:     "))
-	sb.WriteString(commitHashStyle.Render(c.FullHash))
-	sb.WriteString("\n")
-
-	// Date
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A3BE8C")).Render("Date:    "))
-	sb.WriteString(dateStyle.Render(c.Date.Format("2006-01-02 15:04:05")))
-	sb.WriteString("\n")
-
-	// Author
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7DD3FC")).Render("Author:  "))
-	sb.WriteString(authorStyle.Render(c.Author))
-	sb.WriteString("\n")
-
-	// Parents
-	if len(c.Parents) > 0 {
-		sb.WriteString(lipgloss.NewStyle().Bold(true).Render("Parents: "))
-		sb.WriteString(strings.Join(c.Parents, ", "))
-		sb.WriteString("\n")
-	}
-
-	// Refs
-	if c.Refs != "" {
-		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0")).Render("Refs:    "))
-		sb.WriteString(branchStyle.Render(c.Refs))
-		sb.WriteString("\n")
-	}
-
-	// Commit message
-	sb.WriteString("\n")
-	sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")).Render("─── Message ───────────────────────"))
-	sb.WriteString("\n")
-	sb.WriteString(messageStyle.Render(c.Message))
-	sb.WriteString("\n")
-
-	// Diff stats
-	if c.DiffLoaded && c.DiffStat != "" {
-		sb.WriteString("\n")
-		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")).Render("─── Stats ─────────────────────────"))
-		sb.WriteString("\n")
-		sb.WriteString(c.DiffStat)
-		sb.WriteString("\n")
-	}
-
-	// Diff content
-	if c.DiffLoaded && c.DiffBody != "" {
-		sb.WriteString("\n")
-		sb.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#7D56F4")).Render("─── Diff ──────────────────────────"))
-		sb.WriteString("\n")
-
-		addStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C"))
-		delStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A"))
-		hunkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#5E81AC"))
-		diffHeaderStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#E5E9F0"))
-
-		for _, line := range strings.Split(c.DiffBody, "\n") {
-			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-				sb.WriteString(addStyle.Render(line))
-			} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-				sb.WriteString(delStyle.Render(line))
-			} else if strings.HasPrefix(line, "@@") {
-				sb.WriteString(hunkStyle.Render(line))
-			} else if strings.HasPrefix(line, "diff ") {
-				sb.WriteString(diffHeaderStyle.Render(line))
-			} else {
-				sb.WriteString(line)
-			}
-			sb.WriteString("\n")
+	if m.showMinimap {
+		for i := range resultLines {
+			resultLines[i] = minimapCell(i, maxLines, total, first, last) + resultLines[i]
 		}
-	} else if !c.DiffLoaded {
-		sb.WriteString("\n")
-		sb.WriteString(helpStyle.Render("Loading diff..."))
-		sb.WriteString("\n")
-	}
-
-	// Apply scroll offset and truncate to fit panel height.
-	// lipgloss Height() only pads short content, it does NOT clip overflow,
-	// so we must truncate here to prevent the panel from growing unbounded.
-	content := sb.String()
-	allLines := strings.Split(content, "\n")
-
-	// Clamp scroll
-	if m.detailsScroll >= len(allLines) {
-		m.detailsScroll = len(allLines) - 1
 	}
-	if m.detailsScroll < 0 {
-		m.detailsScroll = 0
-	}
-	if m.detailsScroll > 0 {
-		allLines = allLines[m.detailsScroll:]
-	}
-
-	// Truncate to available height inside the panel
-	// Panel uses Height(contentHeight) with Padding(1,2) → 2 vertical padding lines
-	maxLines := m.windowHeight - 8 - 2 // contentHeight minus vertical padding
-	if maxLines < 3 {
-		maxLines = 3
-	}
-	if len(allLines) > maxLines {
-		allLines = allLines[:maxLines]
-	}
-
-	return strings.Join(allLines, "\n")
+	return strings.Join(resultLines, "\n")
 }
 
 // addBoxLabel overlays a label like [0] onto the top-left corner of a rendered box border.
@@ -1028,8 +1860,8 @@ func addBoxLabel(rendered string, label string) string {
 func (m model) View() (result string) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("PANIC in View: %v", r)
-			result = fmt.Sprintf("\n  PANIC caught: %v\n\n  Check gitraffe.log for details.\n  Press q to quit.", r)
+			path := reportCrash(r, m.summary())
+			result = fmt.Sprintf("\n  PANIC caught: %v\n\n  Crash report: %s\n  Press q to quit.", r, path)
 		}
 	}()
 	log.Printf("View: ready=%v, err=%v, commits=%d, displayRows=%d, window=%dx%d, focused=%d",
@@ -1044,17 +1876,25 @@ func (m model) View() (result string) {
 		log.Printf("View: window too small (%dx%d), waiting for resize", m.windowWidth, m.windowHeight)
 		return "\n  Waiting for terminal size..."
 	}
+	defer m.prof.step("first frame")()
 
 	if m.err != nil {
-		errorStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF0000")).
-			Bold(true)
-		return fmt.Sprintf("\n  %s\n\n  Error: %v\n\n  Press q to quit. Check gitraffe.log for details.\n",
-			errorStyle.Render("❌ Error loading repository"),
-			m.err)
+		return m.renderError()
 	}
 
-	help := helpStyle.Render("0/1/2: focus box • ↑/↓/j/k: scroll • d/u: half page • g/G: top/bottom • q/esc: quit")
+	help := helpStyle.Render("0/1/2: focus box • ↑/↓/j/k: scroll • d/u: half page • g/G: top/bottom • tab: details tab • w: working tree • z: zoom • ?: keys • q/esc: quit")
+	if m.workTree {
+		help = helpStyle.Render("0/1/2: focus box • ↑/↓/j/k: select • space: stage/unstage • (/): hunk • x: mark file • S: stash • c: commit • w/esc: back to graph • q: quit")
+	}
+	if m.present {
+		help = helpStyle.Render("presentation mode, following HEAD • ↑/↓/j/k: scroll • d/u: half page • g/G: top/bottom • ?: keys • q/esc: quit")
+	}
+	if m.commit != nil {
+		help = helpStyle.Render("ctrl+s: commit • alt+a: amend • alt+s: signoff • esc: close (keeps the message)")
+		if m.commit.reword != nil {
+			help = helpStyle.Render("ctrl+s: reword • esc: cancel")
+		}
+	}
 
 	// Border colors: orange for focused, purple for unfocused
 	focusedBorderColor := lipgloss.Color("#FFA500")
@@ -1071,18 +1911,21 @@ func (m model) View() (result string) {
 		box2Border = focusedBorderColor
 	}
 
-	// Create repo info box - fixed Height(1) so it never changes size
-	repoInfoContent := m.renderRepoInfo()
-	repoInfoBox := addBoxLabel(lipgloss.NewStyle().
-		Width(m.windowWidth-2).
-		Height(1).
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(box0Border).
-		Padding(0, 1).
-		Render(repoInfoContent), "[0]")
+	// Create repo info box - one line, or two in a narrow window
+	reviewed, marked := m.reviewProgress()
+	repoInfoKey := fmt.Sprintf("%d|%s|%s|%s|%s|%d|%d|%d|%v|%v|%s|%s|%d|%d|%d|%s|%s|%v", m.windowWidth, box0Border, m.repoName, m.currentBranch, m.currentCommit, len(m.wtFiles), m.tracking.ahead, m.tracking.behind, m.checkout, m.noReplace, m.filterName(), m.base, reviewed, marked, len(m.commits), m.impactProgress(), m.scopeLabel(), m.highlight)
+	repoInfoBox := m.cache.repoInfo.get(repoInfoKey, func() string {
+		return addBoxLabel(lipgloss.NewStyle().
+			Width(m.windowWidth-2).
+			Height(1).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(box0Border).
+			Padding(0, 1).
+			Render(m.renderRepoInfo()), "[0]")
+	})
 
 	// Calculate dimensions based on actual rendered box 0 height
-	repoInfoHeight := lipgloss.Height(repoInfoBox) // should be 3 (1 content + 2 border)
+	repoInfoHeight := lipgloss.Height(repoInfoBox) // 3 (1 content + 2 border), or 4 when wrapped
 	// Layout: repoInfoBox + \n + content panels (contentHeight + 2 border) + \n + help
 	// Total = repoInfoHeight + 1 + contentHeight + 2 + 1 + 1 = repoInfoHeight + contentHeight + 5
 	contentHeight := m.windowHeight - repoInfoHeight - 5
@@ -1091,9 +1934,103 @@ func (m model) View() (result string) {
 		contentHeight = 3
 	}
 
+	// A single panel is shown when zoomed, or when the window is too small
+	// to stack both; 1/2 then switch which one is visible.
+	singlePanel := m.zoomed || (m.windowWidth < narrowWidth && contentHeight-2 < 2*minStackedHeight)
+	// A menu's detail, like the key help, gets the whole window
+	overlay := m.menu != nil && m.menu.detail != "" || m.finder != nil || m.releases != nil ||
+		m.grep != nil || m.tree != nil || m.fileView != nil || m.compare != nil || m.shell != nil
+
+	var content string
+	switch {
+	case m.present && !overlay:
+		content = m.renderListPanel(m.windowWidth, contentHeight, box1Border)
+	case overlay || (singlePanel && m.focusedBox == 2):
+		content = m.renderDetailsPanel(m.windowWidth, contentHeight, box2Border)
+	case singlePanel:
+		content = m.renderListPanel(m.windowWidth, contentHeight, box1Border)
+	case m.windowWidth >= narrowWidth:
+		content = m.renderSideBySide(contentHeight, box1Border, box2Border)
+	default:
+		// Too narrow for two columns: stack the list above the details.
+		// Two panels need 4 border lines where one needs 2, hence the -2.
+		listHeight := (contentHeight - 2) * 2 / 5
+		if listHeight < minStackedHeight {
+			listHeight = minStackedHeight
+		}
+		content = lipgloss.JoinVertical(lipgloss.Left,
+			m.renderListPanel(m.windowWidth, listHeight, box1Border),
+			m.renderDetailsPanel(m.windowWidth, contentHeight-2-listHeight, box2Border))
+	}
+
+	// Where the list's minimap is, for clicks: past the border and
+	// padding of the list panel, when the panel is on screen
+	m.cache.minimap = minimapArea{}
+	if m.showMinimap && !m.workTree && !overlay && (m.present || !singlePanel || m.focusedBox != 2) {
+		padV, padH := 0, 1
+		if m.present {
+			padV, padH = 1, 3
+		}
+		listHeight := contentHeight
+		if !m.present && !singlePanel && m.windowWidth < narrowWidth {
+			listHeight = max((contentHeight-2)*2/5, minStackedHeight)
+		}
+		m.cache.minimap = minimapArea{x: 1 + padH, y: repoInfoHeight + 1 + padV, height: listHeight - 2*padV}
+	}
+
+	help = lipgloss.NewStyle().MaxWidth(m.windowWidth).Render(m.renderStatusLine(help))
+	output := fmt.Sprintf("%s\n%s\n%s", repoInfoBox, content, help)
+
+	// Force exact windowHeight lines. We count lines via lipgloss.Height which
+	// correctly handles ANSI escape sequences, then trim or pad as needed.
+	actualHeight := lipgloss.Height(output)
+	log.Printf("View: actualHeight=%d, windowHeight=%d", actualHeight, m.windowHeight)
+
+	if actualHeight > m.windowHeight {
+		// Trim from the bottom
+		lines := strings.Split(output, "\n")
+		output = strings.Join(lines[:m.windowHeight], "\n")
+	} else if actualHeight < m.windowHeight {
+		// Pad bottom with empty lines
+		for i := actualHeight; i < m.windowHeight; i++ {
+			output += "\n"
+		}
+	}
+
+	return output
+}
+
+// renderSideBySide lays out the commit list and details panels as two
+// columns, each contentHeight lines tall inside their borders.
+func (m *model) renderSideBySide(contentHeight int, box1Border, box2Border lipgloss.Color) string {
 	// Panel widths - dynamic based on graph width
-	// graph needs: 2 (selection "> ") + maxGraphWidth + 1 (space) + 7 (hash) + borders(2) + padding(2) = maxGraphWidth + 14
-	leftPanelWidth := m.maxGraphWidth + 14
+	// graph needs: 2 (selection "> ") + maxGraphWidth + 1 (space) + hashWidth + borders(2) + padding(2)
+	leftPanelWidth := m.maxGraphWidth + m.hashWidth + 7
+	if len(m.fresh) > 0 {
+		leftPanelWidth += 4 // " new" markers
+	}
+	if m.rewritten > 0 {
+		leftPanelWidth += 2 // replaced and grafted markers
+	}
+	leftPanelWidth += m.stackWidth
+	if len(m.review.commits) > 0 || len(m.review.files) > 0 {
+		leftPanelWidth += 2 // reviewed markers
+	}
+	if len(m.notes.notes) > 0 {
+		leftPanelWidth += 2 // note markers
+	}
+	if m.showCopies && len(m.copies) > 0 {
+		leftPanelWidth += 2 // copy markers
+	}
+	if len(m.marks) > 0 {
+		leftPanelWidth += 4 // marked commits and their merge-base, which can be one
+	}
+	if m.showImpact {
+		leftPanelWidth += impactWidth
+	}
+	if m.showMinimap {
+		leftPanelWidth++
+	}
 	if leftPanelWidth < 25 {
 		leftPanelWidth = 25
 	}
@@ -1128,62 +2065,155 @@ func (m model) View() (result string) {
 
 	log.Printf("View: leftPanelWidth=%d, rightPanelWidth=%d, contentHeight=%d", leftPanelWidth, rightPanelWidth, contentHeight)
 
-	// Target height for both panels (content + 2 border lines)
-	targetPanelHeight := contentHeight + 2
-
-	// Create left panel (commit list)
-	leftContent := m.renderCommitList()
-	leftPanel := addBoxLabel(lipgloss.NewStyle().
-		Width(leftPanelWidth-2). // subtract borders (2); Width includes padding
-		Height(contentHeight).
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(box1Border).
-		Padding(0, 1).
-		Render(leftContent), "[1]")
-
-	// Create right panel (commit details)
-	rightContent := m.renderCommitDetails()
-	rightPanel := addBoxLabel(lipgloss.NewStyle().
-		Width(rightPanelWidth-2). // subtract borders (2); Width includes padding
-		Height(contentHeight).
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(box2Border).
-		Padding(1, 2).
-		Render(rightContent), "[2]")
-
-	// Force both panels to exactly the same height.
-	// lipgloss Height() is a minimum, not a maximum — long lines that wrap
-	// inside the panel can make it taller. Trim any excess lines from either panel.
-	leftPanel = trimToHeight(leftPanel, targetPanelHeight)
-	rightPanel = trimToHeight(rightPanel, targetPanelHeight)
-
 	// Join panels horizontally
-	content := lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, rightPanel)
-
-	output := fmt.Sprintf("%s\n%s\n%s", repoInfoBox, content, help)
-
-	// Force exact windowHeight lines. We count lines via lipgloss.Height which
-	// correctly handles ANSI escape sequences, then trim or pad as needed.
-	actualHeight := lipgloss.Height(output)
-	log.Printf("View: actualHeight=%d, windowHeight=%d", actualHeight, m.windowHeight)
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		m.renderListPanel(leftPanelWidth, contentHeight, box1Border),
+		m.renderDetailsPanel(rightPanelWidth, contentHeight, box2Border))
+}
 
-	if actualHeight > m.windowHeight {
-		// Trim from the bottom
-		lines := strings.Split(output, "\n")
-		output = strings.Join(lines[:m.windowHeight], "\n")
-	} else if actualHeight < m.windowHeight {
-		// Pad bottom with empty lines
-		for i := actualHeight; i < m.windowHeight; i++ {
-			output += "\n"
+// renderListPanel renders box [1] with the given outer width and inner
+// height. Both panels are forced to exactly height+2 lines with trimToHeight:
+// lipgloss Height() is a minimum, not a maximum — long lines that wrap
+// inside the panel can make it taller.
+func (m *model) renderListPanel(width, height int, border lipgloss.Color) string {
+	key := fmt.Sprintf("%d|%d|%v|%d|%d|%s", m.dataVersion, m.selected, m.workTree, width, height, border)
+	return m.cache.left.get(key, func() string {
+		// Presentation mode leaves more room around the graph, for
+		// reading it off a projector
+		padV, padH := 0, 1
+		if m.present {
+			padV, padH = 1, 3
 		}
-	}
+		var content string
+		if m.workTree {
+			content = m.renderWorkTreeList(height - 2*padV)
+		} else {
+			content = m.renderCommitList(height - 2*padV)
+		}
+		return trimToHeight(addBoxLabel(lipgloss.NewStyle().
+			Width(width-2). // subtract borders (2); Width includes padding
+			Height(height).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(border).
+			Padding(padV, padH).
+			Render(content), "[1]"), height+2)
+	})
+}
 
-	return output
+// renderDetailsPanel renders box [2]; see renderListPanel.
+func (m *model) renderDetailsPanel(width, height int, border lipgloss.Color) string {
+	key := fmt.Sprintf("%d|%d|%d|%d|%v|%d|%d|%s|%d|%d", m.dataVersion, m.selected, m.detailTab, m.detailsScroll[m.detailTab], m.containsAll, width, height, border, m.filesCursor, m.link)
+	if m.cfg.RelativeDates {
+		key += m.now.Format(time.RFC3339)
+	}
+	if m.workTree {
+		key += fmt.Sprintf("|wt%d", m.wtScroll)
+	}
+	if m.menu != nil && m.menu.detail != "" {
+		key += "|menu" + m.menu.detail
+	}
+	if m.finder != nil {
+		key += fmt.Sprintf("|finder%d|%d|%s", m.finder.cursor, m.finder.version, m.finder.input.Value())
+	}
+	if m.releases != nil {
+		key += fmt.Sprintf("|releases%d|%s", m.releases.cursor, m.revRange)
+	}
+	if m.grep != nil {
+		key += fmt.Sprintf("|grep%p|%d", m.grep, m.grep.cursor)
+	}
+	if m.tree != nil {
+		key += fmt.Sprintf("|tree%p|%d|%d", m.tree, m.tree.cursor, len(m.tree.last))
+	}
+	if m.fileView != nil {
+		key += fmt.Sprintf("|file%p|%d", m.fileView, m.fileView.cursor)
+	}
+	if m.compare != nil {
+		key += fmt.Sprintf("|compare%p|%d", m.compare, m.compare.scroll)
+	}
+	if m.shell != nil {
+		key += fmt.Sprintf("|shell%p|%v|%d", m.shell, m.shell.running, m.shell.scroll)
+	}
+	if m.commit != nil {
+		key += fmt.Sprintf("|commit%v%v%v%p|%s", m.commit.amend, m.commit.signoff, m.commit.running, m.commit.reword, m.commit.input.View())
+	}
+	return m.cache.right.get(key, func() string {
+		var content string
+		switch {
+		case m.menu != nil && m.menu.detail != "":
+			content = m.menu.detail
+		case m.shell != nil:
+			content = m.renderShell(width-6, height-2)
+		case m.finder != nil:
+			content = m.renderFinder(width-6, height-2)
+		case m.releases != nil:
+			content = m.renderReleases(height - 2)
+		case m.compare != nil:
+			content = m.renderCompare(width-6, height-2)
+		case m.fileView != nil:
+			content = m.renderFileView(width-6, height-2)
+		case m.grep != nil:
+			content = m.renderGrep(width-6, height-2)
+		case m.tree != nil:
+			content = m.renderTree(width-6, height-2)
+		case m.commit != nil:
+			// Inside the border (2) and padding (4 across, 2 down)
+			content = m.renderCommitEditor(width-6, height-2)
+		case m.workTree:
+			content = m.renderWorkTreeDiff(height)
+		default:
+			// Inside the border (2) and padding (4)
+			content = m.renderCommitDetails(width-6, height)
+		}
+		return trimToHeight(addBoxLabel(lipgloss.NewStyle().
+			Width(width-2). // subtract borders (2); Width includes padding
+			Height(height).
+			BorderStyle(lipgloss.RoundedBorder()).
+			BorderForeground(border).
+			Padding(1, 2).
+			Render(content), "[2]"), height+2)
+	})
 }
 
 func main() {
+	logOutputUTF8()
+	args := os.Args[1:]
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help":
+			runHelp(os.Args[2:])
+			return
+		case "export":
+			if err := runExport(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "gitraffe: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "render":
+			if err := runRender(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "gitraffe: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "large-files":
+			if err := runLargeFiles(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "gitraffe: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "demo":
+			dir, err := runDemo(os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "gitraffe: %v\n", err)
+				os.Exit(1)
+			}
+			// Still on the screen after the alternate one is left
+			fmt.Fprintf(os.Stderr, "Demo repository created in %s\n", dir)
+			args = []string{dir}
+		}
+	}
+
 	// Set up logging to file for debugging
-	logFile, err := os.OpenFile("gitraffe.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err == nil {
 		log.SetOutput(logFile)
 		defer logFile.Close()
@@ -1191,21 +2221,74 @@ func main() {
 
 	log.Println("Starting Gitraffe...")
 
-	repoPath := "."
-	if len(os.Args) > 1 {
-		repoPath = os.Args[1]
+	// Anything that panics outside Bubble Tea's own recovery
+	defer func() {
+		if r := recover(); r != nil {
+			restoreTerminal()
+			path := reportCrash(r, "  (outside the UI)\n")
+			fmt.Fprintf(os.Stderr, "gitraffe crashed: %v\nCrash report: %s\n", r, path)
+			os.Exit(1)
+		}
+	}()
+
+	opts, err := parseArgs(args)
+	if err != nil {
+		exitUsage(err)
 	}
 
-	log.Printf("Opening repository: %s\n", repoPath)
+	log.Printf("Opening repository: %s\n", opts.repoPath)
 
+	m := initialModel(opts)
+	if !opts.present && firstRun() {
+		m.startTour()
+	}
+	if opts.profile {
+		if m.prof, err = startProfile(); err != nil {
+			fmt.Fprintf(os.Stderr, "gitraffe: starting the profile: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	p := tea.NewProgram(
-		initialModel(repoPath),
+		m,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
 
-	if _, err := p.Run(); err != nil {
+	saveTitle()
+	final, err := p.Run()
+	restoreTitle()
+
+	if m.prof != nil {
+		// What the model holds at the end is what the heap profile is for
+		files, profErr := m.prof.stop()
+		runtime.KeepAlive(final)
+		log.Printf("Profile:\n%s", m.prof.report())
+		if profErr != nil {
+			fmt.Fprintf(os.Stderr, "gitraffe: writing the profile: %v\n", profErr)
+		}
+		if len(files) > 0 {
+			fmt.Fprintf(os.Stderr, "Profile written to:\n  %s\n", strings.Join(files, "\n  "))
+		}
+	}
+
+	// Bubble Tea has restored the terminal by now, and the title is back,
+	// whether gitraffe quit, was interrupted or killed, or crashed. Point out anything git was
+	// left in the middle of, like a pull that stopped on a conflict.
+	if name, hint := operationInProgress(opts.repoPath); name != "" {
+		fmt.Fprintf(os.Stderr, "A %s is in progress in %s. To finish or undo it: %s\n", name, opts.repoPath, hint)
+	}
+
+	if err != nil {
 		log.Printf("Program error: %v\n", err)
+		if errors.Is(err, tea.ErrProgramPanic) {
+			crashMu.Lock()
+			path := crashReport
+			crashMu.Unlock()
+			if path != "" {
+				fmt.Fprintf(os.Stderr, "gitraffe crashed. Crash report: %s\n", path)
+				os.Exit(1)
+			}
+		}
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
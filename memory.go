@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// defaultMemoryBudget is how many MB of loaded diffs are kept, see
+// gitraffe.memoryBudget.
+const defaultMemoryBudget = 256
+
+// interner keeps one copy of strings that repeat across commits, like
+// author names and the graph's connector rows. Interned strings are
+// copies, so they don't hold on to the git log output they were cut
+// from.
+type interner map[string]string
+
+func (in interner) intern(s string) string {
+	if v, ok := in[s]; ok {
+		return v
+	}
+	s = strings.Clone(s)
+	in[s] = s
+	return s
+}
+
+// diffSize is roughly what a loaded diff takes up.
+func diffSize(c *commit) int {
+	size := len(c.DiffBody) + len(c.Body)
+	for _, f := range c.Files {
+		size += len(f.Path) + 64
+	}
+	return size
+}
+
+// touchDiff moves the diff of a commit to the most recently selected
+// end of diffOrder.
+func (m *model) touchDiff(hash plumbing.Hash) {
+	for i, h := range m.diffOrder {
+		if h == hash {
+			m.diffOrder = append(m.diffOrder[:i], m.diffOrder[i+1:]...)
+			break
+		}
+	}
+	m.diffOrder = append(m.diffOrder, hash)
+}
+
+// trimDiffs unloads the diffs selected longest ago while the loaded ones
+// are over gitraffe.memoryBudget. Selecting such a commit again loads its
+// diff again. The selected commit's diff is always kept.
+func (m *model) trimDiffs() {
+	budget := m.cfg.MemoryBudget << 20
+	if budget <= 0 {
+		return
+	}
+	index := make(map[plumbing.Hash]int, len(m.diffOrder))
+	size := 0
+	for i := range m.commits {
+		if m.commits[i].DiffLoaded {
+			index[m.commits[i].FullHash] = i
+			size += diffSize(&m.commits[i])
+		}
+	}
+
+	kept := m.diffOrder[:0]
+	for n, hash := range m.diffOrder {
+		i, ok := index[hash]
+		switch {
+		case !ok:
+			// Gone with a reload, or unloaded
+			continue
+		case size > budget && i != m.selected && n < len(m.diffOrder)-1:
+			size -= diffSize(&m.commits[i])
+			c := &m.commits[i]
+			// The trailers stay, a few lines naming the co-authors
+			c.DiffLoaded, c.DiffBody, c.Files, c.Body = false, "", nil, ""
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	m.diffOrder = kept
+}
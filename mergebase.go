@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Marking two commits with M shows where their histories meet: the
+// merge-base, marked ⊥ in the graph, and how many commits each of them
+// is past it, as when comparing a branch with the one it was forked
+// from.
+
+var markStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#81A1C1"))
+
+// mergeBase is the best common ancestor of the two marked commits, and
+// how far each is from it, as git rev-list --count base..mark.
+type mergeBase struct {
+	hash, short string
+	ahead       [2]int
+}
+
+type mergeBaseMsg struct {
+	marks [2]string
+	base  *mergeBase // nil when the commits share no history
+	err   error
+}
+
+// loadMergeBase finds the merge-base of two commits.
+func loadMergeBase(repoPath string, marks [2]string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "merge-base", marks[0], marks[1])
+		if err != nil {
+			// Exit status 1 with no output: unrelated histories
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && out == "" {
+				return mergeBaseMsg{marks: marks}
+			}
+			return mergeBaseMsg{marks: marks, err: err}
+		}
+		base := &mergeBase{hash: out}
+		if base.short, err = gitRead(repoPath, "rev-parse", "--short", out); err != nil {
+			return mergeBaseMsg{marks: marks, err: err}
+		}
+		for i, mark := range marks {
+			count, err := gitRead(repoPath, "rev-list", "--count", out+".."+mark)
+			if err != nil {
+				return mergeBaseMsg{marks: marks, err: err}
+			}
+			base.ahead[i], _ = strconv.Atoi(count)
+		}
+		return mergeBaseMsg{marks: marks, base: base}
+	}
+}
+
+// toggleMark marks the selected commit, or unmarks it. Marking a second
+// commit finds the merge-base of the two; a third starts over.
+func (m *model) toggleMark() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	m.mergeBase = nil
+	m.dataVersion++
+	for i, hash := range m.marks {
+		if hash == c.FullHash.String() {
+			m.marks = append(m.marks[:i], m.marks[i+1:]...)
+			m.status = "Unmarked " + c.Hash
+			return nil
+		}
+	}
+	if len(m.marks) == 2 {
+		m.marks = nil
+	}
+	m.marks = append(m.marks, c.FullHash.String())
+	if len(m.marks) == 1 {
+		m.status = "Marked " + c.Hash + "; mark another commit to find their merge-base"
+		return nil
+	}
+	m.status = "Finding the merge-base…"
+	return loadMergeBase(m.repoPath, [2]string{m.marks[0], m.marks[1]})
+}
+
+// handleMergeBase takes in the merge-base of the marked commits, unless
+// the marks changed meanwhile.
+func (m *model) handleMergeBase(msg mergeBaseMsg) {
+	if len(m.marks) != 2 || m.marks[0] != msg.marks[0] || m.marks[1] != msg.marks[1] {
+		return
+	}
+	switch {
+	case msg.err != nil:
+		m.status, m.statusErr = "Finding the merge-base failed: "+msg.err.Error(), true
+	case msg.base == nil:
+		m.status, m.statusErr = "The marked commits share no history", true
+	default:
+		m.mergeBase = msg.base
+		m.dataVersion++
+		m.status = fmt.Sprintf("Merge-base %s: %s +%d, %s +%d commits; ' jumps there", msg.base.short,
+			m.shortOf(msg.marks[0]), msg.base.ahead[0], m.shortOf(msg.marks[1]), msg.base.ahead[1])
+	}
+}
+
+// jumpToMergeBase selects the merge-base of the marked commits.
+func (m *model) jumpToMergeBase() tea.Cmd {
+	if m.mergeBase == nil {
+		m.status, m.statusErr = "Mark two commits with M first", true
+		return nil
+	}
+	if i := m.commitIndex(m.mergeBase.hash); i >= 0 {
+		return m.jumpTo(i)
+	}
+	m.status, m.statusErr = "The merge-base "+m.mergeBase.short+" isn't in the graph", true
+	return nil
+}
+
+// markMarker is shown after the hash of a marked commit and of their
+// merge-base.
+func (m *model) markMarker(c commit) string {
+	marker := ""
+	for _, hash := range m.marks {
+		if hash == c.FullHash.String() {
+			marker = markStyle.Render(" ◆")
+		}
+	}
+	if m.mergeBase != nil && m.mergeBase.hash == c.FullHash.String() {
+		marker += markStyle.Render(" ⊥")
+	}
+	return marker
+}
+
+// mergeBaseNote tells, in the details of a marked commit or of the
+// merge-base, how the marked commits stand against it.
+func (m *model) mergeBaseNote(c commit) string {
+	b := m.mergeBase
+	if b == nil {
+		return ""
+	}
+	var note string
+	switch c.FullHash.String() {
+	case b.hash:
+		note = fmt.Sprintf("⊥ Merge-base of the marked %s (+%d) and %s (+%d)",
+			m.shortOf(m.marks[0]), b.ahead[0], m.shortOf(m.marks[1]), b.ahead[1])
+	case m.marks[0], m.marks[1]:
+		i := 0
+		if c.FullHash.String() == m.marks[1] {
+			i = 1
+		}
+		note = fmt.Sprintf("◆ Marked: +%d over the merge-base %s; the other mark, %s, +%d",
+			b.ahead[i], b.short, m.shortOf(m.marks[1-i]), b.ahead[1-i])
+	default:
+		return ""
+	}
+	return markStyle.Render(note) + "\n"
+}
+
+// shortOf is the short hash of a commit in the graph.
+func (m *model) shortOf(hash string) string {
+	if i := m.commitIndex(hash); i >= 0 {
+		return m.commits[i].Hash
+	}
+	return hash[:min(len(hash), 7)]
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The minimap is a column at the left of the commit list with the whole
+// history squeezed into the height of the panel, each line standing for
+// an equal share of the rows. The lines of the rows on screen are lit,
+// showing where in a long history the list is; clicking a line jumps
+// there.
+
+var (
+	minimapStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#4C566A"))
+	minimapViewStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Bold(true)
+)
+
+// minimapArea is where the minimap was last drawn on the screen, for
+// clicks on it; zero when it isn't shown.
+type minimapArea struct {
+	x, y, height int
+}
+
+// minimapRows are the rows of the list a line of the minimap stands for,
+// from lo up to hi.
+func minimapRows(line, height, total int) (lo, hi int) {
+	lo = line * total / height
+	hi = max((line+1)*total/height, lo+1)
+	return lo, hi
+}
+
+// minimapCell is line of the minimap, with rows first up to last on
+// screen out of total.
+func minimapCell(line, height, total, first, last int) string {
+	lo, hi := minimapRows(line, height, total)
+	switch {
+	case lo >= total:
+		return " "
+	case lo < last && hi > first:
+		return minimapViewStyle.Render("┃")
+	}
+	return minimapStyle.Render("│")
+}
+
+// clickMinimap jumps to the commits a clicked line of the minimap stands
+// for, the first of them.
+func (m *model) clickMinimap(msg tea.MouseMsg) tea.Cmd {
+	area := m.cache.minimap
+	if !m.showMinimap || area.height == 0 || msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft ||
+		msg.X != area.x || msg.Y < area.y || msg.Y >= area.y+area.height {
+		return nil
+	}
+	if len(m.displayRows) == 0 {
+		lo, _ := minimapRows(msg.Y-area.y, area.height, len(m.commits))
+		if lo >= len(m.commits) {
+			return nil
+		}
+		return m.jumpTo(lo)
+	}
+	lo, _ := minimapRows(msg.Y-area.y, area.height, len(m.displayRows))
+	// Rows of graph lines only have no commit; take the next that has
+	for i := lo; i < len(m.displayRows); i++ {
+		if idx := m.displayRows[i].CommitIdx; idx >= 0 {
+			return m.jumpTo(idx)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// noteStyle marks commits with a note.
+var noteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B"))
+
+// noteStore holds free-text notes on commits, for findings made while
+// digging through the history. They are kept as one file per commit in
+// the state dir, or with gitraffe.notesRef set, as git notes on that ref,
+// where they can be pushed and shared.
+type noteStore struct {
+	repoPath string
+	dir      string // state dir for notes, when not using git notes
+	ref      string // git notes ref, e.g. refs/notes/gitraffe
+	notes    map[string]string
+}
+
+func loadNotes(repoPath, ref string) *noteStore {
+	n := &noteStore{repoPath: repoPath, ref: ref, notes: map[string]string{}}
+	if ref != "" {
+		// "<note blob> <commit>" per note
+		out, _ := gitRead(repoPath, "notes", "--ref="+ref, "list")
+		for _, line := range strings.Split(out, "\n") {
+			blob, hash, ok := strings.Cut(line, " ")
+			if !ok {
+				continue
+			}
+			if text, err := gitRead(repoPath, "cat-file", "blob", blob); err == nil {
+				n.notes[hash] = strings.TrimSpace(text)
+			}
+		}
+		return n
+	}
+
+	dir, err := stateDir(repoPath)
+	if err != nil {
+		return n
+	}
+	n.dir = filepath.Join(dir, "notes")
+	entries, _ := os.ReadDir(n.dir)
+	for _, e := range entries {
+		if text, err := os.ReadFile(filepath.Join(n.dir, e.Name())); err == nil {
+			n.notes[e.Name()] = strings.TrimSpace(string(text))
+		}
+	}
+	return n
+}
+
+// set saves a commit's note; an empty one deletes it.
+func (n *noteStore) set(hash, text string) error {
+	if n.ref != "" {
+		args := []string{"notes", "--ref=" + n.ref, "add", "-f", "-m", text, hash}
+		if text == "" {
+			args = []string{"notes", "--ref=" + n.ref, "remove", "--ignore-missing", hash}
+		}
+		if out, err := gitRun(n.repoPath, args...); err != nil {
+			return fmt.Errorf("%v: %s", err, out)
+		}
+	} else {
+		if n.dir == "" {
+			return fmt.Errorf("no git dir to save in")
+		}
+		path := filepath.Join(n.dir, hash)
+		if text == "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		} else {
+			if err := os.MkdirAll(n.dir, 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	if text == "" {
+		delete(n.notes, hash)
+	} else {
+		n.notes[hash] = text
+	}
+	return nil
+}
+
+// editNote asks for the selected commit's note, starting from the one it
+// has.
+func (m *model) editNote() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	hash := m.commits[m.selected].FullHash.String()
+	m.prompt = newPrompt("Note on "+m.commits[m.selected].Hash, "what you found out; empty to remove", func(m *model, value string) tea.Cmd {
+		if err := m.notes.set(hash, value); err != nil {
+			m.status, m.statusErr = "Couldn't save the note: "+err.Error(), true
+			return nil
+		}
+		m.status, m.statusErr = "Note saved", false
+		if value == "" {
+			m.status = "Note removed"
+		}
+		m.dataVersion++
+		return nil
+	})
+	m.prompt.input.SetValue(m.notes.notes[hash])
+	m.prompt.input.CursorEnd()
+	return nil
+}
+
+// noteMarker is shown after the hash of a commit with a note.
+func (m *model) noteMarker(c commit) string {
+	if m.notes.notes[c.FullHash.String()] == "" {
+		return ""
+	}
+	return noteStyle.Render(" ✎")
+}
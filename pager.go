@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+)
+
+// startPager returns where a printing mode like `gitraffe help` writes:
+// the user's pager when stdout is a terminal, as git pages its own
+// output, or else stdout itself, so that piped output stays plain. done
+// must be called once everything is written; it waits for the pager to
+// be quit.
+//
+// git var GIT_PAGER picks the pager as git does: GIT_PAGER, core.pager,
+// PAGER, then less. "cat" or an empty pager turns paging off.
+func startPager(repoPath string) (w io.Writer, done func()) {
+	noPager := func() {}
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return os.Stdout, noPager
+	}
+	pager, err := gitRead(repoPath, "var", "GIT_PAGER")
+	if err != nil || pager == "" || pager == "cat" {
+		return os.Stdout, noPager
+	}
+
+	// Like git, through the shell, so that a pager with options or a
+	// pipeline of its own works; without one, as on Windows outside
+	// Git Bash, the words of the setting are the command
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("sh"); err == nil {
+		cmd = exec.Command("sh", "-c", pager)
+	} else {
+		words := strings.Fields(pager)
+		cmd = exec.Command(words[0], words[1:]...)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = os.Environ()
+	// git's defaults: quit when it all fits on the screen, keep colors
+	// and leave the output on the screen
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		cmd.Env = append(cmd.Env, "LV=-c")
+	}
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return os.Stdout, noPager
+	}
+	if err := cmd.Start(); err != nil {
+		return os.Stdout, noPager
+	}
+	// ctrl+c is for the pager, which would otherwise be left running on
+	// the terminal
+	signal.Ignore(os.Interrupt)
+	return in, func() {
+		in.Close()
+		cmd.Wait()
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// normalizeRepoPath cleans up the repository path as typed, so that git,
+// go-git and the name in the info box all get a path they take. On
+// Windows that undoes a few ways a path arrives mangled, see
+// cleanWindowsPath, and makes a drive-relative path like Z: absolute, as
+// it means the current directory of that drive only to the shell it was
+// typed in. Under WSL a Windows path becomes the Linux one, see
+// wslRepoPath.
+func normalizeRepoPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	if runtime.GOOS == "windows" {
+		path = cleanWindowsPath(path)
+	}
+	path = wslRepoPath(path)
+	path = filepath.Clean(path)
+	if filepath.VolumeName(path) != "" && !filepath.IsAbs(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return path
+}
+
+// cleanWindowsPath undoes what happens to Windows paths on the way in:
+//
+//   - cmd.exe takes the backslash in "C:\my repo\" as escaping the
+//     quote, which is left at the end of the path; no Windows path ends
+//     in a quote
+//   - \\?\C:\repo and \\?\UNC\server\share\repo, the extended-length
+//     forms some tools hand out, which git can't run in
+//   - //server/share/repo, a UNC path written with slashes
+func cleanWindowsPath(path string) string {
+	path = strings.TrimSuffix(path, `"`)
+	path = strings.ReplaceAll(path, "/", `\`)
+	if rest, ok := strings.CutPrefix(path, `\\?\UNC\`); ok {
+		return `\\` + rest
+	}
+	return strings.TrimPrefix(path, `\\?\`)
+}
+
+// repoDisplayName is the name of the repository in the info box: the
+// last element of its path; for the root of a UNC share, like
+// \\server\share, the share.
+func repoDisplayName(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	vol := filepath.VolumeName(path)
+	rest := strings.Trim(path[len(vol):], `/\`)
+	if rest == "" {
+		if i := strings.LastIndexAny(vol, `/\`); i >= 0 {
+			return vol[i+1:]
+		}
+		return cmp.Or(vol, string(filepath.Separator))
+	}
+	return filepath.Base(rest)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCleanWindowsPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"drive", `C:\src\repo`, `C:\src\repo`},
+		{"slashes", `C:/src/my repo`, `C:\src\my repo`},
+		{"mapped drive", `Z:\repo`, `Z:\repo`},
+		{"quote left by cmd.exe", `C:\my repo"`, `C:\my repo`},
+		{"UNC", `\\server\share\repo`, `\\server\share\repo`},
+		{"UNC with slashes", `//server/share/repo`, `\\server\share\repo`},
+		{"extended drive", `\\?\C:\src\repo`, `C:\src\repo`},
+		{"extended UNC", `\\?\UNC\server\share\repo`, `\\server\share\repo`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanWindowsPath(tt.path); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoDisplayName(t *testing.T) {
+	type test struct{ path, want string }
+	tests := []test{
+		{filepath.Join("src", "my repo"), "my repo"},
+		{filepath.Join("src", "repo") + string(filepath.Separator), "repo"},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests,
+			test{`\\server\share\repo`, "repo"},
+			test{`\\server\share`, "share"},
+			test{`Z:\repo`, "repo"},
+		)
+	}
+	for _, tt := range tests {
+		if got := repoDisplayName(tt.path); got != tt.want {
+			t.Errorf("repoDisplayName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestPathWithSpaces loads a repository whose path has spaces with
+// both backends.
+func TestPathWithSpaces(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my repos", "giraffe")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := buildDemoRepo(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range []string{backendCLI, backendGoGit} {
+		t.Run(backend, func(t *testing.T) {
+			m := initialModel(options{repoPath: dir + string(filepath.Separator), backend: backend})
+			tm := settle(m, loadRepo(m.repoPath, m.backend))
+			tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+			m = tm.(model)
+			if m.err != nil {
+				t.Fatal(m.err)
+			}
+			if len(m.commits) != demoCommits {
+				t.Errorf("%d commits, want %d", len(m.commits), demoCommits)
+			}
+			if m.repoName != "giraffe" {
+				t.Errorf("repo name %q, want giraffe", m.repoName)
+			}
+		})
+	}
+}
+
+func TestFromWindowsPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"drive", `C:\Users\me\my repo`, "/mnt/c/Users/me/my repo"},
+		{"slashes", `D:/src/repo`, "/mnt/d/src/repo"},
+		{"drive root", `C:\`, "/mnt/c"},
+		{"bare drive", `E:`, "/mnt/e"},
+		{"this distro", `\\wsl$\Ubuntu\home\me\repo`, "/home/me/repo"},
+		{"localhost share", `\\wsl.localhost\ubuntu\home\me\repo`, "/home/me/repo"},
+		{"other distro", `\\wsl$\Debian\home\me\repo`, `\\wsl$\Debian\home\me\repo`},
+		{"linux path", "/home/me/repo", "/home/me/repo"},
+		{"relative", "repo", "repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fromWindowsPath(tt.path, "/mnt", "Ubuntu"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGitDir opens a repository whose git dir is apart from its work
+// tree, like a dotfiles repository, with --git-dir and --work-tree.
+func TestGitDir(t *testing.T) {
+	dir := demoFixture(t)
+	workTree := t.TempDir()
+	if err := os.Rename(filepath.Join(dir, ".git"), filepath.Join(workTree, "dots.git")); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(workTree)
+	for _, backend := range []string{backendCLI, backendGoGit} {
+		t.Run(backend, func(t *testing.T) {
+			t.Setenv("GIT_DIR", "")
+			t.Setenv("GIT_WORK_TREE", "")
+			opts, err := parseArgs([]string{"--git-dir", "dots.git", "--work-tree", ".", "--backend", backend})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.repoPath != workTree {
+				t.Errorf("repository path %q, want the work tree %q", opts.repoPath, workTree)
+			}
+			m := initialModel(opts)
+			m = settle(m, loadRepo(m.repoPath, m.backend)).(model)
+			if m.err != nil {
+				t.Fatal(m.err)
+			}
+			if len(m.commits) != demoCommits {
+				t.Errorf("%d commits, want %d", len(m.commits), demoCommits)
+			}
+		})
+	}
+}
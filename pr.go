@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prInfo is what a pull (or GitLab merge) request for a branch will be
+// opened with.
+type prInfo struct {
+	branch   string
+	remote   string
+	base     string // branch on the remote the request targets
+	tool     string // gh or glab
+	subjects []string
+	err      error
+}
+
+type prInfoMsg prInfo
+
+type prDoneMsg struct {
+	branch string
+	url    string
+	output string
+	err    error
+}
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// localBranches picks the local branches out of a commit's decorations,
+// leaving out tags, HEAD and remote-tracking branches.
+func (m *model) localBranches(c commit) []string {
+	var branches []string
+	for _, ref := range strings.Split(c.Refs, ", ") {
+		ref = strings.TrimPrefix(ref, "HEAD -> ")
+		if ref == "" || ref == "HEAD" || strings.HasPrefix(ref, "tag: ") || m.isRemoteRef(ref) {
+			continue
+		}
+		branches = append(branches, ref)
+	}
+	return branches
+}
+
+// openPR starts a pull request for a branch of the selected commit,
+// asking which one when several point at it.
+func (m *model) openPR() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	branches := m.localBranches(m.commits[m.selected])
+	switch len(branches) {
+	case 0:
+		m.status, m.statusErr = "No local branch at this commit to open a pull request for", true
+		return nil
+	case 1:
+		return loadPRInfo(m.repoPath, branches[0], m.baseBranch())
+	}
+	var options []menuOption
+	for i, key := range pickerKeys(branches) {
+		if key == "" {
+			continue
+		}
+		branch := branches[i]
+		options = append(options, menuOption{key: key, label: branch, action: func(m *model) tea.Cmd {
+			return loadPRInfo(m.repoPath, branch, m.baseBranch())
+		}})
+	}
+	m.menu = &menu{title: "Pull request for", options: options}
+	return nil
+}
+
+// loadPRInfo works out where a pull request for branch goes: the
+// branch's remote, base or else the remote's default branch, and gh or
+// glab depending on the host. The description lists the commit subjects.
+func loadPRInfo(repoPath, branch, base string) tea.Cmd {
+	return func() tea.Msg {
+		info := prInfo{branch: branch, base: base}
+
+		info.remote, _ = gitRead(repoPath, "config", "branch."+branch+".remote")
+		if info.remote == "" || info.remote == "." {
+			out, _ := gitRead(repoPath, "remote")
+			remotes := strings.Fields(out)
+			for _, r := range remotes {
+				if r == "origin" {
+					info.remote = r
+				}
+			}
+			if info.remote == "" && len(remotes) > 0 {
+				info.remote = remotes[0]
+			}
+		}
+		if info.remote == "" {
+			info.err = fmt.Errorf("no remote to open a pull request on")
+			return prInfoMsg(info)
+		}
+
+		url, _ := gitRead(repoPath, "remote", "get-url", info.remote)
+		info.tool = "gh"
+		if strings.Contains(url, "gitlab") {
+			info.tool = "glab"
+		}
+		if _, err := exec.LookPath(info.tool); err != nil {
+			info.err = fmt.Errorf("%s isn't installed; it opens pull requests for %s", info.tool, url)
+			return prInfoMsg(info)
+		}
+
+		if info.base == "" {
+			head, _ := gitRead(repoPath, "symbolic-ref", "--short", "refs/remotes/"+info.remote+"/HEAD")
+			info.base = strings.TrimPrefix(head, info.remote+"/")
+		}
+		if info.base == "" {
+			info.base = "main"
+		}
+
+		upstream := info.remote + "/" + info.base
+		if _, err := gitRead(repoPath, "rev-parse", "--verify", "--quiet", upstream); err != nil {
+			upstream = info.base
+		}
+		if out, err := gitRead(repoPath, "log", "--reverse", "--format=%s", upstream+".."+branch); err == nil && out != "" {
+			info.subjects = strings.Split(out, "\n")
+		}
+		return prInfoMsg(info)
+	}
+}
+
+// title is the first commit's subject for a single commit, otherwise the
+// branch name, like gh pr create --fill.
+func (p prInfo) title() string {
+	if len(p.subjects) == 1 {
+		return p.subjects[0]
+	}
+	return p.branch
+}
+
+func (p prInfo) body() string {
+	var lines []string
+	for _, s := range p.subjects {
+		lines = append(lines, "- "+s)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prMenu shows the request before it is opened.
+func prMenu(info prInfo) *menu {
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Pull request"))
+	sb.WriteString("\n\n")
+	fmt.Fprintf(&sb, "%s → %s on %s, with %s\n\n", branchStyle.Render(info.branch), branchStyle.Render(info.base), info.remote, info.tool)
+	sb.WriteString(messageStyle.Render(info.title()))
+	sb.WriteString("\n\n")
+	if len(info.subjects) == 0 {
+		sb.WriteString(helpStyle.Render("No commits above " + info.base + "."))
+	} else {
+		sb.WriteString(info.body())
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(helpStyle.Render("The branch is pushed to " + info.remote + " first."))
+	return &menu{
+		title: "Open a pull request",
+		options: []menuOption{{key: "enter", label: "push and open", action: func(m *model) tea.Cmd {
+			m.status = "Opening a pull request for " + info.branch + "…"
+			return createPR(m.repoPath, info)
+		}}},
+		detail: sb.String(),
+	}
+}
+
+// createPR pushes the branch and opens the request with gh or glab, which
+// print its URL.
+func createPR(repoPath string, info prInfo) tea.Cmd {
+	return func() tea.Msg {
+		push := gitCommand(repoPath, "push", "--set-upstream", info.remote, info.branch)
+		push.Env = append(push.Environ(), remoteEnv(repoPath)...)
+		if out, err := push.CombinedOutput(); err != nil {
+			return prDoneMsg{branch: info.branch, output: strings.TrimSpace(string(out)), err: err}
+		}
+
+		var args []string
+		if info.tool == "glab" {
+			args = []string{"mr", "create", "--source-branch", info.branch, "--target-branch", info.base,
+				"--title", info.title(), "--description", info.body(), "--yes"}
+		} else {
+			args = []string{"pr", "create", "--head", info.branch, "--base", info.base,
+				"--title", info.title(), "--body", info.body()}
+		}
+		log.Printf("Running: %s %s\n", info.tool, strings.Join(args, " "))
+		cmd := exec.Command(info.tool, args...)
+		cmd.Dir = repoPath
+		out, err := cmd.CombinedOutput()
+		output := strings.TrimSpace(string(out))
+		return prDoneMsg{branch: info.branch, url: urlPattern.FindString(output), output: output, err: err}
+	}
+}
+
+func (m *model) handlePRDone(msg prDoneMsg) tea.Cmd {
+	switch {
+	case msg.err != nil:
+		lines := strings.Split(msg.output, "\n")
+		m.status, m.statusErr = fmt.Sprintf("Opening a pull request for %s failed: %s", msg.branch, lines[len(lines)-1]), true
+	case msg.url != "":
+		m.status, m.statusErr = "Pull request: "+msg.url, false
+	default:
+		m.status, m.statusErr = "Opened a pull request for "+msg.branch, false
+	}
+	// The push moved the remote-tracking branch
+	return m.reload()
+}
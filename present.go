@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// followInterval is how often presentation mode checks whether HEAD or a
+// ref moved, so the graph keeps up with commands typed in another
+// terminal during a demo.
+const followInterval = time.Second
+
+// followMsg carries `git show-ref --head`: every ref with its commit,
+// HEAD first.
+type followMsg struct {
+	refs string
+	head string // full hash HEAD points at
+}
+
+func followHead(repoPath string) tea.Cmd {
+	return tea.Tick(followInterval, func(time.Time) tea.Msg {
+		refs, _ := gitRead(repoPath, "show-ref", "--head")
+		head, _, _ := strings.Cut(refs, " ")
+		return followMsg{refs: refs, head: head}
+	})
+}
+
+// handleFollow reloads when a ref moved and selects the commit HEAD is
+// on, wherever it went.
+func (m *model) handleFollow(msg followMsg) tea.Cmd {
+	cmds := []tea.Cmd{followHead(m.repoPath)}
+	if msg.refs != m.followRefs && m.ready {
+		if m.followRefs != "" {
+			cmds = append(cmds, m.reload())
+		}
+		m.followRefs = msg.refs
+		if i := m.commitIndex(msg.head); i >= 0 {
+			cmds = append(cmds, m.jumpTo(i))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// presentBlocked are the keys presentation mode ignores: everything that
+// changes the repository or gitraffe's state in it, and the keys that
+// would move the focus to the hidden panels.
+var presentBlocked = map[string]bool{
+	"p": true, "P": true, "B": true, "b": true, "O": true, "n": true, "v": true, "V": true, "w": true, "e": true, "!": true,
+	"0": true, "2": true, "`": true, "tab": true, "shift+tab": true, "z": true,
+}
+
+// presentLabel is the refs and subject shown after the hash in
+// presentation mode, where the details panel is hidden.
+func (m *model) presentLabel(c commit) string {
+	label := "   " + messageStyle.Render(c.Message)
+	if c.Refs != "" {
+		refs, _ := shortRefs(c.Refs, rowRefsWidth)
+		label = "   " + m.styleRefs(refs) + label
+	}
+	return label
+}
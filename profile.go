@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profiler records where the time goes when gitraffe starts, for
+// --profile: a CPU profile of the whole run, a heap profile at the end,
+// and how long each step up to the first frame took. A nil profiler
+// records nothing, so the steps can be timed unconditionally.
+type profiler struct {
+	start time.Time
+	base  string // path of the files written, without the suffix
+	cpu   *os.File
+
+	mu    sync.Mutex
+	steps []profileStep
+}
+
+type profileStep struct {
+	name string
+	took time.Duration
+	done time.Duration // since the start
+}
+
+// startProfile starts the CPU profile, in the log directory.
+func startProfile() (*profiler, error) {
+	p := &profiler{
+		start: time.Now(),
+		base:  filepath.Join(logDir(), "gitraffe-profile-"+time.Now().Format("20060102-150405")),
+	}
+	f, err := os.Create(p.base + "-cpu.pprof")
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	p.cpu = f
+	return p, nil
+}
+
+// step times a step from now until the returned func is called. Only
+// the first time counts: a reload later on is not part of starting up.
+func (p *profiler) step(name string) func() {
+	if p == nil {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for _, s := range p.steps {
+			if s.name == name {
+				return
+			}
+		}
+		p.steps = append(p.steps, profileStep{name, time.Since(begin), time.Since(p.start)})
+	}
+}
+
+// stepCmd times a command as a step.
+func (p *profiler) stepCmd(name string, cmd tea.Cmd) tea.Cmd {
+	if p == nil {
+		return cmd
+	}
+	return func() tea.Msg {
+		defer p.step(name)()
+		return cmd()
+	}
+}
+
+// stop ends the CPU profile and writes the heap profile and the timings
+// next to it, returning the files written.
+func (p *profiler) stop() ([]string, error) {
+	pprof.StopCPUProfile()
+	if err := p.cpu.Close(); err != nil {
+		return nil, err
+	}
+	files := []string{p.cpu.Name()}
+
+	heap, err := os.Create(p.base + "-heap.pprof")
+	if err != nil {
+		return files, err
+	}
+	runtime.GC() // up to date statistics of what is still in use
+	err = pprof.WriteHeapProfile(heap)
+	if closeErr := heap.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return files, err
+	}
+	files = append(files, heap.Name())
+
+	timings := p.base + "-timing.txt"
+	if err := os.WriteFile(timings, []byte(p.report()), 0644); err != nil {
+		return files, err
+	}
+	return append(files, timings), nil
+}
+
+// report lists the steps in the order they finished: how long each took
+// and when it was done, counted from the start.
+func (p *profiler) report() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "built with %s, %s/%s, %d CPUs\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	fmt.Fprintf(&sb, "%-16s %10s %10s\n", "step", "took", "done at")
+	for _, s := range p.steps {
+		fmt.Fprintf(&sb, "%-16s %10s %10s\n", s.name, s.took.Round(time.Microsecond), s.done.Round(time.Microsecond))
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(&sb, "\nheap in use at exit: %s\n", formatSize(int64(mem.HeapInuse)))
+	return sb.String()
+}
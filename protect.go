@@ -0,0 +1,38 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Branches matching a gitraffe.protectedBranch pattern, like main or
+// release/*, are left out when merged branches are deleted or cleaned up,
+// with their upstreams. Force-pushing one, or restoring one to an older
+// commit, takes typing its name after the menu's key, so that a key
+// pressed on the wrong branch can't rewrite it.
+
+// protected tells whether a branch, as its name without refs/heads/,
+// matches a protected pattern.
+func (c config) protected(branch string) bool {
+	for _, pattern := range c.ProtectedBranches {
+		if refGlob(pattern, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmProtected runs an action on a branch straight away, or, for a
+// protected branch, once its name is typed in a prompt.
+func (m *model) confirmProtected(branch, what string, run func(m *model) tea.Cmd) tea.Cmd {
+	if !m.cfg.protected(branch) {
+		return run(m)
+	}
+	m.prompt = newPrompt(branch+" is protected; type its name to "+what, branch, func(m *model, value string) tea.Cmd {
+		if value != branch {
+			m.status, m.statusErr = "Left "+branch+" alone: the name didn't match", true
+			return nil
+		}
+		return run(m)
+	})
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reflogLimit is how many reflog entries of a branch are listed; older
+// ones are reached by date.
+const reflogLimit = 50
+
+// reflogEntry is one move of a branch: where it pointed after it, when,
+// and what moved it, like "commit: Fix the parser" or "reset: moving
+// to HEAD~2".
+type reflogEntry struct {
+	hash    string
+	short   string
+	when    time.Time
+	action  string
+	subject string
+}
+
+type reflogMsg struct {
+	branch  string
+	entries []reflogEntry
+	err     error
+}
+
+// loadReflog lists the latest moves of a local branch, newest first.
+func loadReflog(repoPath, branch string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "reflog", "show", "-z", "--date=unix", "-n", strconv.Itoa(reflogLimit),
+			"--format=%H%x00%h%x00%gd%x00%gs%x00%s", "refs/heads/"+branch, "--")
+		if err != nil {
+			return reflogMsg{branch: branch, err: err}
+		}
+		records, err := splitLog(out, 5)
+		if err != nil {
+			return reflogMsg{branch: branch, err: err}
+		}
+		var entries []reflogEntry
+		for _, f := range records {
+			e := reflogEntry{hash: f[0], short: f[1], action: f[3], subject: f[4]}
+			// With --date=unix the selector is main@{<seconds>}
+			if _, stamp, ok := strings.Cut(f[2], "@{"); ok {
+				if secs, err := strconv.ParseInt(strings.TrimSuffix(stamp, "}"), 10, 64); err == nil {
+					e.when = time.Unix(secs, 0)
+				}
+			}
+			entries = append(entries, e)
+		}
+		return reflogMsg{branch: branch, entries: entries}
+	}
+}
+
+// reflogPicker picks the branch whose reflog to show.
+func reflogPicker(branches []branchInfo) *menu {
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Reflog of which branch?"))
+	sb.WriteString("\n\n")
+	names := make([]string, len(branches))
+	for i, b := range branches {
+		names[i] = b.name
+	}
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	var options []menuOption
+	for i, key := range pickerKeys(names) {
+		if key == "" {
+			sb.WriteString("    " + branchStyle.Render(names[i]) + "\n")
+			continue
+		}
+		name := names[i]
+		options = append(options, menuOption{key: key, action: func(m *model) tea.Cmd {
+			return loadReflog(m.repoPath, name)
+		}})
+		sb.WriteString(keyStyle.Render(key) + "   " + branchStyle.Render(name) + "\n")
+	}
+	return &menu{title: "Reflog of", options: options, detail: sb.String()}
+}
+
+// reflogMenu lists where the branch pointed, newest first. The latest
+// ten are picked by their number, as in main@{3}; any other by when.
+func (m *model) reflogMenu(msg reflogMsg) *menu {
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Reflog of " + msg.branch))
+	sb.WriteString("\n\n")
+	if len(msg.entries) == 0 {
+		sb.WriteString(helpStyle.Render("No reflog: the branch hasn't moved since it was made, or core.logAllRefUpdates is off."))
+		return &menu{title: "Reflog", detail: sb.String()}
+	}
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	var options []menuOption
+	for i, e := range msg.entries {
+		selector := fmt.Sprintf("@{%d}", i)
+		key := " "
+		if i < 10 {
+			key = strconv.Itoa(i)
+			options = append(options, menuOption{key: key, action: func(m *model) tea.Cmd {
+				m.menu = m.reflogEntryMenu(msg.branch, selector, e)
+				return nil
+			}})
+		}
+		fmt.Fprintf(&sb, "%s %-6s %s  %s  %s\n", keyStyle.Render(key), selector, commitHashStyle.Render(e.short),
+			dateStyle.Render(e.when.Format("2006-01-02 15:04")), helpStyle.Render(relativeTime(e.when, m.now)))
+		sb.WriteString("         " + e.action + "\n")
+	}
+	if len(msg.entries) == reflogLimit {
+		sb.WriteString(helpStyle.Render(fmt.Sprintf("\nThe latest %d moves; t reaches further back.", reflogLimit)))
+	}
+	options = append(options, menuOption{key: "t", label: "at a time…", action: func(m *model) tea.Cmd {
+		m.prompt = newPrompt(msg.branch+" at", "last tuesday, 2.days.ago, 2024-03-01 12:00", func(m *model, value string) tea.Cmd {
+			if value == "" {
+				return nil
+			}
+			return reflogAt(m.repoPath, msg.branch, value)
+		})
+		return nil
+	}})
+	return &menu{title: "Reflog of " + msg.branch, options: options, detail: sb.String()}
+}
+
+// reflogAtMsg is where a branch pointed at a time.
+type reflogAtMsg struct {
+	branch string
+	when   string
+	entry  reflogEntry
+	err    error
+}
+
+// reflogAt finds where the branch pointed at a time, as git reads
+// main@{last tuesday}.
+func reflogAt(repoPath, branch, when string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "log", "-1", "--format=%H%x00%h%x00%s", "refs/heads/"+branch+"@{"+when+"}", "--")
+		fields := strings.Split(out, "\x00")
+		if err == nil && len(fields) != 3 {
+			err = fmt.Errorf("unexpected git log output %q", out)
+		}
+		if err != nil {
+			return reflogAtMsg{branch: branch, when: when, err: err}
+		}
+		return reflogAtMsg{branch: branch, when: when, entry: reflogEntry{hash: fields[0], short: fields[1], subject: fields[2]}}
+	}
+}
+
+// reflogEntryMenu offers to look at where a branch pointed, or to move
+// it back there.
+func (m *model) reflogEntryMenu(branch, selector string, e reflogEntry) *menu {
+	hash, short := e.hash, e.short
+	var sb strings.Builder
+	sb.WriteString(sectionHeader(branch + selector))
+	sb.WriteString("\n\n")
+	sb.WriteString(commitHashStyle.Render(short) + " " + e.subject)
+	sb.WriteString("\n\n")
+	current := branch == m.currentBranch
+	if current {
+		sb.WriteString(helpStyle.Render("Restoring the current branch resets it there with --keep: uncommitted changes stay, unless they touch files that differ, and then nothing happens."))
+	} else {
+		sb.WriteString(helpStyle.Render("Restoring moves the branch there; the commits it leaves stay in the reflog."))
+	}
+	options := []menuOption{
+		{key: "j", label: "jump to it", action: func(m *model) tea.Cmd {
+			if i := m.commitIndex(hash); i >= 0 {
+				return m.jumpTo(i)
+			}
+			m.status, m.statusErr = short+" isn't in the graph", true
+			return nil
+		}},
+		{key: "r", label: "restore " + branch + " to it", action: func(m *model) tea.Cmd {
+			return m.confirmProtected(branch, "restore it", func(m *model) tea.Cmd {
+				action := "Restored " + branch + " to " + short
+				if current {
+					return runGitCmd(m.repoPath, action, "reset", "--keep", hash)
+				}
+				return runGitCmd(m.repoPath, action, "branch", "--force", branch, hash)
+			})
+		}},
+	}
+	return &menu{title: branch + selector, options: options, detail: sb.String()}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// release is what went into a tag since the one before it, by version:
+// the commits of prev..tag, or of everything up to the first tag.
+type release struct {
+	tag, prev string
+	commits   int
+	from, to  time.Time // committer dates of the oldest and newest commit in it
+}
+
+// rangeSpec is the range of the release's commits, as git log takes it.
+func (r release) rangeSpec() string {
+	if r.prev == "" {
+		return r.tag
+	}
+	return r.prev + ".." + r.tag
+}
+
+// releaseView is the T overlay: the releases, newest first, with the
+// chosen one's commits opened as the graph's range on enter.
+type releaseView struct {
+	releases []release
+	cursor   int
+	glob     string // the tags listed, all when empty
+}
+
+type releasesMsg struct {
+	releases []release
+	glob     string
+	err      error
+}
+
+// loadReleases lists the tags that name a version, newest first, and
+// counts the commits each added over the one before. With a glob, like
+// v2.*, only the matching tags are listed; the first of them still
+// counts from the tag before it. Tags of something other than a commit
+// are left out.
+func loadReleases(repoPath, glob string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "for-each-ref", "--format=%(refname:short)%00%(objecttype)%00%(*objecttype)", "refs/tags")
+		if err != nil {
+			return releasesMsg{glob: glob, err: err}
+		}
+		var tags []string
+		for _, line := range strings.Split(out, "\n") {
+			// The type of what the tag points at, then of what an
+			// annotated tag points at in turn
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 3 || fields[1] != "commit" && fields[2] != "commit" {
+				continue
+			}
+			if _, ok := parseTagVersion(fields[0]); ok {
+				tags = append(tags, fields[0])
+			}
+		}
+		sortTags(tags)
+		slices.Reverse(tags)
+
+		var releases []release
+		for i, tag := range tags {
+			if glob != "" && !refGlob(glob, tag) {
+				continue
+			}
+			r := release{tag: tag}
+			if i+1 < len(tags) {
+				r.prev = tags[i+1]
+			}
+			out, err := gitRead(repoPath, "log", "--format=%ct", r.rangeSpec(), "--")
+			if err != nil {
+				return releasesMsg{glob: glob, err: fmt.Errorf("%s: %w", r.rangeSpec(), err)}
+			}
+			for _, line := range strings.Fields(out) {
+				secs, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					continue
+				}
+				when := time.Unix(secs, 0)
+				if r.commits == 0 || when.Before(r.from) {
+					r.from = when
+				}
+				if r.commits == 0 || when.After(r.to) {
+					r.to = when
+				}
+				r.commits++
+			}
+			releases = append(releases, r)
+		}
+		return releasesMsg{releases: releases, glob: glob}
+	}
+}
+
+// handleReleases opens the overlay once the releases are counted.
+func (m *model) handleReleases(msg releasesMsg) {
+	if msg.err != nil {
+		m.status, m.statusErr = "Listing releases failed: "+msg.err.Error(), true
+		return
+	}
+	if len(msg.releases) == 0 && msg.glob == "" {
+		m.status, m.statusErr = "No version tags to list releases by", true
+		return
+	}
+	m.status = ""
+	m.releases = &releaseView{releases: msg.releases, glob: msg.glob}
+}
+
+// updateReleases handles a key while the release overview is open:
+// enter shows the chosen release's commits, x every commit again, and /
+// lists only the tags matching a glob.
+func (m *model) updateReleases(msg tea.KeyMsg) tea.Cmd {
+	v := m.releases
+	switch msg.String() {
+	case "esc", "q", "ctrl+c", "T":
+		m.releases = nil
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = max(min(v.cursor+1, len(v.releases)-1), 0)
+	case "g", "home":
+		v.cursor = 0
+	case "G", "end":
+		v.cursor = max(len(v.releases)-1, 0)
+	case "/":
+		m.prompt = newPrompt("Tags matching", "v2.*, empty for all", func(m *model, value string) tea.Cmd {
+			m.tagGlob = value
+			m.status = "Counting the commits of each release…"
+			return loadReleases(m.repoPath, value)
+		})
+		m.prompt.input.SetValue(v.glob)
+		m.prompt.input.CursorEnd()
+	case "enter":
+		if len(v.releases) == 0 {
+			return nil
+		}
+		r := v.releases[v.cursor]
+		m.releases = nil
+		m.revRange = r.rangeSpec()
+		m.status = fmt.Sprintf("Showing %s: %s", r.tag, m.revRange)
+		return m.reload()
+	case "x":
+		if m.revRange == "" {
+			return nil
+		}
+		m.releases = nil
+		m.revRange = ""
+		m.status = "Showing every commit again"
+		return m.reload()
+	}
+	return nil
+}
+
+// renderReleases lists the releases around the chosen one: the tag, its
+// commits and the dates they span.
+func (m *model) renderReleases(height int) string {
+	v := m.releases
+	hint := "↑/↓: choose  enter: show its commits  /: only tags matching  esc: close"
+	if m.revRange != "" {
+		hint += "  x: show every commit"
+	}
+	title := "Releases"
+	if v.glob != "" {
+		title += " " + v.glob
+	}
+	lines := []string{sectionHeader(title), "", helpStyle.Render(hint), ""}
+	if len(v.releases) == 0 {
+		lines = append(lines, helpStyle.Render("No version tag matches "+v.glob+"."))
+	}
+	tagWidth := 0
+	for _, r := range v.releases {
+		tagWidth = max(tagWidth, len(r.tag))
+	}
+	rows := max(height-len(lines), 1)
+	first := max(min(v.cursor-rows/2, len(v.releases)-rows), 0)
+	for i := first; i < len(v.releases) && i < first+rows; i++ {
+		r := v.releases[i]
+		marker := "  "
+		if i == v.cursor {
+			marker = "> "
+		}
+		commits := fmt.Sprintf("%6d commits", r.commits)
+		if r.commits == 1 {
+			commits = "     1 commit "
+		}
+		line := marker + branchStyle.Render(fmt.Sprintf("%-*s", tagWidth, r.tag)) + "  " + commits
+		if r.commits > 0 {
+			line += "  " + dateStyle.Render(r.from.Format("2006-01-02")+" – "+r.to.Format("2006-01-02")) +
+				"  " + helpStyle.Render(relativeTime(r.to, m.now))
+		}
+		if m.revRange == r.rangeSpec() {
+			line += helpStyle.Render("  shown")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// remoteEnv is what to add to the environment of git commands that talk
+// to remotes while the UI owns the terminal. Anything that would prompt
+// on the terminal fails instead: git's own username/password prompt, ssh
+// asking for a passphrase or about an unknown host, and Git Credential
+// Manager's prompts. Credential helpers that have the credentials stored,
+// and keys loaded in ssh-agent (or Pageant), keep working as usual.
+func remoteEnv(repoPath string) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never"}
+	if os.Getenv("GIT_SSH_COMMAND") != "" || os.Getenv("GIT_SSH") != "" {
+		return env
+	}
+	// Don't override a configured ssh command with plain ssh
+	if out, err := gitRead(repoPath, "config", "--get", "core.sshCommand"); err == nil && strings.TrimSpace(out) != "" {
+		return env
+	}
+	return append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+}
+
+// authFailures are the messages of git, ssh and common hosts for a remote
+// operation that needed to ask for credentials.
+var authFailures = []string{
+	"terminal prompts disabled",
+	"could not read Username",
+	"could not read Password",
+	"Authentication failed",
+	"Invalid username or password",
+	"HTTP Basic: Access denied",
+	"Permission denied (publickey",
+	"Host key verification failed",
+}
+
+// runRemoteCmd is runGitCmd for commands that talk to a remote. It runs
+// without prompting (see remoteEnv); when that fails for lack of
+// credentials, the result says so and the command can be run again in
+// the terminal.
+func runRemoteCmd(repoPath, action string, args ...string) tea.Cmd {
+	return func() tea.Msg {
+		log.Printf("Running: git %s\n", strings.Join(args, " "))
+		cmd := gitCommand(repoPath, args...)
+		cmd.Env = append(cmd.Environ(), remoteEnv(repoPath)...)
+		out, err := cmd.CombinedOutput()
+		msg := gitDoneMsg{action: action, output: strings.TrimSpace(string(out)), err: err}
+		if err != nil {
+			for _, failure := range authFailures {
+				if strings.Contains(msg.output, failure) {
+					msg.authArgs = args
+					break
+				}
+			}
+			if failedOnLocalChanges(msg.output) {
+				msg.stashArgs = args
+			}
+		}
+		return msg
+	}
+}
+
+// runInTerminal hands the terminal over to git, so it can ask for a
+// password, passphrase or 2FA code itself, and picks the UI up again
+// when it is done.
+func runInTerminal(repoPath, action string, args ...string) tea.Cmd {
+	return tea.ExecProcess(gitCommand(repoPath, args...), func(err error) tea.Msg {
+		return gitDoneMsg{action: action, err: err}
+	})
+}
+
+// authMenu offers to run a command that needed credentials again in the
+// terminal.
+func authMenu(msg gitDoneMsg) *menu {
+	return &menu{
+		title: msg.action + " needs credentials",
+		options: []menuOption{{key: "enter", label: "run in terminal", action: func(m *model) tea.Cmd {
+			return runInTerminal(m.repoPath, msg.action, msg.authArgs...)
+		}}},
+		detail: sectionHeader("Authentication") + "\n\n" + msg.output + "\n\n" +
+			"git couldn't authenticate without asking. Enter runs it again in\n" +
+			"the terminal, where it can prompt for a password, passphrase or\n" +
+			"token. To avoid this, load your key into ssh-agent or set up a\n" +
+			"credential helper.",
+	}
+}
+
+// upstreamInfo describes the current branch and what it tracks.
+type upstreamInfo struct {
+	branch    string // empty when HEAD is detached
+	remote    string // remote of the upstream, empty when there is none
+	remoteRef string // branch on the remote, e.g. refs/heads/main
+	upstream  string // short name of the upstream, e.g. origin/main
+	sha       string // where the upstream was when loaded
+	behind    []string
+	stat      string
+	err       error
+}
+
+type pushInfoMsg upstreamInfo
+
+// loadUpstream looks up the upstream of the current branch, and the
+// commits it has that HEAD doesn't: what a force push would drop.
+func loadUpstream(repoPath string) upstreamInfo {
+	var info upstreamInfo
+
+	branch, err := gitRead(repoPath, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil || branch == "" {
+		info.err = fmt.Errorf("HEAD is detached")
+		return info
+	}
+	info.branch = branch
+
+	out, err := gitRead(repoPath, "for-each-ref", "--format=%(upstream:remotename)%00%(upstream:remoteref)%00%(upstream:short)", "refs/heads/"+branch)
+	if err != nil {
+		info.err = err
+		return info
+	}
+	fields := strings.Split(out, "\x00")
+	if len(fields) < 3 || fields[0] == "" {
+		return info
+	}
+	info.remote, info.remoteRef, info.upstream = fields[0], fields[1], fields[2]
+
+	// An upstream that was never fetched, or was deleted, has no sha
+	if info.sha, err = gitRead(repoPath, "rev-parse", "-q", "--verify", info.upstream); err != nil {
+		info.sha = ""
+		return info
+	}
+	if out, _ := gitRead(repoPath, "log", "--format=%h %s (%aN)", "HEAD.."+info.sha); out != "" {
+		info.behind = strings.Split(out, "\n")
+		info.stat, _ = gitRead(repoPath, "diff", "--stat", "HEAD", info.sha)
+	}
+	return info
+}
+
+func loadPushInfo(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		return pushInfoMsg(loadUpstream(repoPath))
+	}
+}
+
+// pushMenu offers the ways to push the current branch. Without an upstream
+// it pushes to a chosen remote and sets it. A force push only ever goes
+// through --force-with-lease, leased on the upstream commit that the
+// preview showed, so it can't drop anything that arrived since.
+func (m *model) pushMenu(info upstreamInfo) *menu {
+	if info.remote == "" {
+		if len(m.remotes) == 0 {
+			m.status, m.statusErr = "No remotes to push to", true
+			return nil
+		}
+		var options []menuOption
+		for i, key := range pickerKeys(m.remotes) {
+			if key == "" {
+				continue
+			}
+			remote := m.remotes[i]
+			options = append(options, menuOption{key: key, label: remote, action: func(m *model) tea.Cmd {
+				return runRemoteCmd(m.repoPath, "Pushed "+info.branch+" to "+remote, "push", "--set-upstream", remote, info.branch)
+			}})
+		}
+		return &menu{title: info.branch + " has no upstream; push and track on", options: options}
+	}
+
+	refspec := info.branch + ":" + info.remoteRef
+	options := []menuOption{{key: "p", label: "push", action: func(m *model) tea.Cmd {
+		return runRemoteCmd(m.repoPath, "Pushed to "+info.upstream, "push", info.remote, refspec)
+	}}}
+	mn := &menu{title: "Push " + info.branch + " to " + info.upstream}
+	if len(info.behind) > 0 && info.sha != "" {
+		lease := fmt.Sprintf("--force-with-lease=%s:%s", info.remoteRef, info.sha)
+		// Either end being protected guards it, so the name asked for is
+		// the one that matched
+		guarded := info.branch
+		if !m.cfg.protected(guarded) {
+			guarded = strings.TrimPrefix(info.remoteRef, "refs/heads/")
+		}
+		options = append(options, menuOption{key: "f", label: "force with lease", action: func(m *model) tea.Cmd {
+			return m.confirmProtected(guarded, "force-push it", func(m *model) tea.Cmd {
+				return runRemoteCmd(m.repoPath, "Force-pushed to "+info.upstream, "push", lease, info.remote, refspec)
+			})
+		}})
+		mn.title = fmt.Sprintf("Push %s to %s (behind by %d)", info.branch, info.upstream, len(info.behind))
+		mn.detail = fmt.Sprintf("%s\n\nA force push would drop these commits from %s:\n\n%s\n\n%s",
+			sectionHeader("Force with lease"), info.upstream, strings.Join(info.behind, "\n"), info.stat)
+	}
+	mn.options = options
+	return mn
+}
+
+// pullInfoMsg carries the upstream to pull from and how a plain git pull
+// would integrate it, as configured by pull.rebase and pull.ff.
+type pullInfoMsg struct {
+	upstreamInfo
+	mode string
+}
+
+func loadPullInfo(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		msg := pullInfoMsg{upstreamInfo: loadUpstream(repoPath), mode: "merge"}
+		get := func(key string) string {
+			value, _ := gitRead(repoPath, "config", "--get", key)
+			return value
+		}
+		if rebase := get("branch." + msg.branch + ".rebase"); rebase != "" && rebase != "false" {
+			msg.mode = "rebase"
+		} else if rebase := get("pull.rebase"); rebase != "" && rebase != "false" {
+			msg.mode = "rebase"
+		} else if get("pull.ff") == "only" {
+			msg.mode = "fast-forward only"
+		}
+		return msg
+	}
+}
+
+// pullDoneMsg is the gitDoneMsg of a pull, along with the commits it
+// brought in.
+type pullDoneMsg struct {
+	gitDoneMsg
+	incoming []string
+}
+
+// runPull pulls with the given extra arguments, then lists the commits
+// that arrived: those on the upstream that the old HEAD didn't have.
+func runPull(repoPath, upstream, action string, args ...string) tea.Cmd {
+	pull := runRemoteCmd(repoPath, action, append([]string{"pull"}, args...)...)
+	return func() tea.Msg {
+		before, _ := gitRead(repoPath, "rev-parse", "HEAD")
+
+		msg := pullDoneMsg{gitDoneMsg: pull().(gitDoneMsg)}
+		if msg.err != nil || before == "" {
+			return msg
+		}
+		out, _ := gitRead(repoPath, "rev-list", before+".."+upstream)
+		msg.incoming = strings.Fields(out)
+		return msg
+	}
+}
+
+// pullMenu offers the configured way of pulling, which is what enter
+// picks, or any of the three explicitly for this one pull.
+func (m *model) pullMenu(info pullInfoMsg) *menu {
+	if info.remote == "" {
+		m.status, m.statusErr = info.branch+" has no upstream to pull from", true
+		return nil
+	}
+	pull := func(action string, args ...string) func(m *model) tea.Cmd {
+		return func(m *model) tea.Cmd {
+			return runPull(m.repoPath, info.upstream, action, args...)
+		}
+	}
+	return &menu{title: "Pull " + info.upstream, options: []menuOption{
+		{key: "enter", label: info.mode + " (configured)", action: pull("Pulled " + info.upstream)},
+		{key: "r", label: "rebase", action: pull("Pulled "+info.upstream+" with rebase", "--rebase")},
+		{key: "m", label: "merge", action: pull("Pulled "+info.upstream+" with merge", "--no-rebase")},
+		{key: "f", label: "fast-forward only", action: pull("Pulled "+info.upstream+" fast-forward", "--ff-only")},
+	}}
+}
+
+// trackingMsg is how the current branch stands against its upstream.
+// Commits fetched in the background land in refs/prefetch, leaving the
+// remote-tracking branch alone, so they count as behind until a pull or
+// fetch brings the upstream up to date; until then they are fresh.
+type trackingMsg struct {
+	upstream      string
+	ahead, behind int
+	fresh         []string
+}
+
+func loadTracking(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+
+		branch, err := gitRead(repoPath, "symbolic-ref", "-q", "HEAD")
+		if err != nil {
+			return trackingMsg{}
+		}
+		upstream, _ := gitRead(repoPath, "for-each-ref", "--format=%(upstream)", branch)
+		if !strings.HasPrefix(upstream, "refs/remotes/") {
+			return trackingMsg{}
+		}
+		msg := trackingMsg{upstream: strings.TrimPrefix(upstream, "refs/remotes/")}
+
+		target := upstream
+		prefetch := "refs/prefetch/remotes/" + msg.upstream
+		if _, err := gitRead(repoPath, "rev-parse", "-q", "--verify", prefetch); err == nil {
+			if out, _ := gitRead(repoPath, "rev-list", upstream+".."+prefetch); out != "" {
+				msg.fresh = strings.Fields(out)
+				target = prefetch
+			}
+		}
+		out, err := gitRead(repoPath, "rev-list", "--left-right", "--count", "HEAD..."+target)
+		if err == nil {
+			fmt.Sscanf(out, "%d %d", &msg.ahead, &msg.behind)
+		}
+		return msg
+	}
+}
+
+type fetchTickMsg struct{}
+
+func scheduleFetch(minutes int) tea.Cmd {
+	if minutes <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(minutes)*time.Minute, func(time.Time) tea.Msg {
+		return fetchTickMsg{}
+	})
+}
+
+type fetchDoneMsg struct {
+	err   error
+	moved bool // a ref moved, or came or went
+}
+
+// backgroundFetch prefetches every remote the way git maintenance does:
+// into refs/prefetch, so remote-tracking branches only move when asked
+// to. It never prompts; a remote that needs credentials just fails, and
+// the failure only goes to the log.
+func backgroundFetch(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		refs := func() string {
+			out, _ := gitRead(repoPath, "for-each-ref", "--format=%(objectname) %(refname)")
+			return out
+		}
+		before := refs()
+		cmd := gitCommand(repoPath, "fetch", "--all", "--prefetch", "--quiet")
+		cmd.Env = append(cmd.Environ(), remoteEnv(repoPath)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			log.Printf("Background fetch failed: %v\n%s\n", err, out)
+		}
+		return fetchDoneMsg{err: err, moved: refs() != before}
+	}
+}
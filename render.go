@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// runRender implements `gitraffe render`: one frame of the UI, as it
+// would be drawn in a window of the given size, printed to stdout without
+// starting the UI. Frames can be compared with golden files in tests, or
+// attached to a bug report as they are.
+func runRender(args []string) error {
+	// The UI logs to gitraffe.log; here the log would go to stderr
+	log.SetOutput(io.Discard)
+
+	var opts options
+	var width, height int
+	var frame []string
+	var color bool
+	fs := flag.NewFlagSet("gitraffe render", flag.ContinueOnError)
+	fs.IntVar(&width, "width", 120, "window width in `columns`")
+	fs.IntVar(&height, "height", 40, "window height in `lines`")
+	fs.Var((*stringList)(&frame), "frame", "set up the frame with `key=value`: selected=<n or hash>, focus=0|1|2, tab=commit|diff|files|refs, scroll=<n>, zoom; repeatable, applied in order")
+	fs.BoolVar(&color, "color", false, "keep the colors, as ANSI escapes")
+	fs.StringVar(&opts.revRange, "range", "", "only show the commits in `revspec`, e.g. v1.2.0..HEAD")
+	fs.StringVar(&opts.ref, "ref", "", "start the graph at `ref` instead of showing all refs")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gitraffe render [flags] [path]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		exitUsage(err)
+	}
+	opts.repoPath = "."
+	if fs.NArg() > 0 {
+		opts.repoPath = normalizeRepoPath(fs.Arg(0))
+	}
+
+	// The same frame whether stdout is a terminal or a file
+	if color {
+		lipgloss.SetColorProfile(termenv.TrueColor)
+	} else {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	out, err := renderFrame(opts, width, height, frame)
+	if err != nil {
+		return err
+	}
+	w, done := startPager(opts.repoPath)
+	fmt.Fprintln(w, out)
+	done()
+	return nil
+}
+
+// renderFrame loads the repository into a model, sizes it and applies
+// the frame settings, and returns what it would draw.
+func renderFrame(opts options, width, height int, frame []string) (string, error) {
+	m := initialModel(opts)
+	var tm tea.Model = m
+	tm = settle(tm, loadRepo(m.repoPath, m.backend), loadWorkTreeStatus(m.repoPath), loadTracking(m.repoPath), loadStack(m.repoPath, m.base))
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: width, Height: height})
+
+	m = tm.(model)
+	if m.err != nil {
+		return "", m.err
+	}
+	for _, setting := range frame {
+		if err := m.setFrame(setting); err != nil {
+			return "", err
+		}
+	}
+	tm = settle(m, m.maybeLoadDetails())
+	return tm.View(), nil
+}
+
+// settle runs commands to the end, one after the other, feeding what
+// they return to the model along with any commands that leads to. Only
+// loading commands go in: a tick would wait for its timer.
+func settle(tm tea.Model, cmds ...tea.Cmd) tea.Model {
+	for _, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		switch msg := cmd().(type) {
+		case nil:
+		case tea.BatchMsg:
+			tm = settle(tm, msg...)
+		default:
+			var next tea.Cmd
+			tm, next = tm.Update(msg)
+			tm = settle(tm, next)
+		}
+	}
+	return tm
+}
+
+// setFrame applies one -frame setting of `gitraffe render`.
+func (m *model) setFrame(setting string) error {
+	key, value, _ := strings.Cut(setting, "=")
+	switch key {
+	case "selected":
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			// A hash, full or abbreviated, naming one commit
+			i = -1
+			for j, c := range m.commits {
+				if value == "" || !strings.HasPrefix(c.FullHash.String(), value) {
+					continue
+				}
+				if i >= 0 {
+					return fmt.Errorf("%q is ambiguous: %s and %s are in the graph", value, m.commits[i].FullHash.String(), c.FullHash.String())
+				}
+				i = j
+			}
+			if i < 0 {
+				return fmt.Errorf("no commit %q in the graph", value)
+			}
+		}
+		m.selectCommit(i)
+	case "focus":
+		box, err := strconv.Atoi(value)
+		if err != nil || box < 0 || box > 2 {
+			return fmt.Errorf("focus=%s: give 0, 1 or 2", value)
+		}
+		m.focus(box)
+	case "tab":
+		for i, name := range detailTabNames {
+			if strings.EqualFold(name, value) {
+				m.detailTab = i
+				return nil
+			}
+		}
+		return fmt.Errorf("tab=%s: give commit, diff, files or refs", value)
+	case "scroll":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("scroll=%s: give a number of lines", value)
+		}
+		m.detailsScroll[m.detailTab] = n
+	case "zoom":
+		m.zoomed = value == "" || gitBool(value)
+	default:
+		return fmt.Errorf("unknown frame setting %q", setting)
+	}
+	return nil
+}
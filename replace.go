@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// rewrittenStyle marks commits whose parents aren't the ones stored in
+// the commit object.
+var rewrittenStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#B48EAD"))
+
+// noReplaceObjects follows the model's noReplace, the R toggle, for
+// gitCommand, which adds GIT_NO_REPLACE_OBJECTS to the git commands it
+// builds while it is set. gitraffe's own environment stays as it was, so
+// the editor, the shell of ! and the pager don't pick it up. go-git
+// doesn't read replace refs at all.
+var noReplaceObjects atomic.Bool
+
+// splitRewritten takes the "replaced" and "grafted" decorations git log
+// adds out of a commit's refs. git decorates a commit "replaced" when a
+// replace ref (git replace, git replace --graft) stands in for it, and
+// "grafted" when a shallow clone cuts the history off at it.
+func splitRewritten(refs string) (string, string) {
+	if !strings.Contains(refs, "replaced") && !strings.Contains(refs, "grafted") {
+		return refs, ""
+	}
+	var kept []string
+	rewritten := ""
+	for _, ref := range strings.Split(refs, ", ") {
+		if ref == "replaced" || ref == "grafted" {
+			rewritten = ref
+			continue
+		}
+		kept = append(kept, ref)
+	}
+	return strings.Join(kept, ", "), rewritten
+}
+
+// rewrittenMarker is shown after the hash of a replaced or grafted commit.
+func rewrittenMarker(c commit) string {
+	switch c.Rewritten {
+	case "replaced":
+		return rewrittenStyle.Render(" ≠")
+	case "grafted":
+		return rewrittenStyle.Render(" ✂")
+	}
+	return ""
+}
+
+// rewrittenNote explains the marker in the details panel.
+func rewrittenNote(c commit) string {
+	switch c.Rewritten {
+	case "replaced":
+		return "≠ replaced: a replace ref stands in for this commit, so its parents or content differ from the original (R shows the original history)"
+	case "grafted":
+		return "✂ grafted: the shallow clone ends here; git fetch --deepen or --unshallow brings in its parents"
+	}
+	return ""
+}
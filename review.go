@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// reviewedStyle marks reviewed commits and files.
+var reviewedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C"))
+
+// stateDir is where gitraffe keeps what it remembers about a repository:
+// a gitraffe directory in the git dir, shared by all its worktrees, so it
+// is never committed and goes away with the clone.
+func stateDir(repoPath string) (string, error) {
+	dir, err := gitRead(repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return filepath.Join(dir, "gitraffe"), nil
+}
+
+// reviewState is what has been marked reviewed: whole commits, and files
+// within commits. It is saved in the state dir on every change, one
+// "<hash>" or "<hash> TAB <path>" per line.
+type reviewState struct {
+	path    string
+	commits map[string]bool
+	files   map[string]map[string]bool
+}
+
+func loadReviewState(repoPath string) *reviewState {
+	r := &reviewState{commits: map[string]bool{}, files: map[string]map[string]bool{}}
+	dir, err := stateDir(repoPath)
+	if err != nil {
+		return r
+	}
+	r.path = filepath.Join(dir, "reviewed")
+	f, err := os.Open(r.path)
+	if err != nil {
+		return r
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, file, isFile := strings.Cut(scanner.Text(), "\t")
+		switch {
+		case hash == "":
+		case isFile:
+			r.setFile(hash, file, true)
+		default:
+			r.commits[hash] = true
+		}
+	}
+	return r
+}
+
+func (r *reviewState) save() error {
+	if r.path == "" {
+		return fmt.Errorf("no git dir to save in")
+	}
+	var lines []string
+	for hash := range r.commits {
+		lines = append(lines, hash)
+	}
+	for hash, files := range r.files {
+		for file := range files {
+			lines = append(lines, hash+"\t"+file)
+		}
+	}
+	sort.Strings(lines)
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func (r *reviewState) setFile(hash, file string, reviewed bool) {
+	if r.files[hash] == nil {
+		r.files[hash] = map[string]bool{}
+	}
+	if reviewed {
+		r.files[hash][file] = true
+	} else {
+		delete(r.files[hash], file)
+	}
+}
+
+// toggleCommit marks a commit reviewed, or not, with all its files.
+func (r *reviewState) toggleCommit(c commit) {
+	if r.commits[c.FullHash.String()] {
+		delete(r.commits, c.FullHash.String())
+		delete(r.files, c.FullHash.String())
+	} else {
+		r.commits[c.FullHash.String()] = true
+	}
+}
+
+// toggleFile marks one file of a commit; reviewing its last file
+// reviews the commit.
+func (r *reviewState) toggleFile(c commit, file string) {
+	if r.fileReviewed(c.FullHash.String(), file) {
+		delete(r.commits, c.FullHash.String())
+		for _, f := range c.Files {
+			r.setFile(c.FullHash.String(), f.Path, f.Path != file)
+		}
+		return
+	}
+	r.setFile(c.FullHash.String(), file, true)
+	for _, f := range c.Files {
+		if !r.files[c.FullHash.String()][f.Path] {
+			return
+		}
+	}
+	r.commits[c.FullHash.String()] = true
+}
+
+func (r *reviewState) fileReviewed(hash, file string) bool {
+	return r.commits[hash] || r.files[hash][file]
+}
+
+// reviewMarker is shown after the hash of a reviewed commit, or one with
+// some of its files reviewed.
+func (m *model) reviewMarker(c commit) string {
+	switch {
+	case m.review.commits[c.FullHash.String()]:
+		return reviewedStyle.Render(" ✓")
+	case len(m.review.files[c.FullHash.String()]) > 0:
+		return reviewedStyle.Render(" ◐")
+	}
+	return ""
+}
+
+// reviewProgress counts the reviewed commits among those in the graph.
+func (m *model) reviewProgress() (reviewed, marked int) {
+	for _, c := range m.commits {
+		if m.review.commits[c.FullHash.String()] {
+			reviewed++
+		} else if len(m.review.files[c.FullHash.String()]) > 0 {
+			marked++
+		}
+	}
+	return reviewed, marked
+}
+
+// saveReview writes the review state, reporting a failure in the status
+// line.
+func (m *model) saveReview() {
+	m.dataVersion++
+	if err := m.review.save(); err != nil {
+		m.status, m.statusErr = "Couldn't save the review: "+err.Error(), true
+	}
+}
+
+// updateFilesTab handles the keys of the Files tab that work on the file
+// cursor instead of scrolling.
+func (m *model) updateFilesTab(msg tea.KeyMsg) bool {
+	c := &m.commits[m.selected]
+	if len(c.Files) == 0 {
+		return false
+	}
+	switch msg.String() {
+	case "j", "down":
+		m.filesCursor = min(m.filesCursor+1, len(c.Files)-1)
+	case "k", "up":
+		m.filesCursor = max(m.filesCursor-1, 0)
+	case "v":
+		if m.filesCursor >= len(c.Files) {
+			return true
+		}
+		m.review.toggleFile(*c, sortedFiles(c)[m.filesCursor].Path)
+		m.saveReview()
+		return true
+	default:
+		return false
+	}
+
+	// Keep the cursor in view
+	_, fileLines := m.filesTabLines(c)
+	line := fileLines[m.filesCursor]
+	visible := max(m.windowHeight-12, 3)
+	if line < m.detailsScroll[tabFiles] {
+		m.detailsScroll[tabFiles] = line
+	} else if line >= m.detailsScroll[tabFiles]+visible {
+		m.detailsScroll[tabFiles] = line - visible + 1
+	}
+	return true
+}
+
+// nextUnreviewed selects the next commit down the graph that isn't
+// reviewed yet.
+func (m *model) nextUnreviewed() tea.Cmd {
+	for i := m.selected + 1; i < len(m.commits); i++ {
+		if !m.review.commits[m.commits[i].FullHash.String()] {
+			return m.jumpTo(i)
+		}
+	}
+	m.status = "No unreviewed commits below this one"
+	return nil
+}
+
+// sortedFiles is the order the Files tab lists a commit's files in.
+func sortedFiles(c *commit) []fileChange {
+	files := make([]fileChange, len(c.Files))
+	copy(files, c.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
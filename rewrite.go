@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// e edits a commit of the checked-out branch: rewords its message, in
+// the commit editor, or changes its author. HEAD is amended; an older
+// commit is made again with the change, and the commits after it rebased
+// onto it, as git rebase -i's reword would, the branches among them
+// following. Commits already on a remote branch are only rewritten once
+// that is confirmed, as they will no longer match the remote's.
+
+// rewriteTarget is a commit about to be rewritten.
+type rewriteTarget struct {
+	hash, short string
+	head        bool     // the commit is HEAD
+	after       int      // commits of the branch after it
+	remotes     []string // remote branches that have it
+	message     string
+	author      string // as "Name <email>"
+}
+
+type rewriteInfoMsg struct {
+	target rewriteTarget
+	author bool // change the author rather than the message
+	err    error
+}
+
+// editMenu offers the changes e can make to the selected commit.
+func (m *model) editMenu() *menu {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	load := func(author bool) func(m *model) tea.Cmd {
+		return func(m *model) tea.Cmd {
+			return loadRewriteInfo(m.repoPath, c.FullHash.String(), c.Hash, author)
+		}
+	}
+	return &menu{title: "Edit " + c.Hash, options: []menuOption{
+		{key: "r", label: "reword", action: load(false)},
+		{key: "a", label: "author", action: load(true)},
+	}}
+}
+
+// loadRewriteInfo checks that a commit is on the checked-out branch, and
+// finds what rewriting it touches and its message and author.
+func loadRewriteInfo(repoPath, hash, short string, author bool) tea.Cmd {
+	return func() tea.Msg {
+		t := rewriteTarget{hash: hash, short: short}
+		msg := rewriteInfoMsg{target: t, author: author}
+		if _, err := gitRead(repoPath, "merge-base", "--is-ancestor", hash, "HEAD"); err != nil {
+			msg.err = fmt.Errorf("%s isn't on the checked-out branch", short)
+			return msg
+		}
+		head, _ := gitRead(repoPath, "rev-parse", "HEAD")
+		t.head = head == hash
+		count, _ := gitRead(repoPath, "rev-list", "--count", hash+"..HEAD")
+		fmt.Sscan(count, &t.after)
+		remotes, _ := gitRead(repoPath, "for-each-ref", "--contains", hash, "--format=%(refname:short)", "refs/remotes")
+		for _, r := range strings.Fields(remotes) {
+			if !strings.HasSuffix(r, "/HEAD") {
+				t.remotes = append(t.remotes, r)
+			}
+		}
+		out, err := gitRead(repoPath, "log", "-1", "--format=%an <%ae>%x00%B", hash)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		t.author, t.message, _ = strings.Cut(strings.TrimRight(out, "\n"), "\x00")
+		msg.target = t
+		return msg
+	}
+}
+
+// handleRewriteInfo opens the commit editor or author prompt for a
+// commit, past a confirmation when a remote has it.
+func (m *model) handleRewriteInfo(msg rewriteInfoMsg) {
+	t := msg.target
+	if msg.err != nil {
+		m.status, m.statusErr = "Can't edit "+t.short+": "+msg.err.Error(), true
+		return
+	}
+	edit := func(m *model) tea.Cmd {
+		if msg.author {
+			m.prompt = newPrompt("Author of "+t.short, "Name <email>", func(m *model, value string) tea.Cmd {
+				if !authorPattern.MatchString(value) {
+					m.status, m.statusErr = "Give the author as Name <email>", true
+					return nil
+				}
+				if value == t.author {
+					return nil
+				}
+				return rewriteCommit(m.repoPath, t, "", value)
+			})
+			m.prompt.input.SetValue(t.author)
+			m.prompt.input.CursorEnd()
+			return nil
+		}
+		m.commit = newCommitEditor(t.message)
+		m.commit.reword = &t
+		m.focus(2)
+		return nil
+	}
+	if len(t.remotes) == 0 {
+		edit(m)
+		return
+	}
+	on := t.remotes[0]
+	if len(t.remotes) > 1 {
+		on += fmt.Sprintf(" and %d more", len(t.remotes)-1)
+	}
+	rewritten := "it"
+	if t.after > 0 {
+		rewritten = fmt.Sprintf("it and the %d commits after it", t.after)
+	}
+	m.menu = &menu{
+		title: fmt.Sprintf("%s is on %s; rewriting %s needs a force push", t.short, on, rewritten),
+		options: []menuOption{
+			{key: "y", label: "rewrite anyway", action: edit},
+		},
+	}
+}
+
+var authorPattern = regexp.MustCompile(`^[^<>]*[^<>\s][^<>]* <[^<>]+>$`)
+
+// rewriteCommit gives a commit a new message or author, whichever isn't
+// empty. The commits after it are rebased onto the new one, and other
+// branches at the commit itself moved to it.
+func rewriteCommit(repoPath string, t rewriteTarget, message, author string) tea.Cmd {
+	return rewriteCommitStashed(repoPath, t, message, author, false)
+}
+
+// rewriteCommitStashed is rewriteCommit, with the rebase stashing the
+// local changes first when autostash is set. Refused over local changes,
+// the whole rewrite is offered again that way, so that the branches at
+// the commit are moved too.
+func rewriteCommitStashed(repoPath string, t rewriteTarget, message, author string, autostash bool) tea.Cmd {
+	action := "Reworded " + t.short
+	if author != "" {
+		action = "Changed the author of " + t.short
+	}
+	return func() tea.Msg {
+		// The rebase moves the branches at the commits after this one, not
+		// those at it, and an amend only the current branch
+		current, _ := gitRead(repoPath, "symbolic-ref", "-q", "HEAD")
+		refs, _ := gitRead(repoPath, "for-each-ref", "--points-at", t.hash, "--format=%(refname)", "refs/heads")
+		var others []string
+		for _, ref := range strings.Fields(refs) {
+			if ref != current {
+				others = append(others, ref)
+			}
+		}
+
+		var replacement string
+		done := gitDoneMsg{action: action}
+		if t.head {
+			// --only leaves whatever is staged out of the commit
+			args := []string{"commit", "--amend", "--only", "--allow-empty"}
+			var msg gitDoneMsg
+			if author != "" {
+				msg = runGitCmd(repoPath, action, append(args, "--no-edit", "--author="+author)...)().(gitDoneMsg)
+			} else {
+				msg = runGitCmdInput(repoPath, action, message, append(args, "--cleanup=strip", "-F", "-")...)().(gitDoneMsg)
+			}
+			if msg.err != nil {
+				return msg
+			}
+			replacement, _ = gitRead(repoPath, "rev-parse", "HEAD")
+		} else {
+			var err error
+			replacement, err = recommit(repoPath, t.hash, message, author)
+			if err != nil {
+				return gitDoneMsg{action: action, err: err}
+			}
+			// The new commit has the old one's tree, so the rest replays as
+			// before; merges are made again and branches moved along
+			args := []string{"rebase", "--rebase-merges", "--update-refs", "--no-autosquash", "--onto", replacement, t.hash}
+			if autostash {
+				args = slices.Insert(args, 1, "--autostash")
+			}
+			out, err := gitRun(repoPath, args...)
+			if autostash {
+				// Telling whether the changes came back
+				done = autostashed(repoPath, gitDoneMsg{action: action, output: out, err: err})
+			}
+			if err != nil && !autostash {
+				done = gitDoneMsg{action: action, output: out, err: err}
+				if failedOnLocalChanges(out) {
+					done.stashRetry = rewriteCommitStashed(repoPath, t, message, author, true)
+				}
+			}
+			if err != nil {
+				return done
+			}
+		}
+		for _, ref := range others {
+			if out, err := gitRun(repoPath, "update-ref", ref, replacement, t.hash); err != nil {
+				return gitDoneMsg{action: action + ", but not moving " + strings.TrimPrefix(ref, "refs/heads/"), output: out, err: err}
+			}
+		}
+		return done
+	}
+}
+
+// recommit makes a commit again with another message or author, on the
+// same tree and parents, and returns the new commit's hash.
+func recommit(repoPath, hash, message, author string) (string, error) {
+	out, err := gitRead(repoPath, "log", "-1", "--format=%T%x00%P%x00%an%x00%ae%x00%ad%x00%B", "--date=raw", hash)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.SplitN(out, "\x00", 6)
+	if len(fields) != 6 {
+		return "", fmt.Errorf("can't read %s", hash)
+	}
+	tree, parents, name, email, date, body := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+	if author != "" {
+		name, email, _ = strings.Cut(strings.TrimSuffix(author, ">"), " <")
+	}
+	if message == "" {
+		message = body
+	}
+	stripspace := gitCommand(repoPath, "stripspace")
+	stripspace.Stdin = strings.NewReader(message)
+	message, err = cmdOutput(stripspace)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"commit-tree", tree, "-F", "-"}
+	for _, p := range strings.Fields(parents) {
+		args = append(args, "-p", p)
+	}
+	cmd := gitCommand(repoPath, args...)
+	cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date)
+	cmd.Stdin = strings.NewReader(message + "\n")
+	return cmdOutput(cmd)
+}
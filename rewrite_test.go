@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteCommit(t *testing.T) {
+	d := testRepo(t)
+	d.commit("f", "f\n", "Write f")
+	d.commit("g", "g\n", "Write g")
+	d.git("checkout", "-q", "-b", "side")
+	d.commit("s", "s\n", "Write s")
+	d.git("checkout", "-q", "main")
+	d.commit("h", "h\n", "Write h")
+	d.git("branch", "mid")
+	d.merge("Merge side", "side")
+	d.commit("i", "i\n", "Write i")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	// Each commit's tree, author date and parent count, newest first
+	shape := func() string {
+		return gitOutput(t, d.dir, "log", "--format=%T %ad %p", "--date=raw", "main")
+	}
+	sameShape := func(before string) {
+		t.Helper()
+		after := shape()
+		strip := func(log string) []string {
+			var lines []string
+			for _, line := range strings.Split(log, "\n") {
+				fields := strings.Fields(line)
+				lines = append(lines, strings.Join(fields[:3], " ")+" "+strings.Repeat("p", len(fields)-3))
+			}
+			return lines
+		}
+		if a, b := strip(before), strip(after); strings.Join(a, "\n") != strings.Join(b, "\n") {
+			t.Errorf("trees, dates or merges changed:\n%s\nnow\n%s", before, after)
+		}
+	}
+	target := func(rev string, author bool) rewriteTarget {
+		t.Helper()
+		hash := gitOutput(t, d.dir, "rev-parse", rev)
+		msg := loadRewriteInfo(d.dir, hash, hash[:7], author)().(rewriteInfoMsg)
+		if msg.err != nil {
+			t.Fatal(msg.err)
+		}
+		return msg.target
+	}
+	rewrite := func(tg rewriteTarget, message, author string) {
+		t.Helper()
+		if msg := rewriteCommit(d.dir, tg, message, author)().(gitDoneMsg); msg.err != nil {
+			t.Fatalf("%s failed: %v %q", msg.action, msg.err, msg.output)
+		}
+	}
+
+	before := shape()
+	g := target(":/Write g", false)
+	if g.head || g.after != 4 {
+		t.Fatalf("target %+v, want the fifth commit from HEAD", g)
+	}
+	rewrite(g, "Write g, reworded\n", "")
+	sameShape(before)
+	if got := gitOutput(t, d.dir, "log", "-1", "--format=%s", ":/Write g"); got != "Write g, reworded" {
+		t.Errorf("reworded subject = %q", got)
+	}
+	reworded := gitOutput(t, d.dir, "rev-parse", ":/Write g, reworded")
+	for _, branch := range []string{"side", "mid"} {
+		if base := gitOutput(t, d.dir, "rev-parse", branch+"~1"); base != reworded {
+			t.Errorf("%s~1 = %s, want the branch moved onto the reworded %s", branch, base, reworded)
+		}
+	}
+
+	before = shape()
+	h := target("mid", true)
+	rewrite(h, "", "Bo Tree <bo@example.com>")
+	sameShape(before)
+	if got := gitOutput(t, d.dir, "log", "-1", "--format=%an <%ae> %s", "mid"); got != "Bo Tree <bo@example.com> Write h" {
+		t.Errorf("mid is %q, want Write h by Bo Tree", got)
+	}
+	if got := gitOutput(t, d.dir, "log", "-1", "--format=%an", "main"); got != "Ada Graph" {
+		t.Errorf("the commits after keep their author, HEAD's is %q", got)
+	}
+
+	// HEAD is amended, and a branch at it moved along
+	d.git("branch", "tip")
+	before = shape()
+	rewrite(target("HEAD", false), "Write i, reworded\n", "")
+	sameShape(before)
+	if got := gitOutput(t, d.dir, "log", "-1", "--format=%s", "tip"); got != "Write i, reworded" {
+		t.Errorf("tip is at %q, want the reworded HEAD", got)
+	}
+}
+
+// TestRewriteCommitAutostash rewords a commit with local changes in the
+// way: the rewrite offered again, stashed, moves the branch at the
+// commit too, and brings the changes back.
+func TestRewriteCommitAutostash(t *testing.T) {
+	d := testRepo(t)
+	d.commit("f", "f\n", "Write f")
+	d.commit("g", "g\n", "Write g")
+	d.git("branch", "at-f", "HEAD~1")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	path := filepath.Join(d.dir, "g")
+	if err := os.WriteFile(path, []byte("g, changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := gitOutput(t, d.dir, "rev-parse", "HEAD~1")
+	info := loadRewriteInfo(d.dir, hash, hash[:7], false)().(rewriteInfoMsg)
+	if info.err != nil {
+		t.Fatal(info.err)
+	}
+	msg := rewriteCommit(d.dir, info.target, "Write f, reworded\n", "")().(gitDoneMsg)
+	if msg.err == nil || msg.stashRetry == nil {
+		t.Fatalf("rewrite over local changes: err %v, retry offered %v", msg.err, msg.stashRetry != nil)
+	}
+	msg = msg.stashRetry().(gitDoneMsg)
+	if msg.err != nil || len(msg.stashConflicts) > 0 {
+		t.Fatalf("autostashed rewrite failed: %v %q", msg.err, msg.output)
+	}
+	if got := gitOutput(t, d.dir, "log", "-1", "--format=%s", "at-f"); got != "Write f, reworded" {
+		t.Errorf("at-f is at %q, want it moved to the reworded commit", got)
+	}
+	if base := gitOutput(t, d.dir, "rev-parse", "main~1"); base != gitOutput(t, d.dir, "rev-parse", "at-f") {
+		t.Errorf("main~1 = %s, want at-f", base)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "g, changed\n" {
+		t.Errorf("g = %q, want the local change back", got)
+	}
+}
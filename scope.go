@@ -0,0 +1,252 @@
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// The graph shows the history of one scope, whichever backend loads it:
+// a range (--range), one ref (--ref or gitraffe.ref, HEAD for just the
+// current branch), or else all refs but the excluded ones. logScope
+// gives it to git log; goGitStarts is the same for go-git.
+
+// scopeExcludes are the patterns of refs left out of the all-refs
+// graph: the excluded ones, and refs that don't hold the project's
+// history. A replacement commit already shows up in place of the one it
+// replaces; its ref would list it a second time. Notes refs hold commits
+// of notes, not of the project.
+func (m *model) scopeExcludes() []string {
+	return append([]string{"refs/replace/*", "refs/notes/*"}, m.exclude...)
+}
+
+// refGlob matches a ref name against a pattern like git's --exclude,
+// where * also matches across slashes.
+func refGlob(pattern, name string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	ok, _ := regexp.MatchString("^"+expr+"$", name)
+	return ok
+}
+
+// goGitStarts resolves the scope to the commits go-git walks from, and
+// those whose history it leaves out, as in A..B. go-git takes only that
+// form of range.
+func (m *model) goGitStarts() (from, ignore []plumbing.Hash, err error) {
+	resolve := func(rev string) (plumbing.Hash, error) {
+		h, err := m.repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("%s: %w", rev, err)
+		}
+		return *h, nil
+	}
+	switch {
+	case m.revRange != "":
+		a, b, ok := strings.Cut(m.revRange, "..")
+		if !ok || strings.HasPrefix(b, ".") || strings.HasPrefix(m.revRange, "-") {
+			return nil, nil, fmt.Errorf("go-git only takes ranges like A..B, not %q; the cli backend takes any", m.revRange)
+		}
+		a, b = cmp.Or(a, "HEAD"), cmp.Or(b, "HEAD")
+		to, err := resolve(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		upto, err := resolve(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []plumbing.Hash{to}, []plumbing.Hash{upto}, nil
+	case m.ref != "":
+		h, err := resolve(m.ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []plumbing.Hash{h}, nil, nil
+	}
+
+	refs, err := m.repo.References()
+	if err != nil {
+		return nil, nil, err
+	}
+	excludes := m.scopeExcludes()
+	err = refs.ForEach(func(r *plumbing.Reference) error {
+		if r.Type() != plumbing.HashReference {
+			// Symbolic refs, like HEAD on a branch, point at refs
+			// walked anyway
+			return nil
+		}
+		for _, pattern := range excludes {
+			if refGlob(pattern, r.Name().String()) {
+				return nil
+			}
+		}
+		h := r.Hash()
+		if tag, err := m.repo.TagObject(h); err == nil {
+			// An annotated tag; a tag of a tree or blob has no history
+			c, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			h = c.Hash
+		}
+		from = append(from, h)
+		return nil
+	})
+	return from, nil, err
+}
+
+// goGitWalk calls fn for the commits of the scope, newest commit date
+// first like git log without --graph, until fn returns an error.
+// Parents missing from a shallow clone end the history there.
+func (m *model) goGitWalk(fn func(*object.Commit) error) error {
+	from, ignore, err := m.goGitStarts()
+	if err != nil {
+		return err
+	}
+	seen := map[plumbing.Hash]bool{}
+	for _, h := range ignore {
+		c, err := m.repo.CommitObject(h)
+		if err != nil {
+			return err
+		}
+		err = object.NewCommitPreorderIter(c, seen, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		})
+		if err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+			return err
+		}
+	}
+
+	queue := &commitQueue{}
+	push := func(h plumbing.Hash) error {
+		if seen[h] {
+			return nil
+		}
+		seen[h] = true
+		c, err := m.repo.CommitObject(h)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+	for _, h := range from {
+		if err := push(h); err != nil {
+			return err
+		}
+	}
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(*object.Commit)
+		if err := fn(c); err != nil {
+			return err
+		}
+		for _, p := range c.ParentHashes {
+			if err := push(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commitQueue is a heap of commits, the newest commit date on top.
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// goGitRefs decorates commits with the refs pointing at them, the way
+// git log's %D does for logScope: HEAD first, then the other refs in
+// reverse order of their names, leaving out refs/prefetch and
+// scopeExcludes.
+func (m *model) goGitRefs() (map[plumbing.Hash]string, error) {
+	refs, err := m.repo.References()
+	if err != nil {
+		return nil, err
+	}
+	excludes := append([]string{"refs/prefetch/*"}, m.scopeExcludes()...)
+	var names []string
+	targets := map[string]plumbing.Hash{}
+	err = refs.ForEach(func(r *plumbing.Reference) error {
+		name := r.Name().String()
+		if name == "HEAD" || slices.ContainsFunc(excludes, func(p string) bool { return refGlob(p, name) }) {
+			return nil
+		}
+		resolved, err := m.repo.Reference(r.Name(), true)
+		if err != nil {
+			// A symbolic ref to a branch that isn't there
+			return nil
+		}
+		h := resolved.Hash()
+		if tag, err := m.repo.TagObject(h); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			h = c.Hash
+		}
+		names = append(names, name)
+		targets[name] = h
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	slices.Reverse(names)
+
+	decorations := map[plumbing.Hash][]string{}
+	if head, err := m.repo.Reference(plumbing.HEAD, false); err == nil {
+		if head.Type() == plumbing.SymbolicReference {
+			branch := head.Target().String()
+			if h, ok := targets[branch]; ok {
+				decorations[h] = []string{"HEAD -> " + decorationName(branch)}
+				names = slices.DeleteFunc(names, func(n string) bool { return n == branch })
+			}
+		} else {
+			decorations[head.Hash()] = []string{"HEAD"}
+		}
+	}
+	for _, name := range names {
+		h := targets[name]
+		decorations[h] = append(decorations[h], decorationName(name))
+	}
+	out := make(map[plumbing.Hash]string, len(decorations))
+	for h, d := range decorations {
+		out[h] = strings.Join(d, ", ")
+	}
+	return out, nil
+}
+
+// decorationName shortens a ref name as git decorates with it.
+func decorationName(name string) string {
+	if tag, ok := strings.CutPrefix(name, "refs/tags/"); ok {
+		return "tag: " + tag
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/"} {
+		if short, ok := strings.CutPrefix(name, prefix); ok {
+			return short
+		}
+	}
+	return name
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGoGitRefs decorates the commits with go-git as git log does, with
+// remote branches, a symbolic ref, an annotated tag and the refs left
+// out of the decorations.
+func TestGoGitRefs(t *testing.T) {
+	d := testRepo(t)
+	d.commit("a.txt", "a\n", "First")
+	d.commit("a.txt", "b\n", "Second")
+	d.git("branch", "topic")
+	d.commit("a.txt", "c\n", "Third")
+	d.git("tag", "-a", "-m", "release", "v1", "HEAD~1")
+	d.git("tag", "v0", "HEAD~2")
+	d.git("update-ref", "refs/remotes/origin/main", "HEAD")
+	d.git("symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/main")
+	d.git("update-ref", "refs/prefetch/remotes/origin/main", "HEAD")
+	d.git("notes", "add", "-m", "a note", "HEAD")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	dir := d.dir
+	head := gitOutput(t, dir, "rev-parse", "HEAD")
+
+	refs := map[string]map[string]string{}
+	for _, backend := range []string{backendCLI, backendGoGit} {
+		m := initialModel(options{repoPath: dir, backend: backend})
+		m = settle(m, loadRepo(m.repoPath, m.backend)).(model)
+		if m.err != nil {
+			t.Fatalf("%s: %v", backend, m.err)
+		}
+		refs[backend] = map[string]string{}
+		for _, c := range m.commits {
+			refs[backend][c.FullHash.String()] = c.Refs
+		}
+	}
+	if refs[backendGoGit][head] != "HEAD -> main, origin/main, origin/HEAD" {
+		t.Errorf("refs of HEAD %q", refs[backendGoGit][head])
+	}
+	for hash, want := range refs[backendCLI] {
+		if got := refs[backendGoGit][hash]; got != want {
+			t.Errorf("%s: go-git decorates with %q, git log with %q", hash[:7], got, want)
+		}
+	}
+}
+
+// TestMailmapBothBackends maps the names and emails of the history
+// through .mailmap with either backend.
+func TestMailmapBothBackends(t *testing.T) {
+	d := testRepo(t)
+	d.commit("a.txt", "a\n", "First")
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	mailmap := "Ada Lovelace <ada@lovelace.example> Ada Graph <ada@example.com>\n"
+	if err := os.WriteFile(filepath.Join(d.dir, ".mailmap"), []byte(mailmap), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, backend := range []string{backendCLI, backendGoGit} {
+		t.Run(backend, func(t *testing.T) {
+			m := initialModel(options{repoPath: d.dir, backend: backend})
+			m = settle(m, loadRepo(m.repoPath, m.backend)).(model)
+			if m.err != nil {
+				t.Fatal(m.err)
+			}
+			c := m.commits[0]
+			if c.Author != "Ada Lovelace" || c.AuthorEmail != "ada@lovelace.example" {
+				t.Errorf("author %s <%s>", c.Author, c.AuthorEmail)
+			}
+			if c.Committer != "Ada Lovelace" || c.CommitterEmail != "ada@lovelace.example" {
+				t.Errorf("committer %s <%s>", c.Committer, c.CommitterEmail)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tagVersion is the version a tag names, read the way semantic versioning
+// orders them: "v2.1.0-rc.1+build.5" is 2.1.0, pre-release rc.1, and
+// comes before 2.1.0 itself. Anything before the first digit, like "v"
+// or "release-", is the prefix; build metadata after a "+" doesn't count.
+type tagVersion struct {
+	prefix string
+	nums   []int
+	pre    []string
+}
+
+// parseTagVersion reads the version of a tag, if it names one: dotted
+// numbers, as many as there are, then an optional pre-release.
+func parseTagVersion(tag string) (tagVersion, bool) {
+	start := strings.IndexFunc(tag, unicode.IsDigit)
+	if start < 0 {
+		return tagVersion{}, false
+	}
+	v := tagVersion{prefix: tag[:start]}
+	rest, _, _ := strings.Cut(tag[start:], "+")
+	rest, pre, hasPre := strings.Cut(rest, "-")
+	for _, part := range strings.Split(rest, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return tagVersion{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	if hasPre {
+		if pre == "" {
+			return tagVersion{}, false
+		}
+		v.pre = strings.Split(pre, ".")
+	}
+	return v, true
+}
+
+// compareVersions orders two versions. Missing numbers count as 0, so
+// v1.2 is v1.2.0; a pre-release comes before the release, and
+// pre-releases compare field by field, numbers by value and before
+// words, as rc.2 < rc.10 < rc.beta.
+func compareVersions(a, b tagVersion) int {
+	for i := range max(len(a.nums), len(b.nums)) {
+		var x, y int
+		if i < len(a.nums) {
+			x = a.nums[i]
+		}
+		if i < len(b.nums) {
+			y = b.nums[i]
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+	for i := range min(len(a.pre), len(b.pre)) {
+		x, errX := strconv.Atoi(a.pre[i])
+		y, errY := strconv.Atoi(b.pre[i])
+		var c int
+		switch {
+		case errX == nil && errY == nil:
+			c = cmp.Compare(x, y)
+		case errX == nil:
+			c = -1
+		case errY == nil:
+			c = 1
+		default:
+			c = strings.Compare(a.pre[i], b.pre[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a.pre), len(b.pre))
+}
+
+// compareTags orders tags by version, oldest first, and the tags that
+// name none after them, by name. Versions alike, as v1.2 and 1.2.0, go
+// by name too.
+func compareTags(a, b string) int {
+	va, okA := parseTagVersion(a)
+	vb, okB := parseTagVersion(b)
+	switch {
+	case okA && okB:
+		if c := compareVersions(va, vb); c != 0 {
+			return c
+		}
+	case okA:
+		return -1
+	case okB:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// sortTags sorts tags by version, oldest first.
+func sortTags(tags []string) {
+	slices.SortFunc(tags, compareTags)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortTags(t *testing.T) {
+	tags := []string{
+		"v1.10.0", "nightly", "v1.2.0", "v2.0.0-rc.10", "v2.0.0", "v1.2", "v2.0.0-rc.2",
+		"v2.0.0-beta", "v2.0.0-rc.2.1", "v1.9.3+build.7", "latest", "v2.0.0-rc",
+	}
+	want := []string{
+		"v1.2", "v1.2.0", "v1.9.3+build.7", "v1.10.0",
+		"v2.0.0-beta", "v2.0.0-rc", "v2.0.0-rc.2", "v2.0.0-rc.2.1", "v2.0.0-rc.10", "v2.0.0",
+		"latest", "nightly",
+	}
+	sortTags(tags)
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("got  %q\nwant %q", tags, want)
+	}
+}
+
+func TestParseTagVersion(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want tagVersion
+		ok   bool
+	}{
+		{"v1.2.3", tagVersion{prefix: "v", nums: []int{1, 2, 3}}, true},
+		{"release-4.0-rc.1", tagVersion{prefix: "release-", nums: []int{4, 0}, pre: []string{"rc", "1"}}, true},
+		{"2.0.0+exp.sha.5114f85", tagVersion{nums: []int{2, 0, 0}}, true},
+		{"v1.x", tagVersion{}, false},
+		{"v1.2-", tagVersion{}, false},
+		{"stable", tagVersion{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseTagVersion(tt.tag)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTagVersion(%q) = %+v, %v; want %+v, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ! runs a command of your own in the repository, for what gitraffe has
+// no key for yet: a git command, or anything else, through the shell of
+// gitraffe.shell. Its output, stdout and stderr as they came, opens in a
+// panel to scroll through, and the graph is loaded again after it, as
+// the command may have changed anything. The command has no terminal:
+// it reads nothing, git pages nothing, and an editor it starts exits at
+// once, leaving what it was given.
+
+// shellLimit is the most lines of output the panel keeps, and
+// shellBytes the most bytes; what comes after is dropped as it arrives.
+const (
+	shellLimit = 10000
+	shellBytes = 4 << 20
+)
+
+// shellOutput keeps the start of a command's output, up to shellLimit
+// lines or shellBytes, whichever comes first. Past that it takes what is
+// written without keeping it, so the command runs on.
+type shellOutput struct {
+	mu    sync.Mutex // stdout and stderr are written at once
+	buf   bytes.Buffer
+	lines int
+	cut   bool
+}
+
+func (o *shellOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.lines >= shellLimit || o.buf.Len() >= shellBytes {
+		o.cut = o.cut || len(p) > 0
+		return len(p), nil
+	}
+	keep := p[:min(len(p), shellBytes-o.buf.Len())]
+	for i, b := range keep {
+		if b == '\n' {
+			if o.lines++; o.lines == shellLimit {
+				keep = keep[:i+1]
+				break
+			}
+		}
+	}
+	o.buf.Write(keep)
+	o.cut = len(keep) < len(p)
+	return len(p), nil
+}
+
+// shellView is a command run with !, and its output once it is done.
+type shellView struct {
+	command string
+	running bool
+	cancel  context.CancelFunc
+	lines   []string
+	cut     bool // the output went past shellLimit or shellBytes
+	exit    string
+	failed  bool
+	elapsed time.Duration
+	scroll  int
+}
+
+type shellMsg struct {
+	view    *shellView
+	output  string
+	cut     bool
+	err     error
+	elapsed time.Duration
+}
+
+// runShell runs a command line with the shell, in the repository.
+func runShell(repoPath, shell string, v *shellView) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	return func() tea.Msg {
+		defer cancel()
+		args := shellArgs(shell, v.command)
+		log.Printf("Running: %s\n", strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = repoPath
+		cmd.Env = append(append(os.Environ(), remoteEnv(repoPath)...), "GIT_PAGER=cat", "PAGER=cat", "GIT_EDITOR=true", "GIT_SEQUENCE_EDITOR=true")
+		// Don't wait on children that keep the output open after a kill
+		cmd.WaitDelay = time.Second
+		out := &shellOutput{}
+		cmd.Stdout, cmd.Stderr = out, out
+		start := time.Now()
+		err := cmd.Run()
+		return shellMsg{view: v, output: out.buf.String(), cut: out.cut, err: err, elapsed: time.Since(start)}
+	}
+}
+
+// shellArgs is the command to run a command line with shell, which may
+// come with options of its own. Without a shell, it is sh.
+func shellArgs(shell, command string) []string {
+	args := strings.Fields(shell)
+	if len(args) == 0 {
+		args = []string{"sh"}
+	}
+	return append(args, "-c", command)
+}
+
+// runCommand asks for a command line to run, the last one to start with.
+func (m *model) runCommand() tea.Cmd {
+	m.prompt = newPrompt("!", "git or shell command", func(m *model, value string) tea.Cmd {
+		if value == "" {
+			return nil
+		}
+		m.shellLast = value
+		v := &shellView{command: value, running: true}
+		m.shell = v
+		return runShell(m.repoPath, m.cfg.Shell, v)
+	})
+	m.prompt.input.SetValue(m.shellLast)
+	m.prompt.input.CursorEnd()
+	return nil
+}
+
+// handleShell shows the output of a command and reloads the repository,
+// whether or not the panel is still open.
+func (m *model) handleShell(msg shellMsg) tea.Cmd {
+	v := msg.view
+	v.running, v.elapsed, v.cut = false, msg.elapsed, msg.cut
+	out := strings.TrimRight(msg.output, "\n")
+	if out != "" {
+		v.lines = strings.Split(out, "\n")
+	}
+	for i, line := range v.lines {
+		// Progress redraws its line after a carriage return; the last
+		// drawing is what a terminal would be left showing
+		if j := strings.LastIndex(strings.TrimSuffix(line, "\r"), "\r"); j >= 0 {
+			v.lines[i] = line[j+1:]
+		}
+		v.lines[i] = strings.TrimSuffix(v.lines[i], "\r")
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case msg.err == nil:
+		v.exit = "done"
+	case errors.As(msg.err, &exitErr) && exitErr.ExitCode() >= 0:
+		v.exit, v.failed = fmt.Sprintf("exit status %d", exitErr.ExitCode()), true
+	default:
+		v.exit, v.failed = msg.err.Error(), true
+	}
+	return m.reload()
+}
+
+// updateShell handles a key while the output of a command is shown. esc
+// stops a command still running.
+func (m *model) updateShell(msg tea.KeyMsg) tea.Cmd {
+	v := m.shell
+	page := max(m.windowHeight/2, 1)
+	last := max(len(v.lines)-1, 0)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		if v.running {
+			v.cancel()
+		}
+		m.shell = nil
+	case "up", "k":
+		v.scroll = max(v.scroll-1, 0)
+	case "down", "j":
+		v.scroll = min(v.scroll+1, last)
+	case "u", "ctrl+u", "pgup":
+		v.scroll = max(v.scroll-page, 0)
+	case "d", "ctrl+d", "pgdown":
+		v.scroll = min(v.scroll+page, last)
+	case "g", "home":
+		v.scroll = 0
+	case "G", "end":
+		v.scroll = last
+	case "!":
+		if !v.running {
+			return m.runCommand()
+		}
+	}
+	return nil
+}
+
+// renderShell shows a command's output from the scroll position on.
+func (m *model) renderShell(width, height int) string {
+	v := m.shell
+	state := helpStyle.Render("Running… esc stops it")
+	if !v.running {
+		exit := helpStyle.Render(v.exit)
+		if v.failed {
+			exit = goneStyle.Render(v.exit)
+		}
+		state = exit + helpStyle.Render(" after "+v.elapsed.Round(time.Millisecond).String())
+	}
+	lines := []string{
+		sectionHeader(truncate("! "+v.command, width)),
+		"",
+		state,
+		helpStyle.Render("j/k, d/u, g/G: scroll  !: run another  esc: close"),
+		"",
+	}
+	if !v.running && len(v.lines) == 0 {
+		return strings.Join(append(lines, helpStyle.Render("No output.")), "\n")
+	}
+	rows := max(height-len(lines), 1)
+	for i := v.scroll; i < len(v.lines) && i < v.scroll+rows; i++ {
+		lines = append(lines, truncate(strings.Map(printable, strings.ReplaceAll(v.lines[i], "\t", "    ")), width))
+	}
+	if v.cut && v.scroll+rows > len(v.lines) {
+		lines = append(lines, helpStyle.Render("The rest of the output wasn't kept."))
+	}
+	return strings.Join(lines, "\n")
+}
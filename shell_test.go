@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShellOutput(t *testing.T) {
+	var short shellOutput
+	short.Write([]byte("one\ntwo\n"))
+	if got := short.buf.String(); got != "one\ntwo\n" || short.cut {
+		t.Errorf("short output kept as %q, cut %v", got, short.cut)
+	}
+
+	var lines shellOutput
+	chunk := []byte(strings.Repeat("y\n", 999))
+	for range 30 {
+		if n, err := lines.Write(chunk); n != len(chunk) || err != nil {
+			t.Fatalf("Write = %d, %v; want all of it taken", n, err)
+		}
+	}
+	if n := strings.Count(lines.buf.String(), "\n"); n != shellLimit || !lines.cut {
+		t.Errorf("kept %d lines, cut %v; want %d, cut", n, lines.cut, shellLimit)
+	}
+
+	var bytes shellOutput
+	bytes.Write([]byte(strings.Repeat("x", shellBytes+10)))
+	if bytes.buf.Len() != shellBytes || !bytes.cut {
+		t.Errorf("kept %d bytes, cut %v; want %d, cut", bytes.buf.Len(), bytes.cut, shellBytes)
+	}
+
+	// Output that ends right at the limit isn't cut until more comes
+	var exact shellOutput
+	exact.Write([]byte(strings.Repeat("y\n", shellLimit)))
+	if exact.cut {
+		t.Error("output of exactly shellLimit lines is cut")
+	}
+	exact.Write([]byte("more\n"))
+	if n := strings.Count(exact.buf.String(), "\n"); n != shellLimit || !exact.cut {
+		t.Errorf("kept %d lines, cut %v after more came; want %d, cut", n, exact.cut, shellLimit)
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	tests := []struct {
+		shell string
+		want  []string
+	}{
+		{"sh", []string{"sh", "-c", "git status"}},
+		{"bash -O extglob", []string{"bash", "-O", "extglob", "-c", "git status"}},
+		// A blank shell would run -c as the program
+		{"", []string{"sh", "-c", "git status"}},
+		{"  ", []string{"sh", "-c", "git status"}},
+	}
+	for _, tt := range tests {
+		if got := shellArgs(tt.shell, "git status"); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("shellArgs(%q) = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+}
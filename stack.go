@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// stackStyle is the "+3" after a branch tip: its commits above the base.
+var stackStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#88C0D0"))
+
+// stackBranch is a local branch compared to the base ref, for stacked
+// branch workflows where each branch builds on the one before it.
+type stackBranch struct {
+	name   string
+	hash   string // full hash of the tip
+	above  int    // commits not in the base
+	behind int    // base commits not in the branch
+}
+
+type stackMsg struct {
+	base     string
+	branches []stackBranch
+	err      error
+}
+
+// loadStack compares every local branch with base.
+func loadStack(repoPath, base string) tea.Cmd {
+	if base == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/heads")
+		if err != nil {
+			return stackMsg{base: base, err: err}
+		}
+		var branches []stackBranch
+		for _, line := range strings.Split(out, "\n") {
+			name, hash, ok := strings.Cut(line, " ")
+			if !ok {
+				continue
+			}
+			// "<behind> <above>" for base...branch
+			counts, err := gitRead(repoPath, "rev-list", "--count", "--left-right", base+"..."+name)
+			if err != nil {
+				return stackMsg{base: base, err: fmt.Errorf("%s is not a ref", base)}
+			}
+			fields := strings.Fields(counts)
+			if len(fields) != 2 {
+				continue
+			}
+			b := stackBranch{name: name, hash: hash}
+			b.behind, _ = strconv.Atoi(fields[0])
+			b.above, _ = strconv.Atoi(fields[1])
+			branches = append(branches, b)
+		}
+		return stackMsg{base: base, branches: branches}
+	}
+}
+
+// setStack takes loaded branches and works out the annotations after
+// the hashes in the list: the commits above the base of each local
+// branch pointing at a commit.
+func (m *model) setStack(branches []stackBranch) {
+	m.stack = branches
+	m.stackLabels = map[string]string{}
+	m.stackWidth = 0
+	for _, b := range branches {
+		if b.above == 0 {
+			continue
+		}
+		label := m.stackLabels[b.hash]
+		if label == "" {
+			label = " "
+		} else {
+			label += ","
+		}
+		label += fmt.Sprintf("+%d", b.above)
+		m.stackLabels[b.hash] = label
+		m.stackWidth = max(m.stackWidth, len(label))
+	}
+	m.dataVersion++
+}
+
+// baseBranch is the base as a branch name on the remote, e.g. main for
+// origin/main, which is what a pull request targets.
+func (m *model) baseBranch() string {
+	for _, remote := range m.remotes {
+		if name, ok := strings.CutPrefix(m.base, remote+"/"); ok {
+			return name
+		}
+	}
+	return m.base
+}
+
+// setBase asks for the base ref; an empty answer turns stack mode off.
+func setBase(m *model) tea.Cmd {
+	m.prompt = newPrompt("Base", "ref to compare branches with, e.g. origin/main", func(m *model, value string) tea.Cmd {
+		m.base = value
+		m.setStack(nil)
+		if value == "" {
+			m.status = "Stack mode off"
+			return nil
+		}
+		return loadStack(m.repoPath, value)
+	})
+	m.prompt.input.SetValue(m.base)
+	m.prompt.input.CursorEnd()
+	return nil
+}
+
+// stackMenu lists the local branches against the base, with actions for
+// the current branch.
+func (m *model) stackMenu() *menu {
+	if m.base == "" {
+		return &menu{title: "No base set", options: []menuOption{{key: "b", label: "set base", action: setBase}}}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sectionHeader("Branches on " + m.base))
+	sb.WriteString("\n\n")
+	if len(m.stack) == 0 {
+		sb.WriteString(helpStyle.Render("No local branches."))
+	}
+	for _, b := range m.stack {
+		if b.name == m.base {
+			continue
+		}
+		marker := "  "
+		if b.name == m.currentBranch {
+			marker = "> "
+		}
+		line := fmt.Sprintf("%s%-30s %s", marker, b.name, stackStyle.Render(fmt.Sprintf("+%d", b.above)))
+		if b.behind > 0 {
+			line += helpStyle.Render(fmt.Sprintf("  %d behind, needs a rebase", b.behind))
+		}
+		if b.above == 0 {
+			line += helpStyle.Render("  nothing above the base")
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(helpStyle.Render("Rebasing with --update-refs moves the branches stacked below the current one along with it."))
+
+	branch, base := m.currentBranch, m.base
+	return &menu{
+		title: "Stack on " + base,
+		options: []menuOption{
+			{key: "r", label: "rebase " + branch + " onto " + base, action: func(m *model) tea.Cmd {
+				m.status = "Rebasing…"
+				return runGitCmd(m.repoPath, "Rebased "+branch+" onto "+base, "rebase", "--update-refs", base)
+			}},
+			{key: "c", label: "create PR", action: func(m *model) tea.Cmd {
+				return loadPRInfo(m.repoPath, branch, m.baseBranch())
+			}},
+			{key: "b", label: "change base", action: setBase},
+		},
+		detail: sb.String(),
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	statAddStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C"))
+	statDelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A"))
+)
+
+// addNumstat fills in the line counts of files from `git diff --numstat
+// -z`: "<added>\t<deleted>\t<path>\0", or for a rename or copy
+// "<added>\t<deleted>\t\0<old>\0<new>\0". Binary files count "-".
+func addNumstat(files []fileChange, out string) {
+	byPath := make(map[string]*fileChange, len(files))
+	for i := range files {
+		byPath[files[i].Path] = &files[i]
+	}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		counts := strings.SplitN(fields[i], "\t", 3)
+		if len(counts) < 3 {
+			continue
+		}
+		path := counts[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		f := byPath[path]
+		if f == nil {
+			continue
+		}
+		if counts[0] == "-" {
+			f.Binary = true
+			continue
+		}
+		f.Added, _ = strconv.Atoi(counts[0])
+		f.Deleted, _ = strconv.Atoi(counts[1])
+	}
+}
+
+// diffTotals summarizes the changed lines, as in the details header.
+func diffTotals(files []fileChange) string {
+	added, deleted := 0, 0
+	for _, f := range files {
+		added += f.Added
+		deleted += f.Deleted
+	}
+	noun := "files"
+	if len(files) == 1 {
+		noun = "file"
+	}
+	return statAddStyle.Render(fmt.Sprintf("+%d", added)) + " " +
+		statDelStyle.Render(fmt.Sprintf("−%d", deleted)) +
+		helpStyle.Render(fmt.Sprintf(" across %d %s", len(files), noun))
+}
+
+// statBars draws git's stat graph in color, a bar of +s and -s per file,
+// scaled so the largest change fits in width.
+func statBars(files []fileChange, width int) []string {
+	pathWidth, countWidth, most := 0, 1, 0
+	for _, f := range files {
+		pathWidth = max(pathWidth, len([]rune(f.Path)))
+		countWidth = max(countWidth, len(strconv.Itoa(f.Added+f.Deleted)))
+		most = max(most, f.Added+f.Deleted)
+	}
+	// The paths give way to the bars on narrow panels, down to a third,
+	// and a column at the least: the Diff tab is laid out at width 0 to
+	// find where the patch starts
+	pathWidth = max(min(pathWidth, max(width/3, width-countWidth-3-40)), 1)
+	barWidth := max(width-pathWidth-countWidth-5, 1)
+
+	var lines []string
+	for _, f := range files {
+		path := []rune(f.Path)
+		if len(path) > pathWidth {
+			path = append([]rune("…"), path[len(path)-pathWidth+1:]...)
+		}
+		line := fmt.Sprintf(" %-*s │ ", pathWidth, string(path))
+		if f.Binary {
+			lines = append(lines, line+helpStyle.Render("binary"))
+			continue
+		}
+		adds, dels := f.Added, f.Deleted
+		if most > barWidth {
+			// Scale, keeping at least one mark for any change
+			adds = scaleStat(f.Added, most, barWidth)
+			dels = scaleStat(f.Deleted, most, barWidth)
+		}
+		line += fmt.Sprintf("%*d ", countWidth, f.Added+f.Deleted)
+		line += statAddStyle.Render(strings.Repeat("+", adds)) + statDelStyle.Render(strings.Repeat("-", dels))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func scaleStat(n, most, width int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(n*width/most, 1)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatBarsNarrow(t *testing.T) {
+	files := []fileChange{
+		{Path: "internal/deeply/nested/path/to/a/file.go", Added: 120, Deleted: 7},
+		{Path: "README.md", Added: 1},
+		{Path: "logo.png", Binary: true},
+	}
+	// The Diff tab is laid out at width 0 too, and any width must do
+	for _, width := range []int{0, 1, 5, 80} {
+		lines := statBars(files, width)
+		if len(lines) != len(files) {
+			t.Fatalf("statBars at width %d gave %d lines, want %d", width, len(lines), len(files))
+		}
+		if !strings.Contains(lines[2], "binary") {
+			t.Errorf("statBars at width %d = %q, want the binary file marked", width, lines[2])
+		}
+	}
+}
+
+func TestDiffStart(t *testing.T) {
+	m := &model{}
+	files := []fileChange{{Path: "a.go", Added: 3, Deleted: 1}, {Path: "b.go", Added: 1}}
+	for _, c := range []*commit{
+		{DiffLoaded: true, Files: files, DiffBody: "diff --git a/a.go b/a.go\n"},
+		{DiffLoaded: true, Files: files, DiffBody: "diff --git a/a.go b/a.go\n", Parents: []string{"1", "2"}},
+		{DiffLoaded: true},
+		{},
+	} {
+		_, want := m.diffTabLines(c, 80)
+		if got := m.diffStart(c); got != want {
+			t.Errorf("diffStart = %d, the Diff tab's patch starts at %d", got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// symbols are the glyphs the graph is drawn with. Each should take one
+// column, as git's own characters do. They are set in config by key,
+// gitraffe.commitSymbol and so on, for fonts that render the defaults
+// poorly.
+type symbols struct {
+	Commit   string // an ordinary commit, git's "*"
+	Merge    string
+	Octopus  string // a merge of three or more branches
+	Root     string // a commit without parents
+	Selected string // an ordinary commit or merge when selected
+	Tag      string // a tagged commit, over the above when set
+	Branch   string // a local branch tip, over the above when set
+
+	// Lines replaces the ASCII of git's graph: "|", "-" and "." and,
+	// when five are given, "/" and "\"
+	Lines map[rune]string
+}
+
+var defaultSymbols = symbols{
+	Commit:   "●",
+	Merge:    "●",
+	Octopus:  "✱",
+	Root:     "○",
+	Selected: "◉",
+	Lines:    map[rune]string{'|': "│", '-': "─", '.': "╮"},
+}
+
+// set reads a gitraffe.<name>Symbol(s) key, name lowercased as git
+// prints it, and reports whether it was one.
+func (s *symbols) set(name, value string) bool {
+	switch name {
+	case "commitsymbol":
+		s.Commit = value
+	case "mergesymbol":
+		s.Merge = value
+	case "octopussymbol":
+		s.Octopus = value
+	case "rootsymbol":
+		s.Root = value
+	case "selectedsymbol":
+		s.Selected = value
+	case "tagsymbol":
+		s.Tag = value
+	case "branchsymbol":
+		s.Branch = value
+	case "linesymbols":
+		chars := []rune(value)
+		if len(chars) != 3 && len(chars) != 5 {
+			log.Printf("Ignoring gitraffe.lineSymbols %q: give three characters for | - . or five for | - . / \\\n", value)
+			return true
+		}
+		s.Lines = make(map[rune]string)
+		for i, ascii := range "|-./\\"[:len(chars)] {
+			s.Lines[ascii] = string(chars[i])
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// nodeSymbol is the glyph of a commit's node: by the refs at it first,
+// then by its parents.
+func (m *model) nodeSymbol(c commit) string {
+	sym := m.cfg.Symbols
+	switch {
+	case sym.Tag != "" && strings.Contains(c.Refs, "tag: "):
+		return sym.Tag
+	case sym.Branch != "" && len(m.localBranches(c)) > 0:
+		return sym.Branch
+	case len(c.Parents) > 2:
+		return sym.Octopus
+	case len(c.Parents) == 0:
+		return sym.Root
+	case len(c.Parents) == 2:
+		return sym.Merge
+	}
+	return sym.Commit
+}
+
+// drawGraph draws a row of git's ASCII graph with the line symbols and
+// node at its "*". It returns where the node went, -1 on rows without
+// one.
+func (s symbols) drawGraph(ascii, node string) (string, int) {
+	var sb strings.Builder
+	at := -1
+	for _, r := range ascii {
+		switch line, ok := s.Lines[r]; {
+		case r == '*':
+			at = sb.Len()
+			sb.WriteString(node)
+		case ok:
+			sb.WriteString(line)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String(), at
+}
+
+// selectNode swaps the node of the selected row for the selected
+// symbol. Nodes that tell something, a root or a tag, are kept.
+func (m *model) selectNode(row displayRow, graph string) string {
+	sym := m.cfg.Symbols
+	if row.NodeAt < 0 || (row.Node != sym.Commit && row.Node != sym.Merge) {
+		return graph
+	}
+	return graph[:row.NodeAt] + sym.Selected + graph[row.NodeAt+len(row.Node):]
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Bubble Tea sets the terminal up for the UI, and puts it back when the
+// program ends, a panic it recovers included. What it leaves alone is the
+// window title, which it sets but has no way to read back, and panics
+// that escape it; those are covered here.
+
+// windowTitle is what the terminal's window or tab is called while
+// gitraffe runs.
+func windowTitle(repoPath string) string {
+	return "gitraffe: " + repoDisplayName(repoPath)
+}
+
+// saveTitle pushes the window title onto the terminal's title stack
+// (XTWINOPS 22), for restoreTitle to pop once gitraffe is done.
+// Terminals without a stack ignore both, and keep gitraffe's title.
+func saveTitle() {
+	fmt.Fprint(os.Stdout, "\x1b[22;0t")
+}
+
+// restoreTitle brings back the title saveTitle saved.
+func restoreTitle() {
+	fmt.Fprint(os.Stdout, "\x1b[23;0t")
+}
+
+// restoreTerminal undoes everything the program sets up: mouse reporting
+// in all the modes Bubble Tea may turn on, bracketed paste, the alternate
+// screen, the hidden cursor and the window title. Bubble Tea does this
+// itself when it recovers a panic; this covers panics that escape it.
+func restoreTerminal() {
+	fmt.Fprint(os.Stdout, "\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1006l\x1b[?2004l\x1b[?1049l\x1b[?25h")
+	restoreTitle()
+}
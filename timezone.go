@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// Time zones commit dates can be shown in, see gitraffe.timeZone.
+const (
+	zoneLocal  = "local"  // the zone gitraffe runs in
+	zoneAuthor = "author" // the offset the date was recorded with
+	zoneUTC    = "utc"
+)
+
+// parseTimeZone reads gitraffe.timeZone, falling back to local time.
+func parseTimeZone(value string) string {
+	switch zone := strings.ToLower(value); zone {
+	case zoneLocal, zoneAuthor, zoneUTC:
+		return zone
+	}
+	log.Printf("Ignoring gitraffe.timeZone %q: not local, author or utc\n", value)
+	return zoneLocal
+}
+
+// formatDate shows a commit date in the configured zone, with its offset.
+// Outside the zone it was recorded in, the clock of who recorded it
+// follows, so a late-night commit still reads as one:
+// 2024-03-01 09:12:00 +0100 (00:12 -0800 for the author).
+func (m *model) formatDate(t time.Time, who string) string {
+	shown := t
+	switch m.cfg.TimeZone {
+	case zoneUTC:
+		shown = t.UTC()
+	case zoneAuthor:
+	default:
+		shown = t.Local()
+	}
+	s := dateStyle.Render(shown.Format("2006-01-02 15:04:05 -0700"))
+	if _, theirs := t.Zone(); m.cfg.TimeZone != zoneAuthor {
+		if _, ours := shown.Zone(); theirs != ours {
+			s += helpStyle.Render(" (" + t.Format("15:04 -0700") + " for the " + who + ")")
+		}
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tourStop is one step of the first-run tour: the box it focuses, so its
+// border lights up, and what it says about it.
+type tourStop struct {
+	box  int
+	text string
+}
+
+var tourStops = []tourStop{
+	{0, "[0] Repository: branch, HEAD and what's uncommitted"},
+	{1, "[1] Graph: j/k or ↑/↓ select, g/G ends, d/u half a page"},
+	{2, "[2] Details: tab cycles Commit/Diff/Files/Refs, j/k scroll"},
+	{1, "0/1/2 focus a box, ` the last one, z zooms, ? all keys, q quits"},
+}
+
+// tour is the first-run tour, shown in the status line until it is
+// finished or dismissed.
+type tour struct {
+	stop int
+}
+
+// tourSeenPath marks that the tour was seen. Unlike the state dir it is
+// per user, not per repository, so the tour is shown once.
+func tourSeenPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gitraffe", "toured"), nil
+}
+
+// firstRun reports whether the tour hasn't been seen yet.
+func firstRun() bool {
+	path, err := tourSeenPath()
+	if err != nil {
+		// Nowhere to remember it, so it would be shown every time
+		return false
+	}
+	_, err = os.Stat(path)
+	return os.IsNotExist(err)
+}
+
+// startTour opens the tour on the first box.
+func (m *model) startTour() {
+	m.tour = &tour{}
+	m.focus(tourStops[0].box)
+}
+
+// updateTour moves through the tour: enter, space or → to the next stop,
+// ← back, esc or q to end it. Either way it is not shown again.
+func (m *model) updateTour(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter", " ", "right", "l":
+		if m.tour.stop+1 < len(tourStops) {
+			m.tour.stop++
+			m.focus(tourStops[m.tour.stop].box)
+			return nil
+		}
+	case "left", "h":
+		if m.tour.stop > 0 {
+			m.tour.stop--
+			m.focus(tourStops[m.tour.stop].box)
+		}
+		return nil
+	case "esc", "q", "ctrl+c":
+	default:
+		return nil
+	}
+	m.tour = nil
+	m.focus(1)
+	if path, err := tourSeenPath(); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = os.WriteFile(path, nil, 0644)
+		}
+		if err != nil {
+			log.Printf("Could not remember the tour was seen: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// renderTour is the status line during the tour.
+func (m *model) renderTour() string {
+	stop := tourStops[m.tour.stop]
+	keyStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	next := "next"
+	if m.tour.stop == len(tourStops)-1 {
+		next = "done"
+	}
+	return strings.Join([]string{
+		keyStyle.Render(fmt.Sprintf("Tour %d/%d", m.tour.stop+1, len(tourStops))),
+		stop.text,
+		helpStyle.Render("enter: " + next + " • esc: skip"),
+	}, "  ")
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// t browses the whole tree of the selected commit, not only the files it
+// changed: directories first, then files, each with the commit that last
+// changed it, as a forge's tree view shows them. enter goes into a
+// directory or opens a file in the file viewer; backspace goes up.
+
+// treeEntry is a file, directory or submodule of a tree.
+type treeEntry struct {
+	name string
+	kind string // blob, tree, or commit for a submodule
+	size int64  // of a blob
+}
+
+// lastChange is the commit that last changed a tree entry.
+type lastChange struct {
+	hash    string
+	when    time.Time
+	subject string
+}
+
+// treeView is a directory of a commit's tree.
+type treeView struct {
+	rev, short string
+	dir        string // "" for the top, else ending in "/"
+	entries    []treeEntry
+	cursor     int
+	last       map[string]lastChange // by entry name, nil while loading
+}
+
+type treeMsg struct {
+	view  *treeView
+	focus string // the entry to put the cursor on, as the directory gone up from
+	err   error
+}
+
+type treeLastMsg struct {
+	rev, dir string
+	last     map[string]lastChange
+}
+
+// loadTree lists a directory of a commit's tree.
+func loadTree(repoPath, rev, short, dir, focus string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "ls-tree", "-z", "--long", rev+":"+dir)
+		if err != nil {
+			return treeMsg{err: err}
+		}
+		return treeMsg{view: &treeView{rev: rev, short: short, dir: dir, entries: parseTree(out)}, focus: focus}
+	}
+}
+
+// parseTree reads git ls-tree -z --long: mode, type, object and size,
+// then a tab and the name. It puts directories first, as git sorts
+// them in with the files.
+func parseTree(out string) []treeEntry {
+	var entries []treeEntry
+	for _, record := range strings.Split(out, "\x00") {
+		info, name, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 4 {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[3], 10, 64)
+		entries = append(entries, treeEntry{name: name, kind: fields[1], size: size})
+	}
+	slices.SortStableFunc(entries, func(a, b treeEntry) int {
+		switch {
+		case a.kind == "tree" && b.kind != "tree":
+			return -1
+		case a.kind != "tree" && b.kind == "tree":
+			return 1
+		}
+		return 0
+	})
+	return entries
+}
+
+// loadLastChanges finds the commit that last changed each entry of a
+// directory, walking the history back from rev only until each is found.
+func loadLastChanges(repoPath, rev, dir string, entries []treeEntry) tea.Cmd {
+	return func() tea.Msg {
+		msg := treeLastMsg{rev: rev, dir: dir, last: map[string]lastChange{}}
+		pathspec := "."
+		if dir != "" {
+			pathspec = dir
+		}
+		cmd := gitCommand(repoPath, "log", "-z", "--name-only", "--no-renames",
+			"--format=%x01%H%x02%ct%x02%s", rev, "--", pathspec)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil || cmd.Start() != nil {
+			return msg
+		}
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+
+		// A commit's header, then the paths it changed, each after a NUL
+		var cur lastChange
+		r := bufio.NewReader(stdout)
+		for len(msg.last) < len(entries) {
+			field, err := r.ReadString(0)
+			if err != nil {
+				break
+			}
+			field = strings.TrimPrefix(strings.TrimSuffix(field, "\x00"), "\n")
+			if header, ok := strings.CutPrefix(field, "\x01"); ok {
+				parts := strings.SplitN(header, "\x02", 3)
+				if len(parts) == 3 {
+					secs, _ := strconv.ParseInt(parts[1], 10, 64)
+					cur = lastChange{hash: parts[0], when: time.Unix(secs, 0), subject: parts[2]}
+				}
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimPrefix(field, dir), "/")
+			if _, ok := msg.last[name]; !ok && name != "" && cur.hash != "" {
+				msg.last[name] = cur
+			}
+		}
+		return msg
+	}
+}
+
+// browseTree opens the tree of the selected commit at the top.
+func (m *model) browseTree() tea.Cmd {
+	if m.selected < 0 || m.selected >= len(m.commits) {
+		return nil
+	}
+	c := m.commits[m.selected]
+	return loadTree(m.repoPath, c.FullHash.String(), c.Hash, "", "")
+}
+
+// handleTree shows a listed directory and looks up its entries' last
+// changes.
+func (m *model) handleTree(msg treeMsg) tea.Cmd {
+	if msg.err != nil {
+		m.status, m.statusErr = "Can't list the tree: "+msg.err.Error(), true
+		return nil
+	}
+	v := msg.view
+	for i, e := range v.entries {
+		if e.name == msg.focus {
+			v.cursor = i
+		}
+	}
+	m.tree = v
+	return loadLastChanges(m.repoPath, v.rev, v.dir, v.entries)
+}
+
+// handleTreeLast fills in the last changes of the directory shown, if
+// it still is.
+func (m *model) handleTreeLast(msg treeLastMsg) {
+	if m.tree != nil && m.tree.rev == msg.rev && m.tree.dir == msg.dir {
+		m.tree.last = msg.last
+	}
+}
+
+// updateTree handles a key while the tree is open.
+func (m *model) updateTree(msg tea.KeyMsg) tea.Cmd {
+	v := m.tree
+	page := max(m.windowHeight/2, 1)
+	switch msg.String() {
+	case "esc", "q", "ctrl+c":
+		m.tree = nil
+	case "up", "k":
+		v.cursor = max(v.cursor-1, 0)
+	case "down", "j":
+		v.cursor = max(min(v.cursor+1, len(v.entries)-1), 0)
+	case "u", "ctrl+u", "pgup":
+		v.cursor = max(v.cursor-page, 0)
+	case "d", "ctrl+d", "pgdown":
+		v.cursor = max(min(v.cursor+page, len(v.entries)-1), 0)
+	case "g", "home":
+		v.cursor = 0
+	case "G", "end":
+		v.cursor = max(len(v.entries)-1, 0)
+	case "backspace", "left", "h", "-":
+		if v.dir == "" {
+			return nil
+		}
+		parent, name := "", strings.TrimSuffix(v.dir, "/")
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			parent, name = name[:i+1], name[i+1:]
+		}
+		return loadTree(m.repoPath, v.rev, v.short, parent, name)
+	case "enter", "right", "l":
+		if len(v.entries) == 0 {
+			return nil
+		}
+		e := v.entries[v.cursor]
+		switch e.kind {
+		case "tree":
+			return loadTree(m.repoPath, v.rev, v.short, v.dir+e.name+"/", "")
+		case "blob":
+			return m.openFile(v.rev, v.dir+e.name, 1)
+		default:
+			m.status, m.statusErr = v.dir+e.name+" is a submodule", true
+		}
+	case "c":
+		if len(v.entries) == 0 || v.entries[v.cursor].kind != "blob" {
+			m.status, m.statusErr = "Choose a file to compare", true
+			return nil
+		}
+		return m.compareFile(v.rev, v.dir+v.entries[v.cursor].name)
+	}
+	return nil
+}
+
+// renderTree lists the directory around the cursor: each entry's name,
+// size, and the commit that last changed it.
+func (m *model) renderTree(width, height int) string {
+	v := m.tree
+	dirStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0"))
+	lines := []string{
+		sectionHeader("Tree /"+v.dir) + " " + commitHashStyle.Render(v.short),
+		"",
+		helpStyle.Render("↑/↓: choose  enter: open  backspace: up  c: compare a file with another commit  esc: close"),
+		"",
+	}
+	if len(v.entries) == 0 {
+		return strings.Join(append(lines, helpStyle.Render("The tree is empty.")), "\n")
+	}
+
+	nameWidth := 0
+	for _, e := range v.entries {
+		nameWidth = max(nameWidth, lipgloss.Width(e.name)+1)
+	}
+	nameWidth = min(nameWidth, max(width/3, 12))
+	rows := max(height-len(lines), 1)
+	first := max(min(v.cursor-rows/2, len(v.entries)-rows), 0)
+	for i := first; i < len(v.entries) && i < first+rows; i++ {
+		e := v.entries[i]
+		marker := "  "
+		if i == v.cursor {
+			marker = "> "
+		}
+		name, size := e.name, ""
+		switch e.kind {
+		case "tree":
+			name += "/"
+		case "commit":
+			name += "@"
+		default:
+			size = formatSize(e.size)
+		}
+		name = truncate(name, nameWidth)
+		padded := name + strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		if e.kind == "tree" {
+			padded = dirStyle.Render(padded)
+		}
+		line := marker + padded + " " + helpStyle.Render(fmt.Sprintf("%9s", size))
+		if c, ok := v.last[e.name]; ok {
+			line += "  " + commitHashStyle.Render(m.shortOf(c.hash)) + " " +
+				dateStyle.Render(fmt.Sprintf("%-14s", relativeTime(c.when, m.now)))
+			used := lipgloss.Width(line) + 1
+			line += " " + truncate(c.subject, max(width-used, 0))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// workTreeFile is one entry of `git status --porcelain`.
+type workTreeFile struct {
+	Path     string
+	OrigPath string // source of a rename
+	Staged   byte   // X column: status in the index
+	Unstaged byte   // Y column: status in the working tree
+}
+
+func (f workTreeFile) untracked() bool { return f.Staged == '?' }
+
+// parsePorcelain parses `git status --porcelain -z`, where a rename is
+// followed by an extra NUL-terminated field holding the original path.
+func parsePorcelain(out string) []workTreeFile {
+	var files []workTreeFile
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		f := workTreeFile{Staged: entry[0], Unstaged: entry[1], Path: entry[3:]}
+		if (f.Staged == 'R' || f.Staged == 'C') && i+1 < len(fields) {
+			i++
+			f.OrigPath = fields[i]
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+type workTreeMsg struct {
+	files []workTreeFile
+}
+
+// loadWorkTreeStatus lists the uncommitted changes in the working tree.
+func loadWorkTreeStatus(repoPath string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := gitRead(repoPath, "status", "--porcelain", "-z")
+		if err != nil {
+			return workTreeMsg{}
+		}
+		return workTreeMsg{files: parsePorcelain(out)}
+	}
+}
+
+// workTreeDiff is the diff of one working tree file against the index
+// (unstaged) and of the index against HEAD (staged).
+type workTreeDiff struct {
+	path     string
+	staged   string
+	unstaged string
+	hunks    []diffHunk // staged hunks first, then unstaged
+}
+
+// diffHunk is one @@ hunk of a single-file diff, together with the file
+// header needed to turn it back into a patch for git apply.
+type diffHunk struct {
+	header []string
+	lines  []string // the @@ line and the hunk body
+	staged bool
+}
+
+// splitHunks splits a single-file diff into its hunks. Diffs without
+// hunks, such as binary changes, yield none, and so do LFS pointer files,
+// which are shown summarized.
+func splitHunks(diff string, staged bool) []diffHunk {
+	if strings.Contains(diff, lfsSpec) {
+		return nil
+	}
+	var hunks []diffHunk
+	var header []string
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			hunks = append(hunks, diffHunk{header: header, lines: []string{line}, staged: staged})
+		case len(hunks) == 0:
+			header = append(header, line)
+		default:
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, line)
+		}
+	}
+	return hunks
+}
+
+func (h diffHunk) patch() string {
+	return strings.Join(h.header, "\n") + "\n" + strings.Join(h.lines, "\n") + "\n"
+}
+
+type workTreeDiffMsg workTreeDiff
+
+func loadWorkTreeDiffCmd(repoPath string, f workTreeFile) tea.Cmd {
+	return func() tea.Msg {
+		d := workTreeDiff{path: f.Path}
+
+		if f.untracked() {
+			// Show new files as all-added. --no-index exits 1 when the
+			// files differ, which they always do here.
+			out, _ := gitCommand(repoPath, "diff", "--no-color", "--no-index", "--", "/dev/null", f.Path).Output()
+			d.unstaged = string(out)
+			d.hunks = splitHunks(d.unstaged, false)
+			return workTreeDiffMsg(d)
+		}
+
+		if out, err := gitCommand(repoPath, "diff", "--no-color", "--cached", "--", f.Path).Output(); err == nil {
+			d.staged = string(out)
+		}
+
+		if out, err := gitCommand(repoPath, "diff", "--no-color", "--", f.Path).Output(); err == nil {
+			d.unstaged = string(out)
+		}
+
+		d.hunks = append(splitHunks(d.staged, true), splitHunks(d.unstaged, false)...)
+		return workTreeDiffMsg(d)
+	}
+}
+
+// maybeLoadWorkTreeDiff loads the diff of the selected working tree file
+// unless it is already shown.
+func (m *model) maybeLoadWorkTreeDiff(force bool) tea.Cmd {
+	if !m.workTree || m.wtSelected < 0 || m.wtSelected >= len(m.wtFiles) {
+		return nil
+	}
+	f := m.wtFiles[m.wtSelected]
+	if !force && m.wtDiff.path == f.Path {
+		return nil
+	}
+	return loadWorkTreeDiffCmd(m.repoPath, f)
+}
+
+// markedFiles returns the paths marked with x, in list order.
+func (m *model) markedFiles() []string {
+	var paths []string
+	for _, f := range m.wtFiles {
+		if m.wtMarked[f.Path] {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// stashMenu offers the stash variants. Each asks for an optional message
+// before running.
+func (m *model) stashMenu() *menu {
+	stashWith := func(label string, args ...string) func(m *model) tea.Cmd {
+		return func(m *model) tea.Cmd {
+			m.prompt = newPrompt("Stash message", "optional, enter to skip", func(m *model, msg string) tea.Cmd {
+				cmdArgs := []string{"stash", "push"}
+				if msg != "" {
+					cmdArgs = append(cmdArgs, "-m", msg)
+				}
+				cmdArgs = append(cmdArgs, args...)
+				return runGitCmd(m.repoPath, label, cmdArgs...)
+			})
+			return nil
+		}
+	}
+
+	options := []menuOption{
+		{key: "a", label: "everything", action: stashWith("Stashed everything", "--include-untracked")},
+		{key: "s", label: "staged only", action: stashWith("Stashed staged changes", "--staged")},
+	}
+	if marked := m.markedFiles(); len(marked) > 0 {
+		args := append([]string{"--include-untracked", "--"}, marked...)
+		label := fmt.Sprintf("%d marked files", len(marked))
+		options = append(options, menuOption{key: "f", label: label, action: stashWith("Stashed "+label, args...)})
+	}
+	return &menu{title: "Stash", options: options}
+}
+
+// updateWorkTree handles the keys of the working tree view.
+func (m *model) updateWorkTree(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch m.focusedBox {
+	case 1:
+		moved := true
+		switch msg.String() {
+		case "j", "down":
+			if m.wtSelected < len(m.wtFiles)-1 {
+				m.wtSelected++
+			}
+		case "k", "up":
+			if m.wtSelected > 0 {
+				m.wtSelected--
+			}
+		case "g", "home":
+			m.wtSelected = 0
+		case "G", "end":
+			m.wtSelected = len(m.wtFiles) - 1
+		default:
+			moved = false
+		}
+		if moved {
+			m.wtScroll = 0
+			m.wtHunk = 0
+			m.dataVersion++
+			return m.maybeLoadWorkTreeDiff(false), true
+		}
+
+		switch msg.String() {
+		case " ":
+			if m.wtSelected < 0 || m.wtSelected >= len(m.wtFiles) {
+				return nil, true
+			}
+			f := m.wtFiles[m.wtSelected]
+			if f.untracked() || f.Unstaged != ' ' {
+				return runWorkTreeCmd(m.repoPath, "Staged "+f.Path, "", "add", "--", f.Path), true
+			}
+			return runWorkTreeCmd(m.repoPath, "Unstaged "+f.Path, "", "restore", "--staged", "--", f.Path), true
+		case "x":
+			if m.wtSelected >= 0 && m.wtSelected < len(m.wtFiles) {
+				path := m.wtFiles[m.wtSelected].Path
+				m.wtMarked[path] = !m.wtMarked[path]
+				m.dataVersion++
+			}
+			return nil, true
+		}
+
+	case 2:
+		switch msg.String() {
+		case "j", "down":
+			m.wtScroll++
+			return nil, true
+		case "k", "up":
+			if m.wtScroll > 0 {
+				m.wtScroll--
+			}
+			return nil, true
+		case "d", "ctrl+d":
+			m.wtScroll += 10
+			return nil, true
+		case "u", "ctrl+u":
+			m.wtScroll -= 10
+			if m.wtScroll < 0 {
+				m.wtScroll = 0
+			}
+			return nil, true
+		case "g", "home":
+			m.wtScroll = 0
+			return nil, true
+		case ")", "(":
+			if len(m.wtDiff.hunks) == 0 {
+				return nil, true
+			}
+			if msg.String() == ")" && m.wtHunk < len(m.wtDiff.hunks)-1 {
+				m.wtHunk++
+			} else if msg.String() == "(" && m.wtHunk > 0 {
+				m.wtHunk--
+			}
+			// Scroll so the hunk starts near the top, under a little context
+			_, starts := m.workTreeDiffLines()
+			m.wtScroll = starts[m.wtHunk] - 2
+			if m.wtScroll < 0 {
+				m.wtScroll = 0
+			}
+			m.dataVersion++
+			return nil, true
+		case " ":
+			if m.wtHunk < 0 || m.wtHunk >= len(m.wtDiff.hunks) {
+				return nil, true
+			}
+			h := m.wtDiff.hunks[m.wtHunk]
+			if h.staged {
+				return runWorkTreeCmd(m.repoPath, "Unstaged hunk", h.patch(), "apply", "--cached", "--reverse", "-"), true
+			}
+			return runWorkTreeCmd(m.repoPath, "Staged hunk", h.patch(), "apply", "--cached", "-"), true
+		}
+	}
+
+	switch msg.String() {
+	case "S":
+		if len(m.wtFiles) == 0 {
+			m.status, m.statusErr = "Nothing to stash", true
+			return nil, true
+		}
+		m.menu = m.stashMenu()
+		return nil, true
+	case "c":
+		if m.commitDraft != "" {
+			return m.startCommit(nil), true
+		}
+		return loadCommitTemplates(m.repoPath, m.cfg.CommitTemplates), true
+	}
+	return nil, false
+}
+
+// runWorkTreeCmd runs a git command that only changes the index or working
+// tree, feeding it input on stdin when given. Unlike runGitCmd it leaves
+// the graph alone and only refreshes the working tree view.
+func runWorkTreeCmd(repoPath, action, input string, args ...string) tea.Cmd {
+	run := runGitCmdInput(repoPath, action, input, args...)
+	return func() tea.Msg {
+		done := run().(gitDoneMsg)
+		done.workTreeOnly = true
+		return done
+	}
+}
+
+// renderWorkTreeList renders the changed files in `git status -s` style:
+// the index status in green, the working tree status in red.
+func (m *model) renderWorkTreeList(height int) string {
+	if len(m.wtFiles) == 0 {
+		return helpStyle.Render("Working tree clean")
+	}
+
+	stagedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A3BE8C"))
+	unstagedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#BF616A"))
+	selStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")).Bold(true)
+	markStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Bold(true)
+
+	start := 0
+	if m.wtSelected >= height {
+		start = m.wtSelected - height + 1
+	}
+	end := start + height
+	if end > len(m.wtFiles) {
+		end = len(m.wtFiles)
+	}
+
+	var lines []string
+	for i := start; i < end; i++ {
+		f := m.wtFiles[i]
+		var sb strings.Builder
+		if i == m.wtSelected {
+			sb.WriteString("> ")
+		} else {
+			sb.WriteString("  ")
+		}
+		if m.wtMarked[f.Path] {
+			sb.WriteString(markStyle.Render("* "))
+		} else {
+			sb.WriteString("  ")
+		}
+		sb.WriteString(stagedStyle.Render(string(f.Staged)))
+		sb.WriteString(unstagedStyle.Render(string(f.Unstaged)))
+		sb.WriteString(" ")
+		name := f.Path
+		if f.OrigPath != "" {
+			name = f.OrigPath + " → " + f.Path
+		}
+		if i == m.wtSelected {
+			sb.WriteString(selStyle.Render(name))
+		} else {
+			sb.WriteString(name)
+		}
+		lines = append(lines, sb.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// workTreeDiffLines lays out the diff of the selected working tree file,
+// with the selected hunk marked in the gutter. It also returns the line
+// each hunk starts on, for jumping between them.
+func (m *model) workTreeDiffLines() ([]string, []int) {
+	gutter := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Render("▌ ")
+	lines := []string{lipgloss.NewStyle().Bold(true).Render(m.wtDiff.path), ""}
+	var starts []int
+
+	section := func(title, diff string, hunks []diffHunk, first int) {
+		if diff == "" {
+			return
+		}
+		lines = append(lines, sectionHeader(title))
+		if len(hunks) == 0 {
+			// Nothing to stage piecewise (binary files and the like)
+			lines = append(lines, strings.Split(strings.TrimRight(m.cfg.Colors.colorizeDiff(summarizeLFSDiff(diff)), "\n"), "\n")...)
+			lines = append(lines, "")
+			return
+		}
+		for _, line := range strings.Split(strings.TrimRight(m.cfg.Colors.colorizeDiff(strings.Join(hunks[0].header, "\n")), "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+		for i, h := range hunks {
+			starts = append(starts, len(lines))
+			prefix := "  "
+			if first+i == m.wtHunk {
+				prefix = gutter
+			}
+			for _, line := range strings.Split(strings.TrimRight(m.cfg.Colors.colorizeDiff(strings.Join(h.lines, "\n")), "\n"), "\n") {
+				lines = append(lines, prefix+line)
+			}
+		}
+		lines = append(lines, "")
+	}
+
+	var staged, unstaged []diffHunk
+	for _, h := range m.wtDiff.hunks {
+		if h.staged {
+			staged = append(staged, h)
+		} else {
+			unstaged = append(unstaged, h)
+		}
+	}
+	section("Staged", m.wtDiff.staged, staged, 0)
+	section("Unstaged", m.wtDiff.unstaged, unstaged, len(staged))
+	return lines, starts
+}
+
+// renderWorkTreeDiff renders the staged and unstaged diff of the selected
+// working tree file.
+func (m *model) renderWorkTreeDiff(height int) string {
+	if m.wtSelected < 0 || m.wtSelected >= len(m.wtFiles) {
+		return helpStyle.Render("No changes")
+	}
+	f := m.wtFiles[m.wtSelected]
+	if m.wtDiff.path != f.Path {
+		return helpStyle.Render("Loading diff...")
+	}
+
+	lines, _ := m.workTreeDiffLines()
+	scroll := m.wtScroll
+	if scroll >= len(lines) {
+		scroll = len(lines) - 1
+	}
+	lines = lines[scroll:]
+	if maxLines := height - 2; len(lines) > maxLines && maxLines > 0 {
+		lines = lines[:maxLines]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Under WSL, gitraffe is a Linux program that is often handed Windows
+// paths: pasted from Explorer, or from a Windows terminal's working
+// directory. wslRepoPath turns them into the Linux paths they are
+// mounted at. On Windows, a repository inside WSL is a UNC path like
+// \\wsl$\Ubuntu\home\me\repo, which cleanWindowsPath already takes.
+
+// inWSL tells whether gitraffe runs under WSL.
+var inWSL = sync.OnceValue(func() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(release)), "microsoft")
+})
+
+var (
+	windowsDrivePath = regexp.MustCompile(`^([A-Za-z]):(?:[\\/](.*))?$`)
+	wslSharePath     = regexp.MustCompile(`(?i)^[\\/]{2}wsl(?:\$|\.localhost)[\\/]([^\\/]+)(?:[\\/](.*))?$`)
+)
+
+// wslRepoPath is the Linux path of a Windows path under WSL, or the path
+// as is. wslpath knows where the drives are mounted; without it they are
+// taken to be at the default, /mnt.
+func wslRepoPath(path string) string {
+	if !inWSL() || !windowsDrivePath.MatchString(path) && !wslSharePath.MatchString(path) {
+		return path
+	}
+	if out, err := exec.Command("wslpath", "-u", path).Output(); err == nil {
+		if p := strings.TrimSpace(string(out)); p != "" {
+			return p
+		}
+	}
+	return fromWindowsPath(path, "/mnt", os.Getenv("WSL_DISTRO_NAME"))
+}
+
+// fromWindowsPath turns a Windows path into a path of the WSL distro
+// named distro, with the drives mounted under mountRoot: C:\src\repo is
+// /mnt/c/src/repo, and \\wsl$\<distro>\home\me\repo is /home/me/repo.
+// The share of another distro isn't reachable from this one and is left
+// as is.
+func fromWindowsPath(path, mountRoot, distro string) string {
+	if m := windowsDrivePath.FindStringSubmatch(path); m != nil {
+		rest := strings.ReplaceAll(m[2], `\`, "/")
+		return strings.TrimSuffix(mountRoot+"/"+strings.ToLower(m[1])+"/"+rest, "/")
+	}
+	if m := wslSharePath.FindStringSubmatch(path); m != nil && strings.EqualFold(m[1], distro) {
+		return "/" + strings.ReplaceAll(m[2], `\`, "/")
+	}
+	return path
+}